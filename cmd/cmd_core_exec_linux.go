@@ -0,0 +1,58 @@
+//go:build linux
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+
+	"github.com/UruhaLushia/sparkle-service/core/landlock"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	coreExecReadOnly []string
+	coreExecWritable []string
+	coreExecFD       int
+)
+
+var coreExecCmd = &cobra.Command{
+	Use:    "__core-exec",
+	Hidden: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("core exec requires an executable")
+		}
+
+		rules := make([]landlock.Rule, 0, len(coreExecReadOnly)+len(coreExecWritable))
+		for _, path := range coreExecReadOnly {
+			rules = append(rules, landlock.Rule{Path: path})
+		}
+		for _, path := range coreExecWritable {
+			rules = append(rules, landlock.Rule{Path: path, Writable: true})
+		}
+
+		runtime.LockOSThread()
+		if err := landlock.RestrictSelf(rules); err != nil {
+			return err
+		}
+		target := args[0]
+		if coreExecFD > 0 {
+			// 通过已校验的文件描述符执行，避免路径在校验后被替换；exec 前关闭该描述符以免泄漏给核心
+			syscall.CloseOnExec(coreExecFD)
+			target = fmt.Sprintf("/proc/self/fd/%d", coreExecFD)
+		}
+		return syscall.Exec(target, args, os.Environ())
+	},
+}
+
+func init() {
+	MainCmd.AddCommand(coreExecCmd)
+
+	coreExecCmd.Flags().StringArrayVar(&coreExecReadOnly, "ro", nil, "read-only path")
+	coreExecCmd.Flags().StringArrayVar(&coreExecWritable, "rw", nil, "writable path")
+	coreExecCmd.Flags().IntVar(&coreExecFD, "exec-fd", 0, "verified executable file descriptor")
+}
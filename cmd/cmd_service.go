@@ -2,10 +2,13 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/UruhaLushia/sparkle-service/core"
 	"github.com/UruhaLushia/sparkle-service/log"
 	"github.com/UruhaLushia/sparkle-service/route"
 	appservice "github.com/UruhaLushia/sparkle-service/service"
@@ -124,6 +127,15 @@ var serviceInstallCmd = &cobra.Command{
 			return outputServiceCommandError("install", "创建服务失败", err)
 		}
 
+		// 服务私钥在安装时生成，保存在只有 root/SYSTEM 可读的文件中
+		// 安装只需要服务身份密钥，不受现有公钥与授权主体文件的影响
+		km, _ := loadKeyManager()
+		identity, err := km.ServiceIdentity()
+		if err != nil {
+			return outputServiceCommandError("install", "生成服务身份密钥失败", err)
+		}
+		log.S().Infow("服务身份公钥", "status", serviceCommandStatus{Action: "install", Success: true}, "service_identity", identity)
+
 		if err := s.Install(); err != nil {
 			return outputServiceCommandError("install", "安装服务失败", err)
 		}
@@ -279,38 +291,41 @@ var serviceInitCmd = &cobra.Command{
 	Short: "初始化服务（传入公钥）",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		publicKey := cmd.Flag("public-key").Value.String()
-		authorizedSID := cmd.Flag("authorized-sid").Value.String()
-		authorizedUID, _ := cmd.Flags().GetUint32("authorized-uid")
 		if publicKey == "" {
 			return outputServiceCommandError("init", "错误：必须通过 --public-key 参数提供公钥", errors.New("必须通过 --public-key 参数提供公钥"))
 		}
-		if authorizedSID == "" && !cmd.Flags().Changed("authorized-uid") {
-			return outputServiceCommandError("init", "错误：必须通过 --authorized-sid 或 --authorized-uid 绑定允许访问服务的用户身份", errors.New("必须通过 --authorized-sid 或 --authorized-uid 绑定允许访问服务的用户身份"))
+		principal, ok := principalFromFlags(cmd, "authorized-")
+		if !ok {
+			return outputServiceCommandError("init", "错误：必须通过 --authorized-sid、--authorized-uid 或 --authorized-gid 绑定允许访问服务的用户身份", errors.New("必须通过 --authorized-sid、--authorized-uid 或 --authorized-gid 绑定允许访问服务的用户身份"))
 		}
-		userDataDir := route.GetConfigDir()
-		keyDir := filepath.Join(userDataDir, "sparkle", "keys")
 
-		_ = route.InitKeyManager(keyDir)
-
-		km := route.GetKeyManager()
-		keyChanged, err := km.SetPublicKey(publicKey)
+		var keyOptions []route.KeyOption
+		if cmd.Flags().Changed("key-scopes") {
+			keyScopes, _ := cmd.Flags().GetStringSlice("key-scopes")
+			keyOptions = append(keyOptions, route.WithKeyScopes(keyScopes...))
+		}
+		if cmd.Flags().Changed("key-label") {
+			keyOptions = append(keyOptions, route.WithKeyLabel(cmd.Flag("key-label").Value.String()))
+		}
+		km, err := loadKeyManager()
+		if err != nil {
+			return outputServiceCommandError("init", "加载认证配置失败", err)
+		}
+		keyChanged, err := km.SetPublicKey(publicKey, keyOptions...)
 		if err != nil {
 			return outputServiceCommandError("init", "设置公钥失败", err)
 		}
 
-		principalChanged := false
-		switch {
-		case authorizedSID != "":
-			principalChanged, err = km.SetAuthorizedSID(authorizedSID)
-			if err != nil {
-				return outputServiceCommandError("init", "设置授权 SID 失败", err)
-			}
-		case cmd.Flags().Changed("authorized-uid"):
-			principalChanged, err = km.SetAuthorizedUID(authorizedUID)
-			if err != nil {
-				return outputServiceCommandError("init", "设置授权 UID 失败", err)
-			}
+		principalChanged, err := km.AddAuthorizedPrincipal(keepStoredPrincipalSettings(cmd, km, principal))
+		if err != nil {
+			return outputServiceCommandError("init", "设置授权主体失败", err)
+		}
+
+		identity, err := km.ServiceIdentity()
+		if err != nil {
+			return outputServiceCommandError("init", "加载服务身份密钥失败", err)
 		}
+		log.S().Infow("服务身份公钥，客户端应保存并用于校验响应签名", "status", serviceCommandStatus{Action: "init", Success: true}, "service_identity", identity)
 
 		changed := keyChanged || principalChanged
 		if changed {
@@ -318,40 +333,296 @@ var serviceInitCmd = &cobra.Command{
 		} else {
 			_ = outputServiceCommandResult("服务初始化成功，认证配置未变化", serviceCommandStatus{Action: "init"})
 		}
+		return restartServiceIfChanged("init", changed)
+	},
+}
 
-		listenAddr := listen
-		if listenAddr == "" {
-			listenAddr = defaultAddr
+var servicePrincipalsCmd = &cobra.Command{
+	Use:   "principals",
+	Short: "管理允许访问服务的用户身份",
+}
+
+var servicePrincipalsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出授权主体",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		km, err := loadKeyManager()
+		if err != nil {
+			return outputServiceCommandError("principals", "加载认证配置失败", err)
 		}
-		prg := &Program{listen: listenAddr}
-		s, err := appservice.New(prg, "")
+		principals := km.AuthorizedPrincipals()
+		log.S().Infow("授权主体列表", "status", serviceCommandStatus{Action: "principals", Success: true}, "principals", principals)
+		return nil
+	},
+}
+
+var servicePrincipalsAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "新增授权主体或修改其角色",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		principal, ok := principalFromFlags(cmd, "")
+		if !ok {
+			return outputServiceCommandError("principals", "错误：必须通过 --sid、--uid 或 --gid 指定用户身份", errors.New("必须通过 --sid、--uid 或 --gid 指定用户身份"))
+		}
+
+		km, err := loadKeyManager()
+		if err != nil {
+			return outputServiceCommandError("principals", "加载认证配置失败", err)
+		}
+		changed, err := km.AddAuthorizedPrincipal(keepStoredPrincipalSettings(cmd, km, principal))
 		if err != nil {
-			return outputServiceCommandError("init", "创建服务失败", err)
+			return outputServiceCommandError("principals", "保存授权主体失败", err)
 		}
+		_ = outputServiceCommandResult("授权主体已保存", serviceCommandStatus{Action: "principals", Changed: changed})
+		return restartServiceIfChanged("principals", changed)
+	},
+}
 
-		status, err := s.Status()
+var servicePrincipalsRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "删除授权主体",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		principal, ok := principalFromFlags(cmd, "")
+		if !ok {
+			return outputServiceCommandError("principals", "错误：必须通过 --sid、--uid 或 --gid 指定用户身份", errors.New("必须通过 --sid、--uid 或 --gid 指定用户身份"))
+		}
+
+		km, err := loadKeyManager()
+		if err != nil {
+			return outputServiceCommandError("principals", "加载认证配置失败", err)
+		}
+		changed, err := km.RemoveAuthorizedPrincipal(principal.Type, principal.Value)
 		if err != nil {
-			return outputServiceCommandError("status", "查询服务状态失败；如果服务正在运行，请手动执行 'restart' 命令", err)
+			return outputServiceCommandError("principals", "删除授权主体失败", err)
 		}
+		_ = outputServiceCommandResult("授权主体已删除", serviceCommandStatus{Action: "principals", Changed: changed})
+		return restartServiceIfChanged("principals", changed)
+	},
+}
 
-		state := normalizeServiceStatus(status)
-		if status == kservice.StatusRunning {
-			if !changed {
-				return outputServiceCommandResult("服务已在运行，配置未变化，无需重启", serviceCommandStatus{Action: "init", State: state})
-			}
-			log.S().Infow("正在重启服务...", "status", serviceCommandStatus{Action: "restart", State: state, Success: true})
-			if err := s.Restart(); err != nil {
-				return outputServiceCommandError("restart", "重启服务失败；请手动执行 'sparkle-service service restart' 命令", err)
+var serviceKeysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "管理客户端公钥",
+}
+
+var serviceKeysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出公钥",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		km, err := loadKeyManager()
+		if err != nil {
+			return outputServiceCommandError("keys", "加载认证配置失败", err)
+		}
+		keys := km.PublicKeys()
+		log.S().Infow("公钥列表", "status", serviceCommandStatus{Action: "keys", Success: true}, "keys", keys)
+		return nil
+	},
+}
+
+var serviceKeysAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "添加公钥或修改其标签与权限范围",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		publicKey := cmd.Flag("public-key").Value.String()
+		if publicKey == "" {
+			return outputServiceCommandError("keys", "错误：必须通过 --public-key 参数提供公钥", errors.New("必须通过 --public-key 参数提供公钥"))
+		}
+
+		var options []route.KeyOption
+		if cmd.Flags().Changed("scopes") {
+			scopes, _ := cmd.Flags().GetStringSlice("scopes")
+			options = append(options, route.WithKeyScopes(scopes...))
+		}
+		if cmd.Flags().Changed("label") {
+			options = append(options, route.WithKeyLabel(cmd.Flag("label").Value.String()))
+		}
+		if cmd.Flags().Changed("expires-in") {
+			expiresIn, _ := cmd.Flags().GetDuration("expires-in")
+			var expiresAt time.Time
+			if expiresIn > 0 {
+				expiresAt = time.Now().Add(expiresIn)
 			}
-			return outputServiceCommandResult("服务已成功重启", serviceCommandStatus{Action: "restart", State: "running"})
+			options = append(options, route.WithKeyExpiry(expiresAt))
 		}
 
-		return outputServiceCommandResult("服务未运行，配置将在下次启动时生效", serviceCommandStatus{Action: "init", State: state, Changed: changed})
+		km, err := loadKeyManager()
+		if err != nil {
+			return outputServiceCommandError("keys", "加载认证配置失败", err)
+		}
+		changed, err := km.SetPublicKey(publicKey, options...)
+		if err != nil {
+			return outputServiceCommandError("keys", "保存公钥失败", err)
+		}
+		_ = outputServiceCommandResult("公钥已保存", serviceCommandStatus{Action: "keys", Changed: changed})
+		return restartServiceIfChanged("keys", changed)
+	},
+}
+
+var serviceKeysRevokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "撤销公钥",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keyID := cmd.Flag("key-id").Value.String()
+		if keyID == "" {
+			return outputServiceCommandError("keys", "错误：必须通过 --key-id 参数指定公钥", errors.New("必须通过 --key-id 参数指定公钥"))
+		}
+
+		km, err := loadKeyManager()
+		if err != nil {
+			return outputServiceCommandError("keys", "加载认证配置失败", err)
+		}
+		changed, err := km.RevokePublicKey(keyID)
+		if err != nil {
+			return outputServiceCommandError("keys", "撤销公钥失败", err)
+		}
+		_ = outputServiceCommandResult("公钥已撤销", serviceCommandStatus{Action: "keys", Changed: changed})
+		return restartServiceIfChanged("keys", changed)
+	},
+}
+
+var servicePairCmd = &cobra.Command{
+	Use:   "pair",
+	Short: "开启配对模式，允许客户端凭配对码登记本地身份与公钥",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ttl, _ := cmd.Flags().GetDuration("ttl")
+		role := cmd.Flag("role").Value.String()
+
+		// 配对信息保存在文件中，正在运行的服务无需重启即可读取
+		km, err := loadKeyManager()
+		if err != nil {
+			return outputServiceCommandError("pair", "加载认证配置失败", err)
+		}
+		code, expiresAt, err := km.OpenPairing(ttl, role)
+		if err != nil {
+			return outputServiceCommandError("pair", "开启配对模式失败", err)
+		}
+		log.S().Infow("配对模式已开启", "status", serviceCommandStatus{Action: "pair", Success: true, Changed: true}, "code", code, "role", role, "expires_at", expiresAt)
+		return nil
+	},
+}
+
+var serviceAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "管理审计日志",
+}
+
+var serviceAuditVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "校验审计日志的摘要链是否完整",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		result, err := route.VerifyAuditLog()
+		if err != nil {
+			return outputServiceCommandError("audit", "读取审计日志失败", err)
+		}
+		if !result.Valid {
+			return outputServiceCommandError("audit", fmt.Sprintf("审计日志第 %d 行校验失败", result.BrokenLine), errors.New(result.Error))
+		}
+		log.S().Infow("审计日志校验通过", "status", serviceCommandStatus{Action: "audit", Success: true}, "audit", result)
+		return nil
+	},
+}
+
+// 全新安装时公钥与授权主体文件尚不存在，可以正常写入；现有文件无法读取时返回错误，避免覆盖
+func loadKeyManager() (*route.KeyManager, error) {
+	keyDir := filepath.Join(route.GetConfigDir(), "sparkle", "keys")
+	_ = route.InitKeyManager(keyDir)
+	km := route.GetKeyManager()
+	return km, km.LoadError()
+}
+
+// 按 --<prefix>sid / --<prefix>uid / --<prefix>gid 与 --role、--scopes 构造授权主体
+func principalFromFlags(cmd *cobra.Command, prefix string) (route.AuthorizedPrincipal, bool) {
+	var principal route.AuthorizedPrincipal
+	if cmd.Flags().Changed("role") {
+		principal.Role = cmd.Flag("role").Value.String()
+	}
+	if cmd.Flags().Changed("scopes") {
+		principal.Scopes, _ = cmd.Flags().GetStringSlice("scopes")
+	}
+	switch {
+	case cmd.Flag(prefix+"sid").Value.String() != "":
+		principal.Type = "sid"
+		principal.Value = cmd.Flag(prefix + "sid").Value.String()
+	case cmd.Flags().Changed(prefix + "uid"):
+		principal.Type = "uid"
+		principal.Value = cmd.Flag(prefix + "uid").Value.String()
+	case cmd.Flags().Changed(prefix + "gid"):
+		principal.Type = "gid"
+		principal.Value = cmd.Flag(prefix + "gid").Value.String()
+	default:
+		return principal, false
+	}
+	return principal, true
+}
+
+// 未指定 --role 或 --scopes 时沿用已保存的设置，避免重复执行命令时意外提升或放宽授权主体的权限；新授权主体默认为 admin
+func keepStoredPrincipalSettings(cmd *cobra.Command, km *route.KeyManager, principal route.AuthorizedPrincipal) route.AuthorizedPrincipal {
+	stored, ok := km.AuthorizedPrincipal(principal.Type, principal.Value)
+	if !ok {
+		return principal
+	}
+	if !cmd.Flags().Changed("role") {
+		principal.Role = stored.Role
+	}
+	if !cmd.Flags().Changed("scopes") {
+		principal.Scopes = stored.Scopes
+	}
+	return principal
+}
+
+// 认证配置写入文件后需要重启正在运行的服务才能生效
+func restartServiceIfChanged(action string, changed bool) error {
+	listenAddr := listen
+	if listenAddr == "" {
+		listenAddr = defaultAddr
+	}
+	prg := &Program{listen: listenAddr}
+	s, err := appservice.New(prg, "")
+	if err != nil {
+		return outputServiceCommandError(action, "创建服务失败", err)
+	}
+
+	status, err := s.Status()
+	if err != nil {
+		return outputServiceCommandError("status", "查询服务状态失败；如果服务正在运行，请手动执行 'restart' 命令", err)
+	}
+
+	state := normalizeServiceStatus(status)
+	if status == kservice.StatusRunning {
+		if !changed {
+			return outputServiceCommandResult("服务已在运行，配置未变化，无需重启", serviceCommandStatus{Action: action, State: state})
+		}
+		log.S().Infow("正在重启服务...", "status", serviceCommandStatus{Action: "restart", State: state, Success: true})
+		if err := s.Restart(); err != nil {
+			return outputServiceCommandError("restart", "重启服务失败；请手动执行 'sparkle-service service restart' 命令", err)
+		}
+		return outputServiceCommandResult("服务已成功重启", serviceCommandStatus{Action: "restart", State: "running"})
+	}
+
+	return outputServiceCommandResult("服务未运行，配置将在下次启动时生效", serviceCommandStatus{Action: action, State: state, Changed: changed})
+}
+
+var servicePinCoreCmd = &cobra.Command{
+	Use:   "pin-core",
+	Short: "固定当前核心文件的 SHA-256",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		corePath := cmd.Flag("core-path").Value.String()
+		decision, err := core.PinCoreBinary(corePath)
+		if err != nil {
+			return outputServiceCommandError("pin-core", "固定核心文件失败", err)
+		}
+		log.S().Infow("核心文件已固定", "status", serviceCommandStatus{Action: "pin-core", Success: true}, "trust", decision)
+		return nil
 	},
 }
 
 func init() {
 	serviceCmd.AddCommand(serviceInitCmd)
+	serviceCmd.AddCommand(servicePrincipalsCmd)
+	serviceCmd.AddCommand(serviceKeysCmd)
+	serviceCmd.AddCommand(servicePairCmd)
+	serviceCmd.AddCommand(serviceAuditCmd)
+	serviceCmd.AddCommand(servicePinCoreCmd)
 	serviceCmd.AddCommand(serviceInstallCmd)
 	serviceCmd.AddCommand(serviceUninstallCmd)
 	serviceCmd.AddCommand(serviceStartCmd)
@@ -363,4 +634,36 @@ func init() {
 	serviceInitCmd.Flags().StringP("public-key", "k", "", "客户端公钥")
 	serviceInitCmd.Flags().String("authorized-sid", "", "允许访问服务的 Windows SID")
 	serviceInitCmd.Flags().Uint32("authorized-uid", 0, "允许访问服务的 Unix UID")
+	serviceInitCmd.Flags().Uint32("authorized-gid", 0, "允许访问服务的 Unix 用户组 GID")
+	serviceInitCmd.Flags().String("role", "admin", "授权主体角色（admin、operator、viewer，未指定时保留已有授权主体的角色）")
+	serviceInitCmd.Flags().StringSlice("scopes", nil, "限制授权主体的权限范围（默认为角色的全部权限范围）")
+	serviceInitCmd.Flags().StringSlice("key-scopes", nil, "限制公钥的权限范围（默认不限制）")
+	serviceInitCmd.Flags().String("key-label", "", "公钥标签")
+
+	servicePrincipalsCmd.AddCommand(servicePrincipalsListCmd)
+	servicePrincipalsCmd.AddCommand(servicePrincipalsAddCmd)
+	servicePrincipalsCmd.AddCommand(servicePrincipalsRemoveCmd)
+	for _, command := range []*cobra.Command{servicePrincipalsAddCmd, servicePrincipalsRemoveCmd} {
+		command.Flags().String("sid", "", "Windows SID")
+		command.Flags().Uint32("uid", 0, "Unix UID")
+		command.Flags().Uint32("gid", 0, "Unix 用户组 GID")
+	}
+	servicePrincipalsAddCmd.Flags().String("role", "admin", "授权主体角色（admin、operator、viewer，未指定时保留已有授权主体的角色）")
+	servicePrincipalsAddCmd.Flags().StringSlice("scopes", nil, "限制授权主体的权限范围（默认为角色的全部权限范围）")
+
+	serviceKeysCmd.AddCommand(serviceKeysListCmd)
+	serviceKeysCmd.AddCommand(serviceKeysAddCmd)
+	serviceKeysCmd.AddCommand(serviceKeysRevokeCmd)
+	serviceKeysAddCmd.Flags().StringP("public-key", "k", "", "客户端公钥")
+	serviceKeysAddCmd.Flags().String("label", "", "公钥标签")
+	serviceKeysAddCmd.Flags().Duration("expires-in", 0, "公钥有效期（如 720h，0 表示永不过期，只能在添加时设置）")
+	serviceKeysAddCmd.Flags().StringSlice("scopes", nil, "限制公钥的权限范围（默认不限制）")
+	serviceKeysRevokeCmd.Flags().String("key-id", "", "公钥 ID")
+
+	serviceAuditCmd.AddCommand(serviceAuditVerifyCmd)
+
+	servicePairCmd.Flags().Duration("ttl", route.DefaultPairingTTL, "配对码有效期")
+	servicePairCmd.Flags().String("role", "admin", "配对成功后授予的角色（admin、operator、viewer）")
+
+	servicePinCoreCmd.Flags().String("core-path", "", "核心文件路径（默认使用已保存的启动配置）")
 }
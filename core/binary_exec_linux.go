@@ -0,0 +1,29 @@
+//go:build linux
+
+package core
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// landlock 辅助进程中已校验核心文件的描述符编号
+const coreBinaryExecFD = 3
+
+// 直接通过 service 以 O_CLOEXEC 打开的描述符执行：fork 后子进程仍持有该描述符，
+// 内核打开核心文件后在 exec 时将其关闭，不会泄漏给核心进程
+func coreBinaryExecPath(launch *launchSession) string {
+	if launch.binary == nil || launch.binary.File == nil {
+		return launch.executablePath
+	}
+	return fmt.Sprintf("/proc/self/fd/%d", launch.binary.File.Fd())
+}
+
+// landlock 辅助进程需要在限制自身后再执行核心，由其在 exec 前将描述符设为 close-on-exec
+func attachCoreBinary(cmd *exec.Cmd, launch *launchSession) {
+	if launch.binary == nil || launch.binary.File == nil {
+		return
+	}
+	cmd.ExtraFiles = append([]*os.File{launch.binary.File}, cmd.ExtraFiles...)
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package core
+
+func coreBinaryExecPath(launch *launchSession) string {
+	return launch.executablePath
+}
@@ -0,0 +1,302 @@
+package core
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/UruhaLushia/sparkle-service/core/security"
+)
+
+const (
+	BinaryTrustMethodNone      = "none"
+	BinaryTrustMethodPinned    = "pinned"
+	BinaryTrustMethodSignature = "signature"
+)
+
+// 签名文件为 64 字节原始签名或其 base64 编码，留出换行等空白
+const maxBinarySignatureSize = 1 << 10
+
+var ErrUntrustedCoreBinary = errors.New("核心文件未通过信任校验")
+
+type BinaryTrustPolicy struct {
+	SHA256    []string `json:"sha256,omitempty"`
+	PublicKey string   `json:"public_key,omitempty"`
+}
+
+type BinaryTrustDecision struct {
+	Path      string `json:"path"`
+	SHA256    string `json:"sha256,omitempty"`
+	Enforced  bool   `json:"enforced"`
+	Trusted   bool   `json:"trusted"`
+	Method    string `json:"method,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+func LoadBinaryTrustPolicy() (BinaryTrustPolicy, error) {
+	path := binaryTrustPolicyPath()
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return BinaryTrustPolicy{}, nil
+		}
+		return BinaryTrustPolicy{}, fmt.Errorf("读取核心信任策略失败：%w", err)
+	}
+	if err := security.CheckAdminOwned(path, info); err != nil {
+		return BinaryTrustPolicy{}, fmt.Errorf("核心信任策略文件不可信：%w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return BinaryTrustPolicy{}, fmt.Errorf("读取核心信任策略失败：%w", err)
+	}
+
+	var policy BinaryTrustPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return BinaryTrustPolicy{}, fmt.Errorf("解析核心信任策略失败：%w", err)
+	}
+	return normalizeBinaryTrustPolicy(policy)
+}
+
+func SaveBinaryTrustPolicy(policy BinaryTrustPolicy) error {
+	normalized, err := normalizeBinaryTrustPolicy(policy)
+	if err != nil {
+		return err
+	}
+
+	path := binaryTrustPolicyPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("创建核心配置目录失败：%w", err)
+	}
+
+	data, err := json.MarshalIndent(normalized, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化核心信任策略失败：%w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("写入核心信任策略失败：%w", err)
+	}
+	return nil
+}
+
+func PinCoreBinary(corePath string) (*BinaryTrustDecision, error) {
+	path, err := trustCoreExecutablePath(corePath)
+	if err != nil {
+		return nil, err
+	}
+
+	binaryOptions, err := coreBinaryOptions()
+	if err != nil {
+		return nil, err
+	}
+	binary, err := security.OpenVerifiedBinary(path, binaryOptions...)
+	if err != nil {
+		return nil, err
+	}
+	defer binary.Close()
+
+	policy, err := LoadBinaryTrustPolicy()
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(policy.SHA256, binary.SHA256) {
+		policy.SHA256 = append(policy.SHA256, binary.SHA256)
+		if err := SaveBinaryTrustPolicy(policy); err != nil {
+			return nil, err
+		}
+	}
+
+	decision := evaluateBinaryTrust(policy, binary.Path, binary.File, binary.SHA256)
+	return &decision, nil
+}
+
+// 只检查当前启动配置中的核心文件，不接受客户端指定的任意路径
+func CheckCoreBinaryTrust() (*BinaryTrustDecision, error) {
+	path, err := trustCoreExecutablePath("")
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	file, err := security.OpenRegularFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开核心文件失败：%w", err)
+	}
+	defer file.Close()
+
+	digest, err := security.FileSHA256(file)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := LoadBinaryTrustPolicy()
+	if err != nil {
+		return &BinaryTrustDecision{
+			Path:     path,
+			SHA256:   digest,
+			Enforced: true,
+			Reason:   err.Error(),
+		}, nil
+	}
+
+	decision := evaluateBinaryTrust(policy, path, file, digest)
+	return &decision, nil
+}
+
+func verifyCoreBinaryTrust(binary *security.VerifiedBinary) error {
+	policy, err := LoadBinaryTrustPolicy()
+	if err != nil {
+		return fmt.Errorf("%w：%v", ErrUntrustedCoreBinary, err)
+	}
+
+	decision := evaluateBinaryTrust(policy, binary.Path, binary.File, binary.SHA256)
+	if !decision.Trusted {
+		return fmt.Errorf("%w：%s", ErrUntrustedCoreBinary, decision.Reason)
+	}
+	return nil
+}
+
+func evaluateBinaryTrust(policy BinaryTrustPolicy, path string, file *os.File, digest string) BinaryTrustDecision {
+	decision := BinaryTrustDecision{
+		Path:     path,
+		SHA256:   digest,
+		Enforced: len(policy.SHA256) > 0 || policy.PublicKey != "",
+	}
+	if !decision.Enforced {
+		decision.Trusted = true
+		decision.Method = BinaryTrustMethodNone
+		return decision
+	}
+
+	if slices.Contains(policy.SHA256, digest) {
+		decision.Trusted = true
+		decision.Method = BinaryTrustMethodPinned
+		return decision
+	}
+
+	if policy.PublicKey == "" {
+		decision.Reason = fmt.Sprintf("SHA-256 %s 不在已固定的哈希列表中", digest)
+		return decision
+	}
+
+	decision.Signature = path + ".sig"
+	if err := verifyCoreBinarySignature(policy.PublicKey, decision.Signature, file); err != nil {
+		decision.Reason = err.Error()
+		return decision
+	}
+	decision.Trusted = true
+	decision.Method = BinaryTrustMethodSignature
+	return decision
+}
+
+func verifyCoreBinarySignature(publicKey, signaturePath string, file *os.File) error {
+	key, err := parseBinaryTrustPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	signatureFile, err := security.OpenRegularFile(signaturePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("哈希未固定且缺少签名文件 %s", signaturePath)
+		}
+		return fmt.Errorf("读取签名文件失败：%w", err)
+	}
+	raw, err := io.ReadAll(io.LimitReader(signatureFile, maxBinarySignatureSize))
+	signatureFile.Close()
+	if err != nil {
+		return fmt.Errorf("读取签名文件失败：%w", err)
+	}
+	signature, err := decodeBinarySignature(raw)
+	if err != nil {
+		return err
+	}
+
+	// 签名为 Ed25519ph（对 SHA-512 摘要签名），核心文件以流式计算摘要，不整体读入内存
+	hash := sha512.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, 1<<62)); err != nil {
+		return fmt.Errorf("读取核心文件失败：%w", err)
+	}
+	if err := ed25519.VerifyWithOptions(key, hash.Sum(nil), signature, &ed25519.Options{Hash: crypto.SHA512}); err != nil {
+		return fmt.Errorf("签名文件 %s 校验失败", signaturePath)
+	}
+	return nil
+}
+
+func decodeBinarySignature(raw []byte) ([]byte, error) {
+	if len(raw) == ed25519.SignatureSize {
+		return raw, nil
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(raw)))
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("签名文件格式无效，应为 64 字节 Ed25519 签名或其 base64 编码")
+	}
+	return signature, nil
+}
+
+func parseBinaryTrustPublicKey(publicKey string) (ed25519.PublicKey, error) {
+	der, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil {
+		return nil, fmt.Errorf("信任策略公钥 base64 解码失败：%w", err)
+	}
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("解析信任策略公钥失败：%w", err)
+	}
+	key, ok := pub.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("信任策略公钥不是 Ed25519 类型")
+	}
+	return key, nil
+}
+
+func normalizeBinaryTrustPolicy(policy BinaryTrustPolicy) (BinaryTrustPolicy, error) {
+	normalized := BinaryTrustPolicy{PublicKey: strings.TrimSpace(policy.PublicKey)}
+	for _, digest := range policy.SHA256 {
+		digest = strings.ToLower(strings.TrimSpace(digest))
+		if digest == "" {
+			continue
+		}
+		if decoded, err := hex.DecodeString(digest); err != nil || len(decoded) != 32 {
+			return BinaryTrustPolicy{}, fmt.Errorf("无效的 SHA-256 哈希: %s", digest)
+		}
+		if !slices.Contains(normalized.SHA256, digest) {
+			normalized.SHA256 = append(normalized.SHA256, digest)
+		}
+	}
+	if normalized.PublicKey != "" {
+		if _, err := parseBinaryTrustPublicKey(normalized.PublicKey); err != nil {
+			return BinaryTrustPolicy{}, err
+		}
+	}
+	return normalized, nil
+}
+
+func trustCoreExecutablePath(corePath string) (string, error) {
+	if strings.TrimSpace(corePath) == "" {
+		profile, err := LoadLaunchProfile()
+		if err != nil {
+			return "", err
+		}
+		corePath = profile.CorePath
+	}
+	return resolveCoreExecutablePath(corePath, true)
+}
+
+func binaryTrustPolicyPath() string {
+	return filepath.Join(serviceConfigDir(), "sparkle", "core", "binary_trust.json")
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEvaluateBinaryTrust(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encodedKey := base64.StdEncoding.EncodeToString(der)
+
+	content := []byte("mihomo core binary")
+	sum := sha256.Sum256(content)
+	digest := hex.EncodeToString(sum[:])
+	prehash := sha512.Sum512(content)
+	signature, err := privateKey.Sign(rand.Reader, prehash[:], &ed25519.Options{Hash: crypto.SHA512})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pureSignature := ed25519.Sign(privateKey, content)
+
+	tests := []struct {
+		name       string
+		policy     BinaryTrustPolicy
+		signature  []byte
+		wantTrust  bool
+		wantMethod string
+	}{
+		{name: "no policy", wantTrust: true, wantMethod: BinaryTrustMethodNone},
+		{name: "pinned", policy: BinaryTrustPolicy{SHA256: []string{digest}}, wantTrust: true, wantMethod: BinaryTrustMethodPinned},
+		{name: "not pinned", policy: BinaryTrustPolicy{SHA256: []string{hex.EncodeToString(make([]byte, 32))}}},
+		{name: "prehash signature", policy: BinaryTrustPolicy{PublicKey: encodedKey}, signature: signature, wantTrust: true, wantMethod: BinaryTrustMethodSignature},
+		{name: "base64 signature", policy: BinaryTrustPolicy{PublicKey: encodedKey}, signature: []byte(base64.StdEncoding.EncodeToString(signature) + "\n"), wantTrust: true, wantMethod: BinaryTrustMethodSignature},
+		{name: "pure signature", policy: BinaryTrustPolicy{PublicKey: encodedKey}, signature: pureSignature},
+		{name: "missing signature", policy: BinaryTrustPolicy{PublicKey: encodedKey}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "mihomo")
+			if err := os.WriteFile(path, content, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if tt.signature != nil {
+				if err := os.WriteFile(path+".sig", tt.signature, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			decision := evaluateBinaryTrust(tt.policy, path, file, digest)
+			if decision.Trusted != tt.wantTrust || decision.Method != tt.wantMethod {
+				t.Fatalf("decision = %+v, want trusted=%v method=%q", decision, tt.wantTrust, tt.wantMethod)
+			}
+			if !decision.Trusted && decision.Reason == "" {
+				t.Fatal("untrusted decision without reason")
+			}
+		})
+	}
+}
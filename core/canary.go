@@ -0,0 +1,250 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/UruhaLushia/sparkle-service/core/controller"
+)
+
+const (
+	MaxCanaryWindow = 10 * time.Minute
+
+	canaryProbeInterval    = 2 * time.Second
+	canaryProbeTimeout     = 2 * time.Second
+	canaryProbeMaxFailures = 3
+)
+
+var ErrCanaryInProgress = errors.New("已有灰度重启正在进行")
+
+type LaunchProfileSnapshot struct {
+	name        string
+	profile     LaunchProfile
+	saveOptions []ProfileSaveOption
+}
+
+// 记录当前激活的启动配置，回滚时以 options 的身份重新保存
+func SnapshotLaunchProfile(options ...ProfileSaveOption) (*LaunchProfileSnapshot, error) {
+	name := ActiveLaunchProfileName()
+	exists, err := NamedLaunchProfileExists(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrLaunchProfileNotFound, name)
+	}
+	profile, err := LoadNamedLaunchProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	return &LaunchProfileSnapshot{name: name, profile: profile, saveOptions: options}, nil
+}
+
+func (s *LaunchProfileSnapshot) restore() error {
+	current, err := LoadNamedLaunchProfile(s.name)
+	if err != nil || !reflect.DeepEqual(current, s.profile) {
+		if err := SaveNamedLaunchProfile(s.name, s.profile, s.saveOptions...); err != nil {
+			return err
+		}
+	}
+	if ActiveLaunchProfileName() != s.name {
+		return SetActiveLaunchProfile(s.name)
+	}
+	return nil
+}
+
+type CoreCanary struct {
+	manager  *CoreManager
+	previous *LaunchProfileSnapshot
+	window   time.Duration
+	options  launchOptions
+	failed   chan error
+	done     chan struct{}
+}
+
+func (cm *CoreManager) BeginCanary(previous *LaunchProfileSnapshot, window time.Duration) (*CoreCanary, error) {
+	if previous == nil {
+		return nil, fmt.Errorf("灰度重启缺少回滚配置")
+	}
+	if window <= 0 || window > MaxCanaryWindow {
+		return nil, fmt.Errorf("灰度观察时间必须在 1 到 %d 秒之间", int(MaxCanaryWindow/time.Second))
+	}
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if cm.canary != nil {
+		return nil, ErrCanaryInProgress
+	}
+	canary := &CoreCanary{
+		manager:  cm,
+		previous: previous,
+		window:   window,
+		failed:   make(chan error, 1),
+		done:     make(chan struct{}),
+	}
+	cm.canary = canary
+	return canary, nil
+}
+
+func (c *CoreCanary) Abort() {
+	c.manager.mutex.Lock()
+	defer c.manager.mutex.Unlock()
+
+	if c.manager.canary == c {
+		c.manager.cancelCanaryLocked()
+	}
+}
+
+// 使用新配置重启核心，未能就绪时立即回滚，否则在观察期内继续检查存活与崩溃
+func (c *CoreCanary) Restart(profile *LaunchProfile, options ...LaunchOption) error {
+	cm := c.manager
+	c.options = collectLaunchOptions(options)
+
+	cm.mutex.Lock()
+	if cm.canary != c {
+		cm.mutex.Unlock()
+		return fmt.Errorf("灰度重启已取消")
+	}
+	err := cm.restartCoreLocked(profile, c.options)
+	cm.mutex.Unlock()
+	if err != nil {
+		c.revert(fmt.Errorf("核心未能就绪：%w", err))
+		return err
+	}
+
+	go c.watch()
+	return nil
+}
+
+func (c *CoreCanary) fail(err error) {
+	select {
+	case c.failed <- err:
+	default:
+	}
+}
+
+func (c *CoreCanary) watch() {
+	timer := time.NewTimer(c.window)
+	defer timer.Stop()
+	ticker := time.NewTicker(canaryProbeInterval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		select {
+		case <-c.done:
+			return
+		case err := <-c.failed:
+			c.revert(err)
+			return
+		case <-ticker.C:
+			if err := c.manager.probeController(); err != nil {
+				failures++
+				log.Printf("灰度存活检查失败 (%d/%d): %v", failures, canaryProbeMaxFailures, err)
+				if failures >= canaryProbeMaxFailures {
+					c.revert(fmt.Errorf("核心控制器存活检查连续失败：%w", err))
+					return
+				}
+				continue
+			}
+			failures = 0
+		case <-timer.C:
+			c.pass()
+			return
+		}
+	}
+}
+
+func (c *CoreCanary) pass() {
+	cm := c.manager
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if cm.canary != c {
+		return
+	}
+	cm.canary = nil
+	close(c.done)
+
+	event := cm.newCoreEvent(CoreEventCanaryPassed, "灰度重启已通过", nil, 0, 0)
+	event.Data = map[string]string{"seconds": strconv.Itoa(int(c.window / time.Second))}
+	cm.publishCoreEvent(event)
+}
+
+func (c *CoreCanary) revert(reason error) {
+	cm := c.manager
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if cm.canary != c {
+		return
+	}
+	cm.canary = nil
+	close(c.done)
+
+	log.Printf("灰度重启失败，正在回滚到启动配置 %s: %v", c.previous.name, reason)
+	err := c.previous.restore()
+	if err == nil {
+		if saveErr := SaveDesiredCoreState(true, withFileAccess(c.options.fileAccess)); saveErr != nil {
+			log.Printf("保存核心运行状态失败: %v", saveErr)
+		}
+		err = cm.restartCoreLocked(nil, c.options)
+	}
+
+	event := cm.newCoreEvent(CoreEventCanaryReverted, "灰度重启失败，已回滚到之前的启动配置", reason, 0, 0)
+	event.Data = map[string]string{"profile": c.previous.name}
+	if err != nil {
+		log.Printf("灰度回滚失败: %v", err)
+		event.Message = "灰度重启失败，回滚失败"
+		event.Data["revert_error"] = err.Error()
+	}
+	cm.publishCoreEvent(event)
+}
+
+func (cm *CoreManager) cancelCanaryLocked() {
+	if cm.canary == nil {
+		return
+	}
+	close(cm.canary.done)
+	cm.canary = nil
+}
+
+func (cm *CoreManager) probeController() error {
+	network, address, err := cm.ControllerEndpoint()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), canaryProbeTimeout)
+	defer cancel()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return controller.Dial(ctx, network, address)
+			},
+			DisableKeepAlives: true,
+		},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://mihomo.local/version", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求核心控制器失败：%w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("核心控制器返回 %s", resp.Status)
+	}
+	return nil
+}
@@ -3,7 +3,9 @@
 package controller
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 )
@@ -25,6 +27,11 @@ func CreatePrivateEndpoint() (string, string, func(), error) {
 	return "unix", address, cleanup, nil
 }
 
+// 预览启动计划时使用的地址，不创建目录
+func PlannedEndpoint() (string, string) {
+	return "unix", filepath.Join(os.TempDir(), "sparkle-mihomo-controller-*", "controller.sock")
+}
+
 func HardenEndpoint(network string, address string) error {
 	if network != "unix" {
 		return nil
@@ -39,3 +46,12 @@ func HardenEndpoint(network string, address string) error {
 	}
 	return nil
 }
+
+func Dial(ctx context.Context, network string, address string) (net.Conn, error) {
+	if network != "unix" {
+		return nil, fmt.Errorf("unix 核心控制器仅支持 unix")
+	}
+
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "unix", address)
+}
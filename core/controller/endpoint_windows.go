@@ -3,11 +3,14 @@
 package controller
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"net"
 
 	"github.com/UruhaLushia/sparkle-service/core/security"
+	"github.com/UruhaLushia/sparkle-service/listen/namedpipe"
 
 	"golang.org/x/sys/windows"
 )
@@ -20,6 +23,11 @@ func CreatePrivateEndpoint() (string, string, func(), error) {
 	return "pipe", `\\.\pipe\sparkle\mihomo-core-` + token, nil, nil
 }
 
+// 预览启动计划时使用的地址，不创建管道
+func PlannedEndpoint() (string, string) {
+	return "pipe", `\\.\pipe\sparkle\mihomo-core-<token>`
+}
+
 func HardenEndpoint(network string, address string) error {
 	if network != "pipe" {
 		return nil
@@ -30,6 +38,13 @@ func HardenEndpoint(network string, address string) error {
 	return nil
 }
 
+func Dial(ctx context.Context, network string, address string) (net.Conn, error) {
+	if network != "pipe" {
+		return nil, fmt.Errorf("windows 核心控制器仅支持 pipe")
+	}
+	return namedpipe.DialContext(ctx, address)
+}
+
 func randomToken(size int) (string, error) {
 	data := make([]byte, size)
 	if _, err := rand.Read(data); err != nil {
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var desiredStateMu sync.Mutex
+
+type DesiredCoreState struct {
+	Running   bool              `json:"running"`
+	Profile   string            `json:"profile,omitempty"`
+	FileOwner *DesiredFileOwner `json:"file_owner,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+type DesiredFileOwner struct {
+	UID int    `json:"uid"`
+	GID int    `json:"gid"`
+	SID string `json:"sid,omitempty"`
+}
+
+func desiredFileOwner(access fileAccess) *DesiredFileOwner {
+	if !access.ok {
+		return nil
+	}
+	return &DesiredFileOwner{UID: access.userID, GID: access.groupID, SID: access.sid}
+}
+
+func (owner *DesiredFileOwner) fileAccess() fileAccess {
+	if owner == nil {
+		return fileAccess{}
+	}
+	return fileAccess{userID: owner.UID, groupID: owner.GID, sid: owner.SID, ok: true}
+}
+
+func LoadDesiredCoreState() (*DesiredCoreState, error) {
+	desiredStateMu.Lock()
+	defer desiredStateMu.Unlock()
+
+	data, err := os.ReadFile(desiredCoreStatePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &DesiredCoreState{}, nil
+		}
+		return nil, fmt.Errorf("读取核心运行状态失败：%w", err)
+	}
+
+	var state DesiredCoreState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("解析核心运行状态失败：%w", err)
+	}
+	return &state, nil
+}
+
+// 记录客户端期望的核心运行状态，服务重启后据此恢复
+func SaveDesiredCoreState(running bool, options ...LaunchOption) error {
+	collected := collectLaunchOptions(options)
+	state := DesiredCoreState{
+		Running:   running,
+		UpdatedAt: time.Now(),
+	}
+	if running {
+		state.Profile = ActiveLaunchProfileName()
+		state.FileOwner = desiredFileOwner(collected.fileAccess)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化核心运行状态失败：%w", err)
+	}
+
+	desiredStateMu.Lock()
+	defer desiredStateMu.Unlock()
+
+	path := desiredCoreStatePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("创建核心配置目录失败：%w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("保存核心运行状态失败：%w", err)
+	}
+	return nil
+}
+
+func (cm *CoreManager) RestoreDesiredState() error {
+	state, err := LoadDesiredCoreState()
+	if err != nil {
+		return err
+	}
+	if !state.Running {
+		return nil
+	}
+
+	name := state.Profile
+	if name == "" {
+		name = defaultLaunchProfileName
+	}
+	profile, err := LoadNamedLaunchProfile(name)
+	if err != nil {
+		return err
+	}
+	if isZeroLaunchProfile(profile) {
+		return fmt.Errorf("%w: %s", ErrLaunchProfileNotFound, name)
+	}
+	if profile.Autostart != nil && !*profile.Autostart {
+		log.Printf("启动配置 %s 已关闭自动启动，跳过恢复核心", name)
+		return nil
+	}
+
+	options := []LaunchOption{withLaunchCause(CoreEventCauseServiceBoot)}
+	if state.FileOwner != nil {
+		options = append(options, withFileAccess(state.FileOwner.fileAccess()))
+	}
+	log.Printf("服务启动，按上次状态恢复核心 (启动配置: %s)", name)
+	return cm.StartCoreWithProfile(&profile, options...)
+}
+
+func desiredCoreStatePath() string {
+	return filepath.Join(serviceConfigDir(), "sparkle", "core", "desired_state.json")
+}
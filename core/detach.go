@@ -0,0 +1,379 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+const coreOutputFIFOName = "output"
+
+type detachedCoreState struct {
+	PID            int32             `json:"pid"`
+	CreateTime     int64             `json:"create_time"`
+	Exe            string            `json:"exe"`
+	Cgroup         string            `json:"cgroup"`
+	SourcePath     string            `json:"source_path"`
+	ExecutablePath string            `json:"executable_path"`
+	SHA256         string            `json:"sha256,omitempty"`
+	ControllerNet  string            `json:"controller_net"`
+	ControllerAddr string            `json:"controller_addr"`
+	HookSocket     string            `json:"hook_socket"`
+	HookToken      string            `json:"hook_token"`
+	OutputDir      string            `json:"output_dir"`
+	SandboxRoot    string            `json:"sandbox_root,omitempty"`
+	SandboxMode    string            `json:"sandbox_mode,omitempty"`
+	Profile        LaunchProfile     `json:"profile"`
+	FileOwner      *DesiredFileOwner `json:"file_owner,omitempty"`
+	DetachedAt     time.Time         `json:"detached_at"`
+}
+
+type coreProcessIdentity struct {
+	CreateTime int64
+	Exe        string
+	Cgroup     string
+}
+
+func readCoreProcessIdentity(pid int32) (coreProcessIdentity, error) {
+	proc, err := process.NewProcess(pid)
+	if err != nil {
+		return coreProcessIdentity{}, fmt.Errorf("核心进程不存在 (PID: %d)：%w", pid, err)
+	}
+	createTime, err := proc.CreateTime()
+	if err != nil {
+		return coreProcessIdentity{}, fmt.Errorf("读取核心进程启动时间失败：%w", err)
+	}
+	exe, err := proc.Exe()
+	if err != nil {
+		return coreProcessIdentity{}, fmt.Errorf("读取核心进程可执行文件失败：%w", err)
+	}
+	cgroup, err := readProcessCgroup(pid)
+	if err != nil {
+		return coreProcessIdentity{}, err
+	}
+	return coreProcessIdentity{CreateTime: createTime, Exe: exe, Cgroup: cgroup}, nil
+}
+
+// 脱离模式下核心的标准输出与错误输出写入 FIFO，service 重启后可重新读取
+func attachCoreOutput(cmd *exec.Cmd, launch *launchSession, output io.Writer) error {
+	dir, writer, err := createCoreOutputFIFO()
+	if err != nil {
+		return err
+	}
+	unregister := registerActiveSandboxRoot(dir)
+	reader, err := openCoreOutputReader(dir)
+	if err != nil {
+		_ = writer.Close()
+		_ = os.RemoveAll(dir)
+		unregister()
+		return err
+	}
+
+	launch.outputDir = dir
+	launch.outputWriter = writer
+	launch.addCleanup(func() {
+		if !launch.detached {
+			_ = os.RemoveAll(dir)
+		}
+		unregister()
+	})
+	launch.addCleanup(func() {
+		launch.closeOutputWriter()
+		_ = reader.Close()
+	})
+
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	go copyCoreOutput(reader, output)
+	return nil
+}
+
+func (s *launchSession) closeOutputWriter() {
+	if s.outputWriter != nil {
+		_ = s.outputWriter.Close()
+		s.outputWriter = nil
+	}
+}
+
+func copyCoreOutput(reader io.Reader, output io.Writer) {
+	if _, err := io.Copy(output, reader); err != nil && !errors.Is(err, os.ErrClosed) {
+		log.Printf("读取核心输出失败: %v", err)
+	}
+}
+
+func (cm *CoreManager) ShutdownCore() error {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	cm.cancelCanaryLocked()
+	if cm.launch != nil && cm.launch.detach && cm.isRunning.Load() && cm.monitoring.Load() {
+		err := cm.detachLocked()
+		if err == nil {
+			return nil
+		}
+		log.Printf("脱离核心进程失败，改为停止核心: %v", err)
+	}
+	return cm.stopCoreLocked()
+}
+
+func (cm *CoreManager) detachLocked() error {
+	launch := cm.launch
+	pid := cm.pid.Load()
+	if pid <= 0 {
+		return fmt.Errorf("核心进程未运行")
+	}
+	identity, err := readCoreProcessIdentity(pid)
+	if err != nil {
+		return err
+	}
+
+	state := detachedCoreState{
+		PID:            pid,
+		CreateTime:     identity.CreateTime,
+		Exe:            identity.Exe,
+		Cgroup:         identity.Cgroup,
+		SourcePath:     launch.sourcePath,
+		ExecutablePath: launch.executablePath,
+		SHA256:         launch.binaryHash,
+		ControllerNet:  launch.controllerNet,
+		ControllerAddr: launch.controllerAddr,
+		HookSocket:     launch.hookUpFile,
+		HookToken:      launch.hookToken,
+		OutputDir:      launch.outputDir,
+		SandboxRoot:    launch.sandboxRoot,
+		SandboxMode:    launch.sandboxMode,
+		Profile:        launch.profile,
+		DetachedAt:     time.Now(),
+	}
+	state.FileOwner = desiredFileOwner(launch.fileAccess)
+	if err := saveDetachedCoreState(state); err != nil {
+		return err
+	}
+
+	launch.detached = true
+	cm.monitoring.Store(false)
+	cm.signalStopLocked()
+	cm.cleanupLocked()
+	log.Printf("核心进程已脱离 service 继续运行 (PID: %d)", pid)
+	return nil
+}
+
+// 服务启动时重新接管上次脱离运行的核心，返回是否接管成功
+func (cm *CoreManager) ReattachDetachedCore() (bool, error) {
+	state, err := loadDetachedCoreState()
+	if err != nil || state == nil {
+		return false, err
+	}
+	removeDetachedCoreState()
+
+	identity, err := readCoreProcessIdentity(state.PID)
+	if err == nil && (identity.CreateTime != state.CreateTime || identity.Exe != state.Exe || identity.Cgroup != state.Cgroup) {
+		err = fmt.Errorf("核心进程身份校验失败 (PID: %d)", state.PID)
+	}
+	if err != nil {
+		cleanupDetachedCoreResources(state)
+		return false, err
+	}
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	if cm.isRunning.Load() {
+		return false, fmt.Errorf("核心进程已在运行中")
+	}
+
+	controller := newProcessController()
+	launch := reattachLaunchSession(state)
+	// 身份已确认但无法恢复接管时停止核心，避免遗留无人管理的进程
+	abandon := func(err error) (bool, error) {
+		if stopErr := controller.Stop(state.PID); stopErr != nil {
+			log.Printf("停止无法接管的核心进程失败: %v", stopErr)
+		}
+		controller.Close()
+		launch.cleanupNow()
+		return false, err
+	}
+
+	reader, err := openCoreOutputReader(state.OutputDir)
+	if err != nil {
+		return abandon(err)
+	}
+	logWriter := newBoundedLogWriter(coreLogSettings{
+		path:     launch.logPath,
+		saveLogs: launch.saveLogs,
+		maxBytes: launch.maxLogBytes,
+		access:   launch.fileAccess,
+	})
+	launch.logWriter = logWriter
+	logEventWatcher := newCoreLogEventWatcher(cm)
+	launch.addCleanup(func() {
+		if err := logWriter.Close(); err != nil {
+			log.Printf("关闭核心日志文件失败: %v", err)
+		}
+	})
+	launch.addCleanup(logEventWatcher.Stop)
+	launch.addCleanup(func() {
+		_ = reader.Close()
+	})
+	go copyCoreOutput(reader, io.MultiWriter(logEventWatcher, logWriter))
+
+	if err := controller.Attach(state.PID); err != nil {
+		return abandon(fmt.Errorf("附加核心进程控制失败：%w", err))
+	}
+
+	cm.cmd = nil
+	cm.controller = controller
+	cm.launch = launch
+	cm.pid.Store(state.PID)
+	cm.updateStartTimeFromPIDLocked(state.PID)
+	cm.isRunning.Store(true)
+	cm.stopChan = make(chan struct{})
+	cm.monitoring.Store(true)
+	cm.startPIDPollingLocked(cm.stopChan)
+	if launch.readyNotify != nil {
+		go cm.monitorStartupNotifications(launch, cm.stopChan)
+	}
+
+	log.Printf("已重新接管脱离运行的核心进程 (PID: %d)", state.PID)
+	event := cm.newCoreEvent(CoreEventReattached, "核心进程已重新接管", nil, state.PID, 0)
+	event.Data = map[string]string{"detached_at": state.DetachedAt.Format(time.RFC3339)}
+	if state.SHA256 != "" {
+		event.Data["sha256"] = state.SHA256
+	}
+	cm.publishCoreEvent(event)
+	return true, nil
+}
+
+func reattachLaunchSession(state *detachedCoreState) *launchSession {
+	settings := coreLogSettingsFromProfile(state.Profile, state.FileOwner.fileAccess())
+
+	launch := &launchSession{
+		sourcePath:     state.SourcePath,
+		executablePath: state.ExecutablePath,
+		binaryHash:     state.SHA256,
+		createTime:     state.CreateTime,
+		coreExe:        state.Exe,
+		hookUpFile:     state.HookSocket,
+		hookToken:      state.HookToken,
+		cpuPriority:    state.Profile.Priority,
+		logPath:        settings.path,
+		saveLogs:       settings.saveLogs,
+		maxLogBytes:    settings.maxBytes,
+		fileAccess:     settings.access,
+		controllerNet:  state.ControllerNet,
+		controllerAddr: state.ControllerAddr,
+		profile:        state.Profile,
+		sandboxMode:    state.SandboxMode,
+		sandboxRoot:    state.SandboxRoot,
+		outputDir:      state.OutputDir,
+		detach:         true,
+	}
+
+	unregisterRoots := registerDetachedCoreRoots(state)
+	launch.cleanup = func() {
+		if !launch.detached {
+			cleanupDetachedCoreResources(state)
+		}
+		unregisterRoots()
+	}
+
+	if state.HookSocket != "" && state.HookToken != "" {
+		hook, err := reopenCoreStartupHook(state.HookSocket, state.HookToken)
+		if err != nil {
+			log.Printf("恢复核心启动通知失败: %v", err)
+		} else {
+			launch.readyNotify = hook.notifications
+			launch.addCleanup(hook.closeListener)
+		}
+	}
+	return launch
+}
+
+func detachedCoreRoots(state *detachedCoreState) []string {
+	roots := []string{state.OutputDir, state.SandboxRoot}
+	if state.HookSocket != "" {
+		roots = append(roots, filepath.Dir(state.HookSocket))
+	}
+	if state.ControllerNet == "unix" && state.ControllerAddr != "" {
+		roots = append(roots, filepath.Dir(state.ControllerAddr))
+	}
+	return roots
+}
+
+func registerDetachedCoreRoots(state *detachedCoreState) func() {
+	var unregister []func()
+	for _, root := range detachedCoreRoots(state) {
+		unregister = append(unregister, registerActiveSandboxRoot(root))
+	}
+	return func() {
+		for _, fn := range unregister {
+			fn()
+		}
+	}
+}
+
+func cleanupDetachedCoreResources(state *detachedCoreState) {
+	if state.SandboxRoot != "" {
+		if err := cleanupDetachedSandboxRoot(state.SandboxRoot); err != nil {
+			logSandboxCleanupError(err)
+		}
+	}
+	for _, root := range detachedCoreRoots(state) {
+		if root == "" || root == state.SandboxRoot {
+			continue
+		}
+		if err := os.RemoveAll(root); err != nil {
+			log.Printf("清理核心临时目录失败: %v", err)
+		}
+	}
+}
+
+func saveDetachedCoreState(state detachedCoreState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化核心脱离状态失败：%w", err)
+	}
+	path := detachedCoreStatePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("创建核心配置目录失败：%w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("保存核心脱离状态失败：%w", err)
+	}
+	return nil
+}
+
+func loadDetachedCoreState() (*detachedCoreState, error) {
+	data, err := os.ReadFile(detachedCoreStatePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取核心脱离状态失败：%w", err)
+	}
+
+	var state detachedCoreState
+	if err := json.Unmarshal(data, &state); err != nil {
+		removeDetachedCoreState()
+		return nil, fmt.Errorf("解析核心脱离状态失败：%w", err)
+	}
+	return &state, nil
+}
+
+func removeDetachedCoreState() {
+	if err := os.Remove(detachedCoreStatePath()); err != nil && !os.IsNotExist(err) {
+		log.Printf("删除核心脱离状态失败: %v", err)
+	}
+}
+
+func detachedCoreStatePath() string {
+	return filepath.Join(serviceConfigDir(), "sparkle", "core", "detached_core.json")
+}
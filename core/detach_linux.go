@@ -0,0 +1,100 @@
+//go:build linux
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	detachedCoreCgroup = "/sys/fs/cgroup/sparkle-core"
+	coreOutputPipeSize = 1 << 20
+)
+
+func detachSupported() bool {
+	return true
+}
+
+func createCoreOutputFIFO() (string, *os.File, error) {
+	dir, err := os.MkdirTemp("", "sparkle-core-output-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("创建核心输出目录失败：%w", err)
+	}
+	if err := os.Chmod(dir, 0o700); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("设置核心输出目录权限失败：%w", err)
+	}
+
+	path := filepath.Join(dir, coreOutputFIFOName)
+	if err := unix.Mkfifo(path, 0o600); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("创建核心输出 FIFO 失败：%w", err)
+	}
+
+	// 核心一侧以读写方式打开，service 退出后写入不会触发 SIGPIPE
+	fd, err := unix.Open(path, unix.O_RDWR|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("打开核心输出 FIFO 失败：%w", err)
+	}
+	if _, err := unix.FcntlInt(uintptr(fd), unix.F_SETPIPE_SZ, coreOutputPipeSize); err != nil {
+		log.Printf("调整核心输出 FIFO 缓冲区失败: %v", err)
+	}
+	return dir, os.NewFile(uintptr(fd), path), nil
+}
+
+func openCoreOutputReader(dir string) (*os.File, error) {
+	path := filepath.Join(dir, coreOutputFIFOName)
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取核心输出 FIFO 失败：%w", err)
+	}
+	if info.Mode()&os.ModeNamedPipe == 0 {
+		return nil, fmt.Errorf("核心输出路径不是 FIFO: %s", path)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDONLY|unix.O_NONBLOCK|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, fmt.Errorf("打开核心输出 FIFO 失败：%w", err)
+	}
+	return file, nil
+}
+
+// 将脱离模式的核心移出 service 所在的 cgroup，避免服务管理器停止服务时一并结束核心
+func moveToDetachedCoreCgroup(pid int32) error {
+	var stat unix.Statfs_t
+	if err := unix.Statfs("/sys/fs/cgroup", &stat); err != nil {
+		return fmt.Errorf("读取 cgroup 挂载信息失败：%w", err)
+	}
+	if stat.Type != unix.CGROUP2_SUPER_MAGIC {
+		return fmt.Errorf("未使用 cgroup v2")
+	}
+	if err := os.Mkdir(detachedCoreCgroup, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("创建核心 cgroup 失败：%w", err)
+	}
+	procs := filepath.Join(detachedCoreCgroup, "cgroup.procs")
+	if err := os.WriteFile(procs, []byte(strconv.Itoa(int(pid))), 0o644); err != nil {
+		return fmt.Errorf("移动核心进程到独立 cgroup 失败：%w", err)
+	}
+	return nil
+}
+
+func readProcessCgroup(pid int32) (string, error) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(int(pid)), "cgroup"))
+	if err != nil {
+		return "", fmt.Errorf("读取核心进程 cgroup 失败：%w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func cleanupDetachedSandboxRoot(root string) error {
+	return cleanupLinuxSandboxRoot(root)
+}
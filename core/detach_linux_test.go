@@ -0,0 +1,147 @@
+//go:build linux
+
+package core
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDetachedCoreStateRoundTrip(t *testing.T) {
+	t.Setenv("SPARKLE_CONFIG_DIR", t.TempDir())
+
+	if state, err := loadDetachedCoreState(); state != nil || err != nil {
+		t.Fatalf("loadDetachedCoreState() without file = %+v, %v", state, err)
+	}
+
+	want := detachedCoreState{
+		PID:            1234,
+		CreateTime:     1700000000000,
+		Exe:            "/usr/bin/mihomo",
+		Cgroup:         "0::/sparkle",
+		SourcePath:     "/usr/bin/mihomo",
+		ExecutablePath: "/usr/bin/mihomo",
+		ControllerNet:  "unix",
+		ControllerAddr: "/tmp/sparkle-controller/mihomo.sock",
+		HookSocket:     "/tmp/sparkle-hook/up.sock",
+		HookToken:      "token",
+		OutputDir:      "/tmp/sparkle-output",
+		Profile:        LaunchProfile{CorePath: "/usr/bin/mihomo", Args: []string{"-d", "/etc/mihomo"}},
+		FileOwner:      &DesiredFileOwner{UID: 1000, GID: 1000},
+		DetachedAt:     time.Now().UTC().Truncate(time.Second),
+	}
+	if err := saveDetachedCoreState(want); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(detachedCoreStatePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("state file mode = %04o, want 0600", info.Mode().Perm())
+	}
+
+	got, err := loadDetachedCoreState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Fatalf("loadDetachedCoreState() = %+v, want %+v", *got, want)
+	}
+}
+
+func TestReattachDetachedCoreRejectsInvalidState(t *testing.T) {
+	sleepPath, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep not found")
+	}
+	cmd := exec.Command(sleepPath, "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	pid := int32(cmd.Process.Pid)
+	identity, err := readCoreProcessIdentity(pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		modify  func(*detachedCoreState)
+		wantErr string
+	}{
+		{name: "corrupt state file", data: "{", wantErr: "解析核心脱离状态失败"},
+		{name: "create time mismatch", modify: func(state *detachedCoreState) { state.CreateTime++ }, wantErr: "身份校验失败"},
+		{name: "executable mismatch", modify: func(state *detachedCoreState) { state.Exe = "/usr/bin/mihomo" }, wantErr: "身份校验失败"},
+		{name: "cgroup mismatch", modify: func(state *detachedCoreState) { state.Cgroup = "0::/other" }, wantErr: "身份校验失败"},
+		{name: "process gone", modify: func(state *detachedCoreState) { state.PID = 1<<22 + 1 }, wantErr: "核心进程不存在"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SPARKLE_CONFIG_DIR", t.TempDir())
+			tmp := t.TempDir()
+			state := detachedCoreState{
+				PID:        pid,
+				CreateTime: identity.CreateTime,
+				Exe:        identity.Exe,
+				Cgroup:     identity.Cgroup,
+				HookSocket: filepath.Join(tmp, "hook", "up.sock"),
+				OutputDir:  filepath.Join(tmp, "output"),
+			}
+			for _, dir := range []string{filepath.Dir(state.HookSocket), state.OutputDir} {
+				if err := os.Mkdir(dir, 0o700); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.data != "" {
+				if err := os.MkdirAll(filepath.Dir(detachedCoreStatePath()), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(detachedCoreStatePath(), []byte(tt.data), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				tt.modify(&state)
+				if err := saveDetachedCoreState(state); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cm := &CoreManager{}
+			reattached, err := cm.ReattachDetachedCore()
+			if reattached || err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ReattachDetachedCore() = %v, %v, want error %q", reattached, err, tt.wantErr)
+			}
+			if cm.isRunning.Load() || cm.pid.Load() != 0 {
+				t.Fatal("rejected state was attached")
+			}
+			if _, err := os.Stat(detachedCoreStatePath()); !os.IsNotExist(err) {
+				t.Fatalf("state file not removed: %v", err)
+			}
+			// 身份不符的进程可能已被复用为无关进程，只清理临时目录，不能停止它
+			if err := syscall.Kill(int(pid), 0); err != nil {
+				t.Fatalf("unrelated process was stopped: %v", err)
+			}
+			if tt.data != "" {
+				return
+			}
+			for _, dir := range []string{filepath.Dir(state.HookSocket), state.OutputDir} {
+				if _, err := os.Stat(dir); !os.IsNotExist(err) {
+					t.Fatalf("%s not cleaned up: %v", dir, err)
+				}
+			}
+		})
+	}
+}
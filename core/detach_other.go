@@ -0,0 +1,32 @@
+//go:build !linux
+
+package core
+
+import (
+	"fmt"
+	"os"
+)
+
+func detachSupported() bool {
+	return false
+}
+
+func createCoreOutputFIFO() (string, *os.File, error) {
+	return "", nil, fmt.Errorf("当前平台不支持核心输出 FIFO")
+}
+
+func openCoreOutputReader(_ string) (*os.File, error) {
+	return nil, fmt.Errorf("当前平台不支持核心输出 FIFO")
+}
+
+func moveToDetachedCoreCgroup(_ int32) error {
+	return nil
+}
+
+func readProcessCgroup(_ int32) (string, error) {
+	return "", nil
+}
+
+func cleanupDetachedSandboxRoot(_ string) error {
+	return nil
+}
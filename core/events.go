@@ -6,19 +6,25 @@ import (
 )
 
 const (
-	CoreEventStarting      = "starting"
-	CoreEventStarted       = "started"
-	CoreEventStopping      = "stopping"
-	CoreEventStopped       = "stopped"
-	CoreEventExited        = "exited"
-	CoreEventRestarting    = "restarting"
-	CoreEventRestartFailed = "restart_failed"
-	CoreEventTakeover      = "takeover"
-	CoreEventReady         = "ready"
-	CoreEventFailed        = "failed"
-	CoreEventLog           = "log"
+	CoreEventStarting        = "starting"
+	CoreEventStarted         = "started"
+	CoreEventStopping        = "stopping"
+	CoreEventStopped         = "stopped"
+	CoreEventExited          = "exited"
+	CoreEventRestarting      = "restarting"
+	CoreEventRestartFailed   = "restart_failed"
+	CoreEventTakeover        = "takeover"
+	CoreEventReady           = "ready"
+	CoreEventFailed          = "failed"
+	CoreEventLog             = "log"
+	CoreEventSandboxFallback = "sandbox_fallback"
+	CoreEventCanaryPassed    = "canary_passed"
+	CoreEventCanaryReverted  = "canary_reverted"
+	CoreEventReattached      = "reattached"
 )
 
+const CoreEventCauseServiceBoot = "service_boot"
+
 type CoreEvent struct {
 	Seq     uint64            `json:"seq,omitempty"`
 	Type    string            `json:"type"`
@@ -26,6 +32,7 @@ type CoreEvent struct {
 	Running bool              `json:"running"`
 	PID     int32             `json:"pid,omitempty"`
 	OldPID  int32             `json:"old_pid,omitempty"`
+	Cause   string            `json:"cause,omitempty"`
 	Message string            `json:"message,omitempty"`
 	Error   string            `json:"error,omitempty"`
 	Data    map[string]string `json:"data,omitempty"`
@@ -1,7 +1,9 @@
 package core
 
 type fileAccess struct {
+	userID  int
 	groupID int
+	sid     string
 	ok      bool
 }
 
@@ -9,23 +11,45 @@ type LaunchOption func(*launchOptions)
 
 type launchOptions struct {
 	fileAccess fileAccess
+	dryRun     bool
+	cause      string
 }
 
-func WithLogFileGroup(groupID uint32) LaunchOption {
+func WithLogFileOwner(userID uint32, groupID uint32) LaunchOption {
 	return func(options *launchOptions) {
 		options.fileAccess = fileAccess{
+			userID:  int(userID),
 			groupID: int(groupID),
 			ok:      true,
 		}
 	}
 }
 
+// Windows 上按请求用户的 SID 放行其配置目录下的日志路径
+func WithLogFileOwnerSID(sid string) LaunchOption {
+	return func(options *launchOptions) {
+		options.fileAccess = fileAccess{sid: sid, ok: true}
+	}
+}
+
 func withFileAccess(access fileAccess) LaunchOption {
 	return func(options *launchOptions) {
 		options.fileAccess = access
 	}
 }
 
+func withDryRun() LaunchOption {
+	return func(options *launchOptions) {
+		options.dryRun = true
+	}
+}
+
+func withLaunchCause(cause string) LaunchOption {
+	return func(options *launchOptions) {
+		options.cause = cause
+	}
+}
+
 func collectLaunchOptions(options []LaunchOption) launchOptions {
 	var collected launchOptions
 	for _, option := range options {
@@ -1,52 +1,189 @@
-//go:build linux || darwin
+//go:build !windows
 
 package core
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
 )
 
 func ensureCoreLogDir(dir string, access fileAccess) error {
-	if err := os.MkdirAll(dir, 0o775); err != nil {
+	file, err := openCoreLogDir(dir, access)
+	if err != nil {
 		return err
 	}
+	return file.Close()
+}
+
+func openCoreLogFile(path string, access fileAccess) (*os.File, error) {
+	dir, err := openCoreLogDir(filepath.Dir(path), access)
+	if err != nil {
+		return nil, fmt.Errorf("创建核心日志目录失败：%w", err)
+	}
+	defer dir.Close()
+
+	fd, err := unix.Openat(int(dir.Fd()), filepath.Base(path), unix.O_RDWR|unix.O_APPEND|unix.O_CREAT|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0o600)
+	if err != nil {
+		if errors.Is(err, unix.ELOOP) {
+			return nil, fmt.Errorf("核心日志文件 %s 是符号链接", path)
+		}
+		return nil, fmt.Errorf("打开核心日志文件失败：%w", err)
+	}
+	file := os.NewFile(uintptr(fd), path)
+
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("检查核心日志文件失败：%w", err)
+	}
+	if stat.Mode&unix.S_IFMT != unix.S_IFREG {
+		_ = file.Close()
+		return nil, fmt.Errorf("核心日志文件 %s 不是普通文件", path)
+	}
+	if stat.Nlink > 1 {
+		_ = file.Close()
+		return nil, fmt.Errorf("核心日志文件 %s 存在多个硬链接", path)
+	}
+	if err := checkCoreLogOwner(path, stat, access); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if err := applyCoreLogFileAccess(file, access); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+func openCoreLogDir(dir string, access fileAccess) (*os.File, error) {
+	root, err := coreLogRoot(dir, access)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+
+	fd, err := unix.Open(root, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("打开核心日志目录 %s 失败：%w", root, err)
+	}
+	current := root
+	if err := checkCoreLogDir(fd, current, access); err != nil {
+		_ = unix.Close(fd)
+		return nil, err
+	}
+
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		_ = unix.Close(fd)
+		return nil, err
+	}
+	if rel != "." {
+		for _, name := range strings.Split(rel, string(os.PathSeparator)) {
+			current = filepath.Join(current, name)
+			next, err := openCoreLogSubdir(fd, name)
+			_ = unix.Close(fd)
+			if err != nil {
+				if errors.Is(err, unix.ELOOP) || errors.Is(err, unix.ENOTDIR) {
+					return nil, fmt.Errorf("核心日志目录 %s 是符号链接或不是目录", current)
+				}
+				return nil, fmt.Errorf("打开核心日志目录 %s 失败：%w", current, err)
+			}
+			fd = next
+			if err := checkCoreLogDir(fd, current, access); err != nil {
+				_ = unix.Close(fd)
+				return nil, err
+			}
+		}
+	}
+
+	file := os.NewFile(uintptr(fd), dir)
+	if err := applyCoreLogDirAccess(file, access); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+func openCoreLogSubdir(parent int, name string) (int, error) {
+	flags := unix.O_RDONLY | unix.O_DIRECTORY | unix.O_NOFOLLOW | unix.O_CLOEXEC
+	fd, err := unix.Openat(parent, name, flags, 0)
+	if !errors.Is(err, unix.ENOENT) {
+		return fd, err
+	}
+	if err := unix.Mkdirat(parent, name, 0o775); err != nil && !errors.Is(err, unix.EEXIST) {
+		return -1, err
+	}
+	return unix.Openat(parent, name, flags, 0)
+}
+
+func checkCoreLogDir(fd int, path string, access fileAccess) error {
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		return fmt.Errorf("检查核心日志目录失败：%w", err)
+	}
+	if err := checkCoreLogOwner(path, stat, access); err != nil {
+		return err
+	}
+	if stat.Mode&0o002 != 0 && stat.Mode&unix.S_ISVTX == 0 {
+		return fmt.Errorf("核心日志目录 %s 可被任意用户写入", path)
+	}
+	return nil
+}
+
+func checkCoreLogOwner(path string, stat unix.Stat_t, access fileAccess) error {
+	if stat.Uid == 0 || int(stat.Uid) == os.Geteuid() {
+		return nil
+	}
+	if access.ok && int(stat.Uid) == access.userID {
+		return nil
+	}
+	return fmt.Errorf("核心日志路径 %s 的所有者 (uid=%d) 不是 service 或请求用户", path, stat.Uid)
+}
+
+func applyCoreLogDirAccess(dir *os.File, access fileAccess) error {
 	if !access.ok {
 		return nil
 	}
 	if os.Geteuid() == 0 {
-		if err := os.Chown(dir, -1, access.groupID); err != nil {
+		if err := dir.Chown(-1, access.groupID); err != nil {
 			return fmt.Errorf("设置核心日志目录用户组失败：%w", err)
 		}
 	}
 
-	info, err := os.Stat(dir)
+	info, err := dir.Stat()
 	if err != nil {
 		return err
 	}
 	mode := info.Mode() | 0o070 | os.ModeSetgid
-	if err := os.Chmod(dir, mode); err != nil {
+	if err := dir.Chmod(mode); err != nil {
 		return fmt.Errorf("设置核心日志目录权限失败：%w", err)
 	}
 	return nil
 }
 
-func applyCoreLogFileAccess(path string, access fileAccess) error {
+func applyCoreLogFileAccess(file *os.File, access fileAccess) error {
 	if !access.ok {
 		return nil
 	}
 	if os.Geteuid() == 0 {
-		if err := os.Chown(path, -1, access.groupID); err != nil {
+		if err := file.Chown(-1, access.groupID); err != nil {
 			return fmt.Errorf("设置核心日志文件用户组失败：%w", err)
 		}
 	}
 
-	info, err := os.Stat(path)
+	info, err := file.Stat()
 	if err != nil {
 		return err
 	}
 	mode := info.Mode() | 0o060
-	if err := os.Chmod(path, mode); err != nil {
+	if err := file.Chmod(mode); err != nil {
 		return fmt.Errorf("设置核心日志文件权限失败：%w", err)
 	}
 	return nil
@@ -0,0 +1,180 @@
+//go:build windows
+
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	coreLogDirAccess  = windows.FILE_LIST_DIRECTORY | windows.FILE_TRAVERSE | windows.FILE_READ_ATTRIBUTES | windows.READ_CONTROL | windows.SYNCHRONIZE
+	coreLogFileAccess = windows.GENERIC_READ | windows.FILE_APPEND_DATA | windows.FILE_WRITE_ATTRIBUTES | windows.FILE_WRITE_EA | windows.STANDARD_RIGHTS_WRITE | windows.SYNCHRONIZE
+	coreLogShareMode  = windows.FILE_SHARE_READ | windows.FILE_SHARE_WRITE | windows.FILE_SHARE_DELETE
+)
+
+func ensureCoreLogDir(dir string, access fileAccess) error {
+	handle, err := openCoreLogDir(dir, access)
+	if err != nil {
+		return err
+	}
+	return windows.CloseHandle(handle)
+}
+
+func openCoreLogFile(path string, access fileAccess) (*os.File, error) {
+	dir, err := openCoreLogDir(filepath.Dir(path), access)
+	if err != nil {
+		return nil, fmt.Errorf("创建核心日志目录失败：%w", err)
+	}
+	defer windows.CloseHandle(dir)
+
+	// 以 FILE_OPEN_REPARSE_POINT 打开链接本身而不跟随，再按属性拒绝
+	handle, err := openCoreLogEntry(dir, filepath.Base(path), coreLogFileAccess, windows.FILE_NON_DIRECTORY_FILE)
+	if err != nil {
+		return nil, fmt.Errorf("打开核心日志文件失败：%w", err)
+	}
+
+	var info windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(handle, &info); err != nil {
+		_ = windows.CloseHandle(handle)
+		return nil, fmt.Errorf("检查核心日志文件失败：%w", err)
+	}
+	if info.FileAttributes&windows.FILE_ATTRIBUTE_REPARSE_POINT != 0 {
+		_ = windows.CloseHandle(handle)
+		return nil, fmt.Errorf("核心日志文件 %s 是符号链接或重解析点", path)
+	}
+	if info.FileAttributes&windows.FILE_ATTRIBUTE_DIRECTORY != 0 {
+		_ = windows.CloseHandle(handle)
+		return nil, fmt.Errorf("核心日志文件 %s 不是普通文件", path)
+	}
+	if info.NumberOfLinks > 1 {
+		_ = windows.CloseHandle(handle)
+		return nil, fmt.Errorf("核心日志文件 %s 存在多个硬链接", path)
+	}
+	if err := checkCoreLogOwner(handle, path, access); err != nil {
+		_ = windows.CloseHandle(handle)
+		return nil, err
+	}
+	return os.NewFile(uintptr(handle), path), nil
+}
+
+func openCoreLogDir(dir string, access fileAccess) (windows.Handle, error) {
+	root, err := coreLogRoot(dir, access)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return 0, err
+	}
+
+	rootPath, err := windows.UTF16PtrFromString(root)
+	if err != nil {
+		return 0, err
+	}
+	handle, err := windows.CreateFile(rootPath, coreLogDirAccess, coreLogShareMode, nil, windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return 0, fmt.Errorf("打开核心日志目录 %s 失败：%w", root, err)
+	}
+	current := root
+	if err := checkCoreLogDir(handle, current, access); err != nil {
+		_ = windows.CloseHandle(handle)
+		return 0, err
+	}
+
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		_ = windows.CloseHandle(handle)
+		return 0, err
+	}
+	if rel == "." {
+		return handle, nil
+	}
+	for _, name := range strings.Split(rel, string(os.PathSeparator)) {
+		current = filepath.Join(current, name)
+		next, err := openCoreLogEntry(handle, name, coreLogDirAccess, windows.FILE_DIRECTORY_FILE)
+		_ = windows.CloseHandle(handle)
+		if err != nil {
+			return 0, fmt.Errorf("打开核心日志目录 %s 失败：%w", current, err)
+		}
+		handle = next
+		if err := checkCoreLogDir(handle, current, access); err != nil {
+			_ = windows.CloseHandle(handle)
+			return 0, err
+		}
+	}
+	return handle, nil
+}
+
+// 相对已打开的目录句柄逐级打开或创建，等价于 Linux 上的 openat
+func openCoreLogEntry(parent windows.Handle, name string, desiredAccess uint32, options uint32) (windows.Handle, error) {
+	objectName, err := windows.NewNTUnicodeString(name)
+	if err != nil {
+		return 0, err
+	}
+	attributes := windows.OBJECT_ATTRIBUTES{
+		RootDirectory: parent,
+		ObjectName:    objectName,
+		Attributes:    windows.OBJ_CASE_INSENSITIVE,
+	}
+	attributes.Length = uint32(unsafe.Sizeof(attributes))
+
+	var handle windows.Handle
+	var status windows.IO_STATUS_BLOCK
+	err = windows.NtCreateFile(
+		&handle,
+		desiredAccess,
+		&attributes,
+		&status,
+		nil,
+		windows.FILE_ATTRIBUTE_NORMAL,
+		coreLogShareMode,
+		windows.FILE_OPEN_IF,
+		options|windows.FILE_OPEN_REPARSE_POINT|windows.FILE_SYNCHRONOUS_IO_NONALERT,
+		0,
+		0,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return handle, nil
+}
+
+func checkCoreLogDir(handle windows.Handle, path string, access fileAccess) error {
+	var info windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(handle, &info); err != nil {
+		return fmt.Errorf("检查核心日志目录失败：%w", err)
+	}
+	if info.FileAttributes&windows.FILE_ATTRIBUTE_REPARSE_POINT != 0 {
+		return fmt.Errorf("核心日志目录 %s 是符号链接或重解析点", path)
+	}
+	if info.FileAttributes&windows.FILE_ATTRIBUTE_DIRECTORY == 0 {
+		return fmt.Errorf("核心日志目录 %s 不是目录", path)
+	}
+	return checkCoreLogOwner(handle, path, access)
+}
+
+func checkCoreLogOwner(handle windows.Handle, path string, access fileAccess) error {
+	sd, err := windows.GetSecurityInfo(handle, windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION)
+	if err != nil {
+		return fmt.Errorf("读取核心日志路径 %s 所有者失败：%w", path, err)
+	}
+	owner, _, err := sd.Owner()
+	if err != nil {
+		return fmt.Errorf("读取核心日志路径 %s 所有者失败：%w", path, err)
+	}
+	if owner.IsWellKnown(windows.WinLocalSystemSid) || owner.IsWellKnown(windows.WinBuiltinAdministratorsSid) {
+		return nil
+	}
+	if current, err := windows.GetCurrentProcessToken().GetTokenUser(); err == nil && owner.Equals(current.User.Sid) {
+		return nil
+	}
+	if access.ok && access.sid != "" && strings.EqualFold(owner.String(), access.sid) {
+		return nil
+	}
+	return fmt.Errorf("核心日志路径 %s 的所有者 (%s) 不是 SYSTEM、Administrators、service 或请求用户", path, owner.String())
+}
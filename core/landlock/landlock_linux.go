@@ -0,0 +1,148 @@
+//go:build linux
+
+package landlock
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const MinABI = 3
+
+const rulePathBeneath = 1
+
+const fileAccess = unix.LANDLOCK_ACCESS_FS_EXECUTE |
+	unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+	unix.LANDLOCK_ACCESS_FS_READ_FILE |
+	unix.LANDLOCK_ACCESS_FS_TRUNCATE |
+	unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+
+const readAccess = unix.LANDLOCK_ACCESS_FS_EXECUTE |
+	unix.LANDLOCK_ACCESS_FS_READ_FILE |
+	unix.LANDLOCK_ACCESS_FS_READ_DIR
+
+type Rule struct {
+	Path     string
+	Writable bool
+}
+
+type rulesetAttr struct {
+	handledAccessFS uint64
+}
+
+type pathBeneathAttr struct {
+	allowedAccess uint64
+	parentFd      int32
+}
+
+func ABI() (int, error) {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return 0, fmt.Errorf("内核不支持 Landlock：%w", errno)
+	}
+	return int(abi), nil
+}
+
+func Supported() error {
+	_, err := requireABI()
+	return err
+}
+
+func requireABI() (int, error) {
+	abi, err := ABI()
+	if err != nil {
+		return 0, err
+	}
+	if abi < MinABI {
+		return 0, fmt.Errorf("内核 Landlock ABI 版本过低：%d，至少需要 %d", abi, MinABI)
+	}
+	return abi, nil
+}
+
+func RestrictSelf(rules []Rule) error {
+	abi, err := requireABI()
+	if err != nil {
+		return err
+	}
+
+	handled := handledAccess(abi)
+	attr := rulesetAttr{handledAccessFS: handled}
+	rulesetFd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("创建 Landlock 规则集失败：%w", errno)
+	}
+	defer unix.Close(int(rulesetFd))
+
+	for _, rule := range rules {
+		if err := addPathRule(int(rulesetFd), rule, handled); err != nil {
+			return err
+		}
+	}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("设置 no_new_privs 失败：%w", err)
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, rulesetFd, 0, 0); errno != 0 {
+		return fmt.Errorf("启用 Landlock 限制失败：%w", errno)
+	}
+	return nil
+}
+
+func addPathRule(rulesetFd int, rule Rule, handled uint64) error {
+	fd, err := unix.Open(rule.Path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("打开 Landlock 规则路径失败 %s：%w", rule.Path, err)
+	}
+	defer unix.Close(fd)
+
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		return fmt.Errorf("读取 Landlock 规则路径失败 %s：%w", rule.Path, err)
+	}
+
+	access := uint64(readAccess)
+	if rule.Writable {
+		access = handled
+	}
+	if stat.Mode&unix.S_IFMT != unix.S_IFDIR {
+		access &= fileAccess
+	}
+	access &= handled
+
+	attr := pathBeneathAttr{
+		allowedAccess: access,
+		parentFd:      int32(fd),
+	}
+	if _, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(rulesetFd), rulePathBeneath, uintptr(unsafe.Pointer(&attr)), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("添加 Landlock 规则失败 %s：%w", rule.Path, errno)
+	}
+	return nil
+}
+
+func handledAccess(abi int) uint64 {
+	access := uint64(unix.LANDLOCK_ACCESS_FS_EXECUTE |
+		unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_FILE |
+		unix.LANDLOCK_ACCESS_FS_MAKE_CHAR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_DIR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_FIFO |
+		unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SYM)
+	if abi >= 2 {
+		access |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		access |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	if abi >= 5 {
+		access |= unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+	}
+	return access
+}
@@ -0,0 +1,97 @@
+//go:build linux
+
+package landlock
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestHandledAccess(t *testing.T) {
+	tests := []struct {
+		abi      int
+		included uint64
+		excluded uint64
+	}{
+		{abi: 1, included: unix.LANDLOCK_ACCESS_FS_MAKE_SYM, excluded: unix.LANDLOCK_ACCESS_FS_REFER | unix.LANDLOCK_ACCESS_FS_TRUNCATE | unix.LANDLOCK_ACCESS_FS_IOCTL_DEV},
+		{abi: 2, included: unix.LANDLOCK_ACCESS_FS_REFER, excluded: unix.LANDLOCK_ACCESS_FS_TRUNCATE | unix.LANDLOCK_ACCESS_FS_IOCTL_DEV},
+		{abi: 3, included: unix.LANDLOCK_ACCESS_FS_REFER | unix.LANDLOCK_ACCESS_FS_TRUNCATE, excluded: unix.LANDLOCK_ACCESS_FS_IOCTL_DEV},
+		{abi: 5, included: unix.LANDLOCK_ACCESS_FS_TRUNCATE | unix.LANDLOCK_ACCESS_FS_IOCTL_DEV},
+	}
+
+	for _, tt := range tests {
+		handled := handledAccess(tt.abi)
+		if handled&tt.included != tt.included || handled&tt.excluded != 0 {
+			t.Fatalf("handledAccess(%d) = %#x, want %#x set and %#x clear", tt.abi, handled, tt.included, tt.excluded)
+		}
+	}
+}
+
+// 在子进程中启用限制，避免影响测试进程本身
+func TestRestrictSelf(t *testing.T) {
+	if dir := os.Getenv("SPARKLE_LANDLOCK_TEST_DIR"); dir != "" {
+		runRestrictedChild(dir)
+		return
+	}
+	if err := Supported(); err != nil {
+		t.Skip(err)
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"readonly", "writable", "outside"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name, "file"), []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRestrictSelf$")
+	cmd.Env = append(os.Environ(), "SPARKLE_LANDLOCK_TEST_DIR="+dir)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("restricted child failed: %v\n%s", err, output)
+	}
+}
+
+func runRestrictedChild(dir string) {
+	fail := func(message string) {
+		os.Stderr.WriteString(message + "\n")
+		os.Exit(1)
+	}
+	if err := RestrictSelf([]Rule{
+		{Path: filepath.Join(dir, "readonly")},
+		{Path: filepath.Join(dir, "writable"), Writable: true},
+	}); err != nil {
+		fail("RestrictSelf: " + err.Error())
+	}
+
+	tests := []struct {
+		name    string
+		op      func() error
+		allowed bool
+	}{
+		{name: "read readonly", op: func() error { _, err := os.ReadFile(filepath.Join(dir, "readonly", "file")); return err }, allowed: true},
+		{name: "write readonly", op: func() error { return os.WriteFile(filepath.Join(dir, "readonly", "new"), nil, 0o644) }},
+		{name: "write writable", op: func() error { return os.WriteFile(filepath.Join(dir, "writable", "new"), nil, 0o644) }, allowed: true},
+		{name: "remove writable", op: func() error { return os.Remove(filepath.Join(dir, "writable", "file")) }, allowed: true},
+		{name: "read outside", op: func() error { _, err := os.ReadFile(filepath.Join(dir, "outside", "file")); return err }},
+		{name: "write outside", op: func() error { return os.WriteFile(filepath.Join(dir, "outside", "new"), nil, 0o644) }},
+	}
+	for _, tt := range tests {
+		err := tt.op()
+		switch {
+		case tt.allowed && err != nil:
+			fail(tt.name + ": " + err.Error())
+		case !tt.allowed && !errors.Is(err, os.ErrPermission):
+			fail(tt.name + ": expected permission error")
+		}
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,26 @@
+//go:build !linux
+
+package landlock
+
+import "fmt"
+
+const MinABI = 3
+
+type Rule struct {
+	Path     string
+	Writable bool
+}
+
+func ABI() (int, error) {
+	return 0, fmt.Errorf("当前平台不支持 Landlock")
+}
+
+func Supported() error {
+	_, err := ABI()
+	return err
+}
+
+func RestrictSelf(_ []Rule) error {
+	_, err := ABI()
+	return err
+}
@@ -7,7 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"maps"
+	"log"
 	"net"
 	"os"
 	"path/filepath"
@@ -21,29 +21,43 @@ import (
 )
 
 type LaunchProfile struct {
-	CorePath         string            `json:"core_path,omitempty"`
-	Args             []string          `json:"args,omitempty"`
-	SafePaths        []string          `json:"safe_paths,omitempty"`
-	Env              map[string]string `json:"env,omitempty"`
-	Priority         string            `json:"mihomo_cpu_priority,omitempty"`
-	LogPath          string            `json:"log_path,omitempty"`
-	SaveLogs         *bool             `json:"save_logs,omitempty"`
-	MaxLogFileSizeMB int               `json:"max_log_file_size_mb,omitempty"`
+	CorePath         string                    `json:"core_path,omitempty"`
+	Args             []string                  `json:"args,omitempty"`
+	SafePaths        []string                  `json:"safe_paths,omitempty"`
+	Env              map[string]LaunchEnvValue `json:"env,omitempty"`
+	Priority         string                    `json:"mihomo_cpu_priority,omitempty"`
+	LogPath          string                    `json:"log_path,omitempty"`
+	SaveLogs         *bool                     `json:"save_logs,omitempty"`
+	MaxLogFileSizeMB int                       `json:"max_log_file_size_mb,omitempty"`
+	Sandbox          *LaunchSandbox            `json:"sandbox,omitempty"`
+	Scheduling       *LaunchScheduling         `json:"scheduling,omitempty"`
+	Autostart        *bool                     `json:"autostart,omitempty"`
+	DetachOnStop     *bool                     `json:"detach_on_service_stop,omitempty"`
 }
 
-type LaunchProfilePatch struct {
-	LogPath          *string `json:"log_path,omitempty"`
-	SaveLogs         *bool   `json:"save_logs,omitempty"`
-	MaxLogFileSizeMB *int    `json:"max_log_file_size_mb,omitempty"`
+const (
+	SandboxModeChroot   = "chroot"
+	SandboxModeLandlock = "landlock"
+	SandboxModeNone     = "none"
+)
+
+type LaunchSandbox struct {
+	Mode string `json:"mode,omitempty"`
 }
 
 type launchSession struct {
 	sourcePath     string
 	executablePath string
+	binary         *security.VerifiedBinary
+	binaryHash     string
+	// 核心首个进程的创建时间（毫秒）与可执行文件，用于识别核心重启后的新进程
+	createTime     int64
+	coreExe        string
 	workingDir     string
 	args           []string
 	env            []string
 	hookUpFile     string
+	hookToken      string
 	waitReady      func(context.Context) error
 	readyNotify    <-chan struct{}
 	cpuPriority    string
@@ -55,6 +69,13 @@ type launchSession struct {
 	controllerNet  string
 	controllerAddr string
 	profile        LaunchProfile
+	sandboxMode    string
+	sandboxRoot    string
+	outputDir      string
+	outputWriter   *os.File
+	detach         bool
+	detached       bool
+	dryRun         bool
 	cleanup        func()
 }
 
@@ -67,7 +88,14 @@ func (s *launchSession) cleanupNow() {
 }
 
 func LoadLaunchProfile() (LaunchProfile, error) {
-	path := launchProfilePath()
+	return LoadNamedLaunchProfile(ActiveLaunchProfileName())
+}
+
+func LoadNamedLaunchProfile(name string) (LaunchProfile, error) {
+	path, err := namedLaunchProfilePath(name)
+	if err != nil {
+		return LaunchProfile{}, err
+	}
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -84,19 +112,50 @@ func LoadLaunchProfile() (LaunchProfile, error) {
 	return normalizeLaunchProfile(profile)
 }
 
-func SaveLaunchProfile(profile LaunchProfile) error {
+func SaveLaunchProfile(profile LaunchProfile, options ...ProfileSaveOption) error {
+	return SaveNamedLaunchProfile(ActiveLaunchProfileName(), profile, options...)
+}
+
+func SaveNamedLaunchProfile(name string, profile LaunchProfile, options ...ProfileSaveOption) error {
+	launchProfileMu.Lock()
+	defer launchProfileMu.Unlock()
+
+	saveOptions := collectProfileSaveOptions(options)
+	if err := checkLaunchProfileIfMatchLocked(name, saveOptions.ifMatch); err != nil {
+		return err
+	}
+	return saveNamedLaunchProfileLocked(name, profile, saveOptions)
+}
+
+func saveNamedLaunchProfileLocked(name string, profile LaunchProfile, options profileSaveOptions) error {
+	path, err := namedLaunchProfilePath(name)
+	if err != nil {
+		return err
+	}
 	normalized, err := normalizeLaunchProfile(profile)
 	if err != nil {
 		return err
 	}
 
-	path := launchProfilePath()
+	if err := writeLaunchProfile(path, normalized); err != nil {
+		return err
+	}
+	if err := recordLaunchProfileRevision(name, normalized, options); err != nil {
+		log.Printf("记录核心启动配置历史失败: %v", err)
+	}
+	return nil
+}
+
+func writeLaunchProfile(path string, normalized LaunchProfile) error {
 	if isZeroLaunchProfile(normalized) {
 		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
 			return fmt.Errorf("清理核心启动配置失败：%w", err)
 		}
 		return nil
 	}
+	if err := enforceLaunchPolicy(normalized); err != nil {
+		return err
+	}
 
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return fmt.Errorf("创建核心配置目录失败：%w", err)
@@ -114,30 +173,6 @@ func SaveLaunchProfile(profile LaunchProfile) error {
 	return nil
 }
 
-func PatchLaunchProfile(patch LaunchProfilePatch) (LaunchProfile, error) {
-	profile, err := LoadLaunchProfile()
-	if err != nil {
-		return LaunchProfile{}, err
-	}
-
-	if patch.LogPath != nil {
-		profile.LogPath = *patch.LogPath
-	}
-	if patch.SaveLogs != nil {
-		saveLogs := *patch.SaveLogs
-		profile.SaveLogs = &saveLogs
-	}
-	if patch.MaxLogFileSizeMB != nil {
-		profile.MaxLogFileSizeMB = *patch.MaxLogFileSizeMB
-	}
-
-	if err := SaveLaunchProfile(profile); err != nil {
-		return LaunchProfile{}, err
-	}
-
-	return LoadLaunchProfile()
-}
-
 func (cm *CoreManager) prepareLaunchSession(profileOverride *LaunchProfile, options launchOptions) (*launchSession, error) {
 	profile, err := resolveLaunchProfile(profileOverride)
 	if err != nil {
@@ -149,23 +184,62 @@ func (cm *CoreManager) prepareLaunchSession(profileOverride *LaunchProfile, opti
 		return nil, err
 	}
 	profile.CorePath = corePath
+	if err := enforceLaunchPolicy(profile); err != nil {
+		return nil, err
+	}
 	saveLogs := true
 	if profile.SaveLogs != nil {
 		saveLogs = *profile.SaveLogs
 	}
-
-	if err := security.SecureBinary(corePath); err != nil {
-		return nil, err
+	if saveLogs && profile.LogPath != "" {
+		if _, err := coreLogRoot(profile.LogPath, options.fileAccess); err != nil {
+			return nil, err
+		}
 	}
-
-	hook, err := createCoreStartupHook()
+	env, err := buildLaunchEnv(profile, options.dryRun)
 	if err != nil {
 		return nil, err
 	}
 
-	args, controllerNet, controllerAddr, controllerCleanup, err := configureManagedController(profile.Args)
+	var binary *security.VerifiedBinary
+	binaryHash := ""
+	if !options.dryRun {
+		binaryOptions, err := coreBinaryOptions()
+		if err != nil {
+			return nil, err
+		}
+		binary, err = security.OpenVerifiedBinary(corePath, binaryOptions...)
+		if err != nil {
+			return nil, err
+		}
+		binaryHash = binary.SHA256
+		if err := verifyCoreBinaryTrust(binary); err != nil {
+			binary.Close()
+			return nil, err
+		}
+	}
+	closeBinary := func() {
+		if err := binary.Close(); err != nil {
+			log.Printf("关闭核心文件失败: %v", err)
+		}
+	}
+
+	// 预览启动计划时只计算路径，不创建启动通知与控制器资源
+	var hook *coreStartupHook
+	if options.dryRun {
+		hook = plannedCoreStartupHook()
+	} else {
+		hook, err = createCoreStartupHook()
+		if err != nil {
+			closeBinary()
+			return nil, err
+		}
+	}
+
+	args, controllerNet, controllerAddr, controllerCleanup, err := configureManagedController(profile.Args, options.dryRun)
 	if err != nil {
 		hook.cleanup()
+		closeBinary()
 		return nil, err
 	}
 	args = append([]string{"-post-up", hook.postUpCommand, "-post-down", hook.postDownCommand}, args...)
@@ -176,16 +250,26 @@ func (cm *CoreManager) prepareLaunchSession(profileOverride *LaunchProfile, opti
 		if controllerCleanup != nil {
 			controllerCleanup()
 		}
+		closeBinary()
 		return nil, err
 	}
 
-	return &launchSession{
+	detach := profile.DetachOnStop != nil && *profile.DetachOnStop
+	if detach && !detachSupported() {
+		log.Printf("当前平台不支持 detach_on_service_stop，已忽略")
+		detach = false
+	}
+
+	session := &launchSession{
 		sourcePath:     corePath,
 		executablePath: corePath,
+		binary:         binary,
+		binaryHash:     binaryHash,
 		workingDir:     workingDir,
 		args:           args,
-		env:            buildLaunchEnv(profile),
+		env:            env,
 		hookUpFile:     hook.upFile,
+		hookToken:      hook.token,
 		waitReady:      hook.wait,
 		readyNotify:    hook.notifications,
 		cpuPriority:    profile.Priority,
@@ -196,13 +280,24 @@ func (cm *CoreManager) prepareLaunchSession(profileOverride *LaunchProfile, opti
 		controllerNet:  controllerNet,
 		controllerAddr: controllerAddr,
 		profile:        profile,
-		cleanup: func() {
-			if controllerCleanup != nil {
-				controllerCleanup()
-			}
-			hook.cleanup()
-		},
-	}, nil
+		sandboxMode:    launchSandboxMode(profile),
+		detach:         detach,
+		dryRun:         options.dryRun,
+	}
+	session.cleanup = func() {
+		if session.detached {
+			// 核心已脱离 service 继续运行，保留控制器与启动通知目录供下次接管
+			hook.closeListener()
+			closeBinary()
+			return
+		}
+		if controllerCleanup != nil {
+			controllerCleanup()
+		}
+		hook.cleanup()
+		closeBinary()
+	}
+	return session, nil
 }
 
 func resolveLaunchProfile(profileOverride *LaunchProfile) (LaunchProfile, error) {
@@ -223,6 +318,14 @@ func normalizeLaunchProfile(profile LaunchProfile) (LaunchProfile, error) {
 		saveLogs := *profile.SaveLogs
 		normalized.SaveLogs = &saveLogs
 	}
+	if profile.Autostart != nil {
+		autostart := *profile.Autostart
+		normalized.Autostart = &autostart
+	}
+	if profile.DetachOnStop != nil {
+		detach := *profile.DetachOnStop
+		normalized.DetachOnStop = &detach
+	}
 
 	if len(profile.Args) > 0 {
 		normalized.Args = append(normalized.Args, profile.Args...)
@@ -247,16 +350,38 @@ func normalizeLaunchProfile(profile LaunchProfile) (LaunchProfile, error) {
 	}
 
 	if len(profile.Env) > 0 {
-		normalized.Env = make(map[string]string, len(profile.Env))
+		normalized.Env = make(map[string]LaunchEnvValue, len(profile.Env))
 		for key, value := range profile.Env {
 			key = strings.TrimSpace(key)
 			if key == "" {
 				return LaunchProfile{}, fmt.Errorf("环境变量名不能为空")
 			}
+			if value.Secret != "" {
+				if err := validateSecretName(value.Secret); err != nil {
+					return LaunchProfile{}, fmt.Errorf("环境变量 %s：%w", key, err)
+				}
+			}
 			normalized.Env[key] = value
 		}
 	}
 
+	if profile.Sandbox != nil {
+		mode := strings.ToLower(strings.TrimSpace(profile.Sandbox.Mode))
+		switch mode {
+		case "":
+		case SandboxModeChroot, SandboxModeLandlock, SandboxModeNone:
+			normalized.Sandbox = &LaunchSandbox{Mode: mode}
+		default:
+			return LaunchProfile{}, fmt.Errorf("不支持的沙盒模式: %s", profile.Sandbox.Mode)
+		}
+	}
+
+	scheduling, err := normalizeLaunchScheduling(profile.Scheduling)
+	if err != nil {
+		return LaunchProfile{}, err
+	}
+	normalized.Scheduling = scheduling
+
 	if normalized.LogPath != "" {
 		absPath, err := filepath.Abs(normalized.LogPath)
 		if err != nil {
@@ -335,10 +460,20 @@ func resolveLaunchWorkingDir(corePath string, args []string) (string, error) {
 	return filepath.Clean(workingDir), nil
 }
 
-func configureManagedController(args []string) ([]string, string, string, func(), error) {
-	controllerNet, controllerAddr, cleanup, err := controller.CreatePrivateEndpoint()
-	if err != nil {
-		return nil, "", "", nil, err
+func configureManagedController(args []string, dryRun bool) ([]string, string, string, func(), error) {
+	var (
+		controllerNet  string
+		controllerAddr string
+		cleanup        func()
+	)
+	if dryRun {
+		controllerNet, controllerAddr = controller.PlannedEndpoint()
+	} else {
+		var err error
+		controllerNet, controllerAddr, cleanup, err = controller.CreatePrivateEndpoint()
+		if err != nil {
+			return nil, "", "", nil, err
+		}
 	}
 
 	filteredArgs := stripControllerArgs(args)
@@ -347,6 +482,17 @@ func configureManagedController(args []string) ([]string, string, string, func()
 		filteredArgs = append([]string{"-ext-ctl-pipe", controllerAddr}, filteredArgs...)
 	case "unix":
 		filteredArgs = append([]string{"-ext-ctl-unix", controllerAddr}, filteredArgs...)
+		if dryRun {
+			break
+		}
+		unregister := registerActiveSandboxRoot(filepath.Dir(controllerAddr))
+		endpointCleanup := cleanup
+		cleanup = func() {
+			if endpointCleanup != nil {
+				endpointCleanup()
+			}
+			unregister()
+		}
 	default:
 		if cleanup != nil {
 			cleanup()
@@ -397,12 +543,23 @@ func isZeroLaunchProfile(profile LaunchProfile) bool {
 		profile.Priority == "" &&
 		profile.LogPath == "" &&
 		profile.SaveLogs == nil &&
+		profile.Autostart == nil &&
+		profile.DetachOnStop == nil &&
 		profile.MaxLogFileSizeMB == 0 &&
+		profile.Sandbox == nil &&
+		profile.Scheduling == nil &&
 		len(profile.Args) == 0 &&
 		len(profile.SafePaths) == 0 &&
 		len(profile.Env) == 0
 }
 
+func launchSandboxMode(profile LaunchProfile) string {
+	if profile.Sandbox == nil {
+		return ""
+	}
+	return profile.Sandbox.Mode
+}
+
 func maxLogFileSizeBytes(mb int) int64 {
 	if mb <= 0 {
 		mb = 20
@@ -450,10 +607,12 @@ func resolveCoreExecutablePath(rawPath string, allowEnv bool) (string, error) {
 
 type coreStartupHook struct {
 	upFile          string
+	token           string
 	postUpCommand   string
 	postDownCommand string
 	wait            func(context.Context) error
 	notifications   <-chan struct{}
+	closeListener   func()
 	cleanup         func()
 }
 
@@ -466,10 +625,33 @@ func createCoreStartupHook() (*coreStartupHook, error) {
 	return createNativeStartupHook(token)
 }
 
+func plannedCoreStartupHook() *coreStartupHook {
+	return &coreStartupHook{
+		upFile:          plannedStartupHookPath(),
+		postUpCommand:   launchPlanRedacted,
+		postDownCommand: launchPlanRedacted,
+		wait: func(context.Context) error {
+			return fmt.Errorf("预览的启动计划不能启动核心")
+		},
+		closeListener: func() {},
+		cleanup:       func() {},
+	}
+}
+
 func newCoreStartupHook(listener net.Listener, token string, upFile string, postUpCommand string, postDownCommand string, cleanup func()) *coreStartupHook {
+	return startCoreStartupHook(listener, token, upFile, postUpCommand, postDownCommand, cleanup, false)
+}
+
+// 重新接管已运行的核心时不再等待首次就绪，所有通知都视为核心重启
+func newReadyCoreStartupHook(listener net.Listener, token string, upFile string, cleanup func()) *coreStartupHook {
+	return startCoreStartupHook(listener, token, upFile, "", "", cleanup, true)
+}
+
+func startCoreStartupHook(listener net.Listener, token string, upFile string, postUpCommand string, postDownCommand string, cleanup func(), ready bool) *coreStartupHook {
 	firstReady := make(chan error, 1)
 	notifications := make(chan struct{}, 8)
 	var firstDelivered atomic.Bool
+	firstDelivered.Store(ready)
 
 	deliverFirst := func(err error) bool {
 		if !firstDelivered.CompareAndSwap(false, true) {
@@ -506,6 +688,7 @@ func newCoreStartupHook(listener net.Listener, token string, upFile string, post
 
 	return &coreStartupHook{
 		upFile:          upFile,
+		token:           token,
 		postUpCommand:   postUpCommand,
 		postDownCommand: postDownCommand,
 		wait: func(ctx context.Context) error {
@@ -517,6 +700,9 @@ func newCoreStartupHook(listener net.Listener, token string, upFile string, post
 			}
 		},
 		notifications: notifications,
+		closeListener: func() {
+			_ = listener.Close()
+		},
 		cleanup: func() {
 			_ = listener.Close()
 			if cleanup != nil {
@@ -555,10 +741,11 @@ func noopShellCommand() string {
 	return "true"
 }
 
-func buildLaunchEnv(profile LaunchProfile) []string {
-	envMap := make(map[string]string)
-
-	maps.Copy(envMap, profile.Env)
+func buildLaunchEnv(profile LaunchProfile, dryRun bool) ([]string, error) {
+	envMap, err := resolveLaunchEnv(profile.Env, dryRun)
+	if err != nil {
+		return nil, err
+	}
 	ensureEssentialEnv(envMap)
 
 	envMap["SAFE_PATHS"] = strings.Join(profile.SafePaths, string(os.PathListSeparator))
@@ -578,7 +765,7 @@ func buildLaunchEnv(profile LaunchProfile) []string {
 	for key, value := range envMap {
 		env = append(env, key+"="+value)
 	}
-	return env
+	return env, nil
 }
 
 func ensureEssentialEnv(envMap map[string]string) {
@@ -630,8 +817,16 @@ func lookupEnvMap(envMap map[string]string, key string) (string, bool) {
 	return "", false
 }
 
-func launchProfilePath() string {
-	return filepath.Join(serviceConfigDir(), "sparkle", "core", "launch_profile.json")
+// Windows 上 filepath.Rel 按不区分大小写比较路径
+func pathWithin(path string, root string) bool {
+	if path == root {
+		return true
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
 }
 
 func serviceConfigDir() string {
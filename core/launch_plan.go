@@ -0,0 +1,93 @@
+package core
+
+import "strings"
+
+const launchPlanRedacted = "<redacted>"
+
+type LaunchPlan struct {
+	Launcher   string            `json:"launcher"`
+	Fallback   string            `json:"fallback,omitempty"`
+	Executable string            `json:"executable"`
+	WorkingDir string            `json:"working_dir"`
+	Args       []LaunchPlanArg   `json:"args"`
+	Env        map[string]string `json:"env"`
+	Mounts     []LaunchPlanMount `json:"mounts,omitempty"`
+}
+
+type LaunchPlanArg struct {
+	Value   string `json:"value"`
+	Managed bool   `json:"managed,omitempty"`
+}
+
+type LaunchPlanMount struct {
+	Source   string `json:"source,omitempty"`
+	Target   string `json:"target"`
+	Kind     string `json:"kind"`
+	ReadOnly bool   `json:"read_only"`
+	Writable bool   `json:"writable"`
+}
+
+func (cm *CoreManager) LaunchPlan(profile *LaunchProfile, options ...LaunchOption) (*LaunchPlan, error) {
+	launchOptions := collectLaunchOptions(append(options, withDryRun()))
+	launch, err := cm.prepareLaunchSession(profile, launchOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer launch.cleanupNow()
+
+	launcher, fallback := newCoreLauncher(launch.sandboxMode)
+	plan, err := launcher.Plan(launch)
+	if err != nil {
+		return nil, err
+	}
+	if fallback != nil {
+		plan.Fallback = fallback.err.Error()
+	}
+	plan.Executable = launch.executablePath
+	plan.WorkingDir = launch.workingDir
+	plan.Args = launchPlanArgs(launch.args)
+	plan.Env = launchPlanEnv(launch.env)
+	return plan, nil
+}
+
+func launchPlanArgs(args []string) []LaunchPlanArg {
+	result := make([]LaunchPlanArg, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, ok := coreArgName(arg)
+		if !ok {
+			result = append(result, LaunchPlanArg{Value: arg})
+			continue
+		}
+
+		switch {
+		case name == "post-up" || name == "post-down":
+			result = append(result, LaunchPlanArg{Value: arg, Managed: true})
+			if !strings.Contains(arg, "=") && i+1 < len(args) {
+				result = append(result, LaunchPlanArg{Value: launchPlanRedacted, Managed: true})
+				i++
+			}
+		case isControllerArg(arg):
+			result = append(result, LaunchPlanArg{Value: arg, Managed: true})
+			if !strings.Contains(arg, "=") && i+1 < len(args) {
+				result = append(result, LaunchPlanArg{Value: args[i+1], Managed: true})
+				i++
+			}
+		default:
+			result = append(result, LaunchPlanArg{Value: arg})
+		}
+	}
+	return result
+}
+
+func launchPlanEnv(env []string) map[string]string {
+	result := make(map[string]string, len(env))
+	for _, item := range env {
+		key, value, _ := strings.Cut(item, "=")
+		if value != "" {
+			value = launchPlanRedacted
+		}
+		result[key] = value
+	}
+	return result
+}
@@ -0,0 +1,51 @@
+//go:build !windows
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLaunchPlanHasNoSideEffects(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	t.Setenv("SPARKLE_CONFIG_DIR", t.TempDir())
+
+	coreDir := t.TempDir()
+	corePath := filepath.Join(coreDir, "mihomo")
+	if err := os.WriteFile(corePath, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cm := &CoreManager{}
+	plan, err := cm.LaunchPlan(&LaunchProfile{CorePath: corePath, Args: []string{"-d", coreDir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Executable != corePath {
+		t.Fatalf("executable = %q, want %q", plan.Executable, corePath)
+	}
+
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("launch plan created %d entries in the temp dir", len(entries))
+	}
+
+	controllerSeen := false
+	for i, arg := range plan.Args {
+		if arg.Value == "-ext-ctl-unix" && i+1 < len(plan.Args) {
+			controllerSeen = true
+			if _, err := os.Stat(filepath.Dir(plan.Args[i+1].Value)); !os.IsNotExist(err) {
+				t.Fatalf("controller dir %q exists", plan.Args[i+1].Value)
+			}
+		}
+	}
+	if !controllerSeen {
+		t.Fatal("managed controller argument missing from plan")
+	}
+}
@@ -0,0 +1,262 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/UruhaLushia/sparkle-service/core/security"
+)
+
+var defaultLaunchPolicyEnvDeny = []string{"LD_*", "GCONV_PATH", "DYLD_*"}
+
+type LaunchPolicy struct {
+	CorePathPrefixes []string `json:"core_path_prefixes,omitempty"`
+	ArgAllow         []string `json:"arg_allow,omitempty"`
+	ArgDeny          []string `json:"arg_deny,omitempty"`
+	EnvAllow         []string `json:"env_allow,omitempty"`
+	EnvDeny          []string `json:"env_deny,omitempty"`
+	LogRoots         []string `json:"log_roots,omitempty"`
+	SafePathRoots    []string `json:"safe_path_roots,omitempty"`
+	// 除 root 与 service 用户外允许拥有核心文件及其上级目录的用户
+	CoreOwnerUIDs []uint32 `json:"core_owner_uids,omitempty"`
+}
+
+type LaunchPolicyViolation struct {
+	Field   string `json:"field"`
+	Value   string `json:"value"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+type LaunchPolicyError struct {
+	Violations []LaunchPolicyViolation `json:"violations"`
+}
+
+func (e *LaunchPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+	return "启动配置违反管理员策略：" + strings.Join(messages, "；")
+}
+
+func LoadLaunchPolicy() (LaunchPolicy, error) {
+	path := launchPolicyPath()
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return LaunchPolicy{}, nil
+		}
+		return LaunchPolicy{}, fmt.Errorf("读取核心启动策略失败：%w", err)
+	}
+	if err := security.CheckAdminOwned(path, info); err != nil {
+		return LaunchPolicy{}, fmt.Errorf("核心启动策略文件不可信：%w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return LaunchPolicy{}, fmt.Errorf("读取核心启动策略失败：%w", err)
+	}
+
+	var policy LaunchPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return LaunchPolicy{}, fmt.Errorf("解析核心启动策略失败：%w", err)
+	}
+	for _, roots := range []*[]string{&policy.CorePathPrefixes, &policy.LogRoots, &policy.SafePathRoots} {
+		for i, root := range *roots {
+			absRoot, err := filepath.Abs(strings.TrimSpace(root))
+			if err != nil {
+				return LaunchPolicy{}, fmt.Errorf("解析核心启动策略路径失败 %q：%w", root, err)
+			}
+			(*roots)[i] = resolvePolicyPath(absRoot)
+		}
+	}
+	return policy, nil
+}
+
+func enforceLaunchPolicy(profile LaunchProfile) error {
+	policy, err := LoadLaunchPolicy()
+	if err != nil {
+		return err
+	}
+
+	violations := policy.check(profile)
+	if len(violations) > 0 {
+		return &LaunchPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func coreBinaryOptions() ([]security.BinaryOption, error) {
+	policy, err := LoadLaunchPolicy()
+	if err != nil {
+		return nil, err
+	}
+	return []security.BinaryOption{security.WithTrustedOwners(policy.CoreOwnerUIDs...)}, nil
+}
+
+func (p LaunchPolicy) check(profile LaunchProfile) []LaunchPolicyViolation {
+	var violations []LaunchPolicyViolation
+
+	if profile.CorePath != "" && len(p.CorePathPrefixes) > 0 {
+		if !pathWithinAny(resolvePolicyPath(profile.CorePath), p.CorePathPrefixes) {
+			violations = append(violations, LaunchPolicyViolation{
+				Field:   "core_path",
+				Value:   profile.CorePath,
+				Rule:    "core_path_prefixes",
+				Message: fmt.Sprintf("核心路径 %s 不在允许的目录中", profile.CorePath),
+			})
+		}
+	}
+
+	for _, arg := range profile.Args {
+		if pattern, ok := matchPolicyPatterns(p.ArgDeny, arg); ok {
+			violations = append(violations, LaunchPolicyViolation{
+				Field:   "args",
+				Value:   arg,
+				Rule:    "arg_deny:" + pattern,
+				Message: fmt.Sprintf("启动参数 %s 被禁止", arg),
+			})
+			continue
+		}
+		if len(p.ArgAllow) > 0 {
+			if _, ok := matchPolicyPatterns(p.ArgAllow, arg); !ok {
+				violations = append(violations, LaunchPolicyViolation{
+					Field:   "args",
+					Value:   arg,
+					Rule:    "arg_allow",
+					Message: fmt.Sprintf("启动参数 %s 不在允许列表中", arg),
+				})
+			}
+		}
+	}
+
+	envDeny := append(append([]string(nil), defaultLaunchPolicyEnvDeny...), p.EnvDeny...)
+	foldEnv := runtime.GOOS == "windows"
+	for _, key := range slices.Sorted(maps.Keys(profile.Env)) {
+		if pattern, ok := matchEnvPolicyPatterns(envDeny, key, foldEnv); ok {
+			violations = append(violations, LaunchPolicyViolation{
+				Field:   "env",
+				Value:   key,
+				Rule:    "env_deny:" + pattern,
+				Message: fmt.Sprintf("环境变量 %s 被禁止", key),
+			})
+			continue
+		}
+		if len(p.EnvAllow) > 0 {
+			if _, ok := matchEnvPolicyPatterns(p.EnvAllow, key, foldEnv); !ok {
+				violations = append(violations, LaunchPolicyViolation{
+					Field:   "env",
+					Value:   key,
+					Rule:    "env_allow",
+					Message: fmt.Sprintf("环境变量 %s 不在允许列表中", key),
+				})
+			}
+		}
+	}
+
+	if profile.LogPath != "" && len(p.LogRoots) > 0 && !pathWithinAny(resolvePolicyPath(profile.LogPath), p.LogRoots) {
+		violations = append(violations, LaunchPolicyViolation{
+			Field:   "log_path",
+			Value:   profile.LogPath,
+			Rule:    "log_roots",
+			Message: fmt.Sprintf("核心日志路径 %s 不在允许的目录中", profile.LogPath),
+		})
+	}
+
+	if len(p.SafePathRoots) > 0 {
+		for _, path := range profile.SafePaths {
+			if pathWithinAny(resolvePolicyPath(path), p.SafePathRoots) {
+				continue
+			}
+			violations = append(violations, LaunchPolicyViolation{
+				Field:   "safe_paths",
+				Value:   path,
+				Rule:    "safe_path_roots",
+				Message: fmt.Sprintf("可信路径 %s 不在允许的目录中", path),
+			})
+		}
+	}
+
+	return violations
+}
+
+func pathWithinAny(path string, roots []string) bool {
+	for _, root := range roots {
+		if pathWithin(path, root) {
+			return true
+		}
+	}
+	return false
+}
+
+// 解析路径中已存在部分的符号链接后再比较，避免通过允许目录内的链接指向目录外；
+// 尚未创建的文件按最近的已存在上级目录解析
+func resolvePolicyPath(path string) string {
+	path = filepath.Clean(path)
+	var missing []string
+	current := path
+	for {
+		if resolved, err := filepath.EvalSymlinks(current); err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...)
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return path
+		}
+		missing = append([]string{filepath.Base(current)}, missing...)
+		current = parent
+	}
+}
+
+// Windows 上环境变量名不区分大小写
+func matchEnvPolicyPatterns(patterns []string, key string, foldCase bool) (string, bool) {
+	if !foldCase {
+		return matchPolicyPatterns(patterns, key)
+	}
+	for _, pattern := range patterns {
+		if matchPolicyPattern(strings.ToUpper(pattern), strings.ToUpper(key)) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+func matchPolicyPatterns(patterns []string, value string) (string, bool) {
+	for _, pattern := range patterns {
+		if matchPolicyPattern(pattern, value) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+func matchPolicyPattern(pattern string, value string) bool {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+
+	matched, err := regexp.MatchString(expr.String(), value)
+	return err == nil && matched
+}
+
+func launchPolicyPath() string {
+	return filepath.Join(serviceConfigDir(), "sparkle", "core", "launch_policy.json")
+}
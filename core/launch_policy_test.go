@@ -0,0 +1,70 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLaunchPolicyCheck(t *testing.T) {
+	allowed := resolvePolicyPath(t.TempDir())
+	outside := resolvePolicyPath(t.TempDir())
+	escape := filepath.Join(allowed, "escape")
+	if err := os.Symlink(outside, escape); err != nil {
+		t.Skipf("symlink unsupported: %v", err)
+	}
+
+	policy := LaunchPolicy{
+		ArgDeny:       []string{"-ext-*"},
+		EnvAllow:      []string{"HOME", "LD_*"},
+		LogRoots:      []string{allowed},
+		SafePathRoots: []string{allowed},
+	}
+
+	tests := []struct {
+		name    string
+		profile LaunchProfile
+		want    []string
+	}{
+		{name: "allowed", profile: LaunchProfile{LogPath: filepath.Join(allowed, "logs", "core.log"), SafePaths: []string{allowed}, Env: map[string]LaunchEnvValue{"HOME": {Value: "/root"}}}},
+		{name: "log through symlink", profile: LaunchProfile{LogPath: filepath.Join(escape, "core.log")}, want: []string{"log_roots"}},
+		{name: "safe path through symlink", profile: LaunchProfile{SafePaths: []string{escape}}, want: []string{"safe_path_roots"}},
+		{name: "log outside", profile: LaunchProfile{LogPath: filepath.Join(outside, "core.log")}, want: []string{"log_roots"}},
+		{name: "denied arg", profile: LaunchProfile{Args: []string{"-ext-ctl", "0.0.0.0:9090"}}, want: []string{"arg_deny:-ext-*"}},
+		{name: "default env deny wins over allow", profile: LaunchProfile{Env: map[string]LaunchEnvValue{"LD_PRELOAD": {Value: "x"}}}, want: []string{"env_deny:LD_*"}},
+		{name: "env not allowed", profile: LaunchProfile{Env: map[string]LaunchEnvValue{"TZ": {Value: "UTC"}}}, want: []string{"env_allow"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := policy.check(tt.profile)
+			if len(violations) != len(tt.want) {
+				t.Fatalf("violations = %+v, want rules %v", violations, tt.want)
+			}
+			for i, violation := range violations {
+				if violation.Rule != tt.want[i] {
+					t.Fatalf("violation %d rule = %q, want %q", i, violation.Rule, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMatchEnvPolicyPatterns(t *testing.T) {
+	tests := []struct {
+		key      string
+		foldCase bool
+		want     bool
+	}{
+		{key: "LD_PRELOAD", want: true},
+		{key: "ld_preload"},
+		{key: "ld_preload", foldCase: true, want: true},
+		{key: "Gconv_Path", foldCase: true, want: true},
+		{key: "PATH", foldCase: true},
+	}
+	for _, tt := range tests {
+		if _, got := matchEnvPolicyPatterns(defaultLaunchPolicyEnvDeny, tt.key, tt.foldCase); got != tt.want {
+			t.Errorf("matchEnvPolicyPatterns(%q, fold=%v) = %v, want %v", tt.key, tt.foldCase, got, tt.want)
+		}
+	}
+}
@@ -4,14 +4,26 @@ import "os/exec"
 
 type coreLauncher interface {
 	Command(*launchSession) (*exec.Cmd, error)
+	Plan(*launchSession) (*LaunchPlan, error)
+}
+
+type coreLauncherFallback struct {
+	requested string
+	launcher  string
+	err       error
 }
 
 type directCoreLauncher struct{}
 
 func (directCoreLauncher) Command(launch *launchSession) (*exec.Cmd, error) {
-	cmd := exec.Command(launch.executablePath, launch.args...)
+	cmd := exec.Command(coreBinaryExecPath(launch), launch.args...)
+	cmd.Args[0] = launch.executablePath
 	cmd.Env = launch.env
 	cmd.Dir = launch.workingDir
 	configureCommand(cmd)
 	return cmd, nil
 }
+
+func (directCoreLauncher) Plan(_ *launchSession) (*LaunchPlan, error) {
+	return &LaunchPlan{Launcher: "direct"}, nil
+}
@@ -0,0 +1,93 @@
+//go:build linux
+
+package core
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+
+	"github.com/UruhaLushia/sparkle-service/core/landlock"
+)
+
+type landlockCoreLauncher struct{}
+
+func (landlockCoreLauncher) Command(launch *launchSession) (*exec.Cmd, error) {
+	rules, err := landlockRules(launch)
+	if err != nil {
+		return nil, err
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("读取 service 可执行文件路径失败：%w", err)
+	}
+
+	args := make([]string, 0, len(rules)*2+len(launch.args)+3)
+	args = append(args, "__core-exec")
+	if launch.binary != nil && launch.binary.File != nil {
+		args = append(args, "--exec-fd", strconv.Itoa(coreBinaryExecFD))
+	}
+	for _, rule := range rules {
+		if rule.Writable {
+			args = append(args, "--rw", rule.Path)
+		} else {
+			args = append(args, "--ro", rule.Path)
+		}
+	}
+	args = append(args, "--", launch.executablePath)
+	args = append(args, launch.args...)
+
+	cmd := exec.Command(executable, args...)
+	cmd.Env = launch.env
+	cmd.Dir = launch.workingDir
+	attachCoreBinary(cmd, launch)
+	configureCommand(cmd)
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	if !launch.detach {
+		cmd.SysProcAttr.Pdeathsig = syscall.SIGKILL
+	}
+
+	return cmd, nil
+}
+
+func (landlockCoreLauncher) Plan(launch *launchSession) (*LaunchPlan, error) {
+	mounts, err := linuxSandboxMounts(launch)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &LaunchPlan{
+		Launcher: SandboxModeLandlock,
+		Mounts:   make([]LaunchPlanMount, 0, len(mounts)),
+	}
+	for _, mount := range mounts {
+		plan.Mounts = append(plan.Mounts, mount.plan())
+	}
+	return plan, nil
+}
+
+func landlockRules(launch *launchSession) ([]landlock.Rule, error) {
+	mounts, err := linuxSandboxMounts(launch)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]landlock.Rule, 0, len(mounts))
+	for _, mount := range mounts {
+		if mount.proc {
+			rules = append(rules, landlock.Rule{Path: mount.target})
+			continue
+		}
+		rules = append(rules, landlock.Rule{
+			Path:     mount.source,
+			Writable: !mount.readOnly,
+		})
+	}
+	return rules, nil
+}
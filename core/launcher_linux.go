@@ -12,6 +12,8 @@ import (
 	"slices"
 	"strings"
 	"syscall"
+
+	"github.com/UruhaLushia/sparkle-service/core/landlock"
 )
 
 const disableLinuxSandboxEnv = "SPARKLE_CORE_DISABLE_LINUX_SANDBOX"
@@ -31,11 +33,26 @@ type linuxSandboxMount struct {
 	proc     bool
 }
 
-func newCoreLauncher() coreLauncher {
+func newCoreLauncher(mode string) (coreLauncher, *coreLauncherFallback) {
 	if sandboxDisabled() {
-		return directCoreLauncher{}
+		return directCoreLauncher{}, nil
+	}
+
+	switch mode {
+	case SandboxModeNone:
+		return directCoreLauncher{}, nil
+	case SandboxModeLandlock:
+		if err := landlock.Supported(); err != nil {
+			return linuxSandboxLauncher{}, &coreLauncherFallback{
+				requested: SandboxModeLandlock,
+				launcher:  SandboxModeChroot,
+				err:       err,
+			}
+		}
+		return landlockCoreLauncher{}, nil
+	default:
+		return linuxSandboxLauncher{}, nil
 	}
-	return linuxSandboxLauncher{}
 }
 
 func (linuxSandboxLauncher) Command(launch *launchSession) (*exec.Cmd, error) {
@@ -43,13 +60,19 @@ func (linuxSandboxLauncher) Command(launch *launchSession) (*exec.Cmd, error) {
 	if err != nil {
 		return nil, err
 	}
+	unregister := registerActiveSandboxRoot(root)
+	launch.sandboxRoot = root
 	launch.addCleanup(func() {
-		if err := cleanup(); err != nil {
-			logSandboxCleanupError(err)
+		if !launch.detached {
+			if err := cleanup(); err != nil {
+				logSandboxCleanupError(err)
+			}
 		}
+		unregister()
 	})
 
-	cmd := exec.Command(launch.executablePath, launch.args...)
+	cmd := exec.Command(coreBinaryExecPath(launch), launch.args...)
+	cmd.Args[0] = launch.executablePath
 	cmd.Env = launch.env
 	cmd.Dir = launch.workingDir
 	configureCommand(cmd)
@@ -60,15 +83,54 @@ func (linuxSandboxLauncher) Command(launch *launchSession) (*exec.Cmd, error) {
 	cmd.SysProcAttr.Chroot = root
 	cmd.SysProcAttr.Cloneflags |= linuxSandboxCloneFlags
 	cmd.SysProcAttr.Unshareflags |= linuxSandboxUnshareFlags
-	cmd.SysProcAttr.Pdeathsig = syscall.SIGKILL
+	if !launch.detach {
+		cmd.SysProcAttr.Pdeathsig = syscall.SIGKILL
+	}
 
 	return cmd, nil
 }
 
+func (linuxSandboxLauncher) Plan(launch *launchSession) (*LaunchPlan, error) {
+	mounts, err := linuxSandboxMounts(launch)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &LaunchPlan{
+		Launcher: SandboxModeChroot,
+		Mounts:   make([]LaunchPlanMount, 0, len(mounts)),
+	}
+	for _, mount := range mounts {
+		plan.Mounts = append(plan.Mounts, mount.plan())
+	}
+	return plan, nil
+}
+
+func (m linuxSandboxMount) plan() LaunchPlanMount {
+	kind := "dir"
+	switch {
+	case m.proc:
+		kind = "proc"
+	case m.file:
+		kind = "file"
+	}
+	return LaunchPlanMount{
+		Source:   m.source,
+		Target:   m.target,
+		Kind:     kind,
+		ReadOnly: m.readOnly,
+		Writable: !m.readOnly && !m.proc,
+	}
+}
+
 func prepareLinuxSandboxRoot(launch *launchSession) (string, func() error, error) {
-	cleanupStaleLinuxSandboxRoots()
+	SweepSandboxRoots()
 
-	root, err := os.MkdirTemp("", "sparkle-core-sandbox-*")
+	baseDir, err := ensureSandboxBaseDir()
+	if err != nil {
+		return "", nil, err
+	}
+	root, err := os.MkdirTemp(baseDir, "sparkle-core-sandbox-*")
 	if err != nil {
 		return "", nil, fmt.Errorf("创建核心沙盒目录失败：%w", err)
 	}
@@ -160,20 +222,6 @@ func prepareLinuxSandboxStaticLayout(root string) error {
 	return nil
 }
 
-func cleanupStaleLinuxSandboxRoots() {
-	roots, err := filepath.Glob(filepath.Join(os.TempDir(), "sparkle-core-sandbox-*"))
-	if err != nil {
-		logSandboxCleanupError(fmt.Errorf("查找残留核心沙盒目录失败：%w", err))
-		return
-	}
-
-	for _, root := range roots {
-		if err := cleanupLinuxSandboxRoot(root); err != nil {
-			logSandboxCleanupError(err)
-		}
-	}
-}
-
 func cleanupLinuxSandboxRoot(root string) error {
 	info, err := os.Lstat(root)
 	if os.IsNotExist(err) {
@@ -245,17 +293,6 @@ func unescapeMountInfoPath(path string) string {
 	return replacer.Replace(path)
 }
 
-func pathWithin(path string, root string) bool {
-	if path == root {
-		return true
-	}
-	rel, err := filepath.Rel(root, path)
-	if err != nil {
-		return false
-	}
-	return rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
-}
-
 func mountIntoSandbox(target string, mount linuxSandboxMount) error {
 	if mount.proc {
 		return mountKernelFilesystem(target, "proc", uintptr(syscall.MS_NOSUID|syscall.MS_NOEXEC|syscall.MS_NODEV))
@@ -354,6 +391,14 @@ func linuxSandboxMounts(launch *launchSession) ([]linuxSandboxMount, error) {
 		}
 		return addMount(path, false, false)
 	}
+	// 预览时日志、启动通知与控制器目录尚未创建，按计划路径列出
+	addPlannedWritableDir := func(path string) error {
+		if _, err := os.Stat(path); launch.dryRun && os.IsNotExist(err) {
+			mounts = append(mounts, linuxSandboxMount{source: path, target: path})
+			return nil
+		}
+		return addWritableDir(path)
+	}
 
 	for _, dir := range []string{"/bin", "/sbin", "/usr", "/lib", "/lib64", "/etc", "/sys"} {
 		if err := addMountIfExists(dir, true, false); err != nil {
@@ -392,16 +437,18 @@ func linuxSandboxMounts(launch *launchSession) ([]linuxSandboxMount, error) {
 	}
 	if launch.logPath != "" {
 		logDir := filepath.Dir(launch.logPath)
-		if err := ensureCoreLogDir(logDir, launch.fileAccess); err != nil {
-			return nil, err
+		if !launch.dryRun {
+			if err := ensureCoreLogDir(logDir, launch.fileAccess); err != nil {
+				return nil, err
+			}
 		}
-		if err := addWritableDir(logDir); err != nil {
+		if err := addPlannedWritableDir(logDir); err != nil {
 			return nil, err
 		}
 	}
 	if launch.hookUpFile != "" {
 		hookDir := filepath.Dir(launch.hookUpFile)
-		if err := addWritableDir(hookDir); err != nil {
+		if err := addPlannedWritableDir(hookDir); err != nil {
 			return nil, err
 		}
 	}
@@ -412,7 +459,7 @@ func linuxSandboxMounts(launch *launchSession) ([]linuxSandboxMount, error) {
 		}
 	}
 	for _, path := range writableDirsFromCoreArgs(launch.args) {
-		if err := addWritableDir(path); err != nil {
+		if err := addPlannedWritableDir(path); err != nil {
 			return nil, err
 		}
 	}
@@ -2,6 +2,6 @@
 
 package core
 
-func newCoreLauncher() coreLauncher {
-	return directCoreLauncher{}
+func newCoreLauncher(_ string) (coreLauncher, *coreLauncherFallback) {
+	return directCoreLauncher{}, nil
 }
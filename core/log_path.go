@@ -0,0 +1,55 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+)
+
+func coreLogRoot(path string, access fileAccess) (string, error) {
+	roots, err := coreLogRoots(access)
+	if err != nil {
+		return "", err
+	}
+	if len(roots) == 0 {
+		return "", nil
+	}
+
+	root := ""
+	for _, candidate := range roots {
+		if pathWithin(path, candidate) && len(candidate) > len(root) {
+			root = candidate
+		}
+	}
+	if root == "" {
+		return "", fmt.Errorf("核心日志路径 %s 不在允许的目录中", path)
+	}
+	return root, nil
+}
+
+func coreLogRoots(access fileAccess) ([]string, error) {
+	policy, err := LoadLaunchPolicy()
+	if err != nil {
+		return nil, err
+	}
+	if len(policy.LogRoots) > 0 {
+		return policy.LogRoots, nil
+	}
+
+	var roots []string
+	switch runtime.GOOS {
+	case "windows":
+		roots = append(roots, filepath.Join(serviceConfigDir(), "sparkle", "logs"))
+	case "darwin":
+		roots = append(roots, filepath.Join("/Library", "Logs", "Sparkle"), filepath.Join("/var", "log", "sparkle"))
+	default:
+		roots = append(roots, filepath.Join("/var", "log", "sparkle"))
+	}
+
+	if access.ok {
+		if home := coreLogUserHome(access); home != "" {
+			roots = append(roots, home)
+		}
+	}
+	return roots, nil
+}
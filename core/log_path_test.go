@@ -0,0 +1,57 @@
+//go:build !windows
+
+package core
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCoreLogRoot(t *testing.T) {
+	t.Setenv("SPARKLE_CONFIG_DIR", t.TempDir())
+
+	account, err := user.Current()
+	if err != nil || account.HomeDir == "" || account.HomeDir == "/" {
+		t.Skip("current user has no home directory")
+	}
+	userAccess := fileAccess{userID: os.Getuid(), groupID: os.Getgid(), ok: true}
+	defaultRoot := filepath.Join("/var", "log", "sparkle")
+	if runtime.GOOS == "darwin" {
+		defaultRoot = filepath.Join("/Library", "Logs", "Sparkle")
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		access  fileAccess
+		want    string
+		wantErr bool
+	}{
+		{name: "default root", path: filepath.Join(defaultRoot, "core.log"), want: defaultRoot},
+		{name: "user home", path: filepath.Join(account.HomeDir, "sparkle", "core.log"), access: userAccess, want: filepath.Clean(account.HomeDir)},
+		{name: "user home without owner", path: filepath.Join(account.HomeDir, "sparkle", "core.log"), wantErr: true},
+		{name: "outside", path: filepath.Join("/etc", "sparkle", "core.log"), access: userAccess, wantErr: true},
+		{name: "parent escape", path: filepath.Join(defaultRoot, "..", "core.log"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := coreLogRoot(tt.path, tt.access)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("coreLogRoot(%q) = %q, want error", tt.path, root)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("coreLogRoot(%q) error: %v", tt.path, err)
+			}
+			if root != tt.want {
+				t.Fatalf("coreLogRoot(%q) = %q, want %q", tt.path, root, tt.want)
+			}
+		})
+	}
+}
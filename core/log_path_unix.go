@@ -0,0 +1,17 @@
+//go:build !windows
+
+package core
+
+import (
+	"os/user"
+	"path/filepath"
+	"strconv"
+)
+
+func coreLogUserHome(access fileAccess) string {
+	account, err := user.LookupId(strconv.Itoa(access.userID))
+	if err != nil || account.HomeDir == "" || account.HomeDir == "/" {
+		return ""
+	}
+	return filepath.Clean(account.HomeDir)
+}
@@ -0,0 +1,36 @@
+//go:build windows
+
+package core
+
+import (
+	"path/filepath"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// 通过注册表中的用户配置列表查找请求用户的配置目录
+func coreLogUserHome(access fileAccess) string {
+	if access.sid == "" {
+		return ""
+	}
+	if _, err := windows.StringToSid(access.sid); err != nil {
+		return ""
+	}
+
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Windows NT\CurrentVersion\ProfileList\`+access.sid, registry.QUERY_VALUE)
+	if err != nil {
+		return ""
+	}
+	defer key.Close()
+
+	home, _, err := key.GetStringValue("ProfileImagePath")
+	if err != nil || home == "" {
+		return ""
+	}
+	home, err = registry.ExpandString(home)
+	if err != nil || home == "" || !filepath.IsAbs(home) {
+		return ""
+	}
+	return filepath.Clean(home)
+}
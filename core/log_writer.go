@@ -6,7 +6,6 @@ import (
 	"io"
 	"log"
 	"os"
-	"path/filepath"
 	"sync"
 )
 
@@ -106,16 +105,9 @@ func (w *boundedLogWriter) ensureOpenLocked() error {
 	if w.file != nil {
 		return nil
 	}
-	if err := ensureCoreLogDir(filepath.Dir(w.path), w.access); err != nil {
-		return fmt.Errorf("创建核心日志目录失败：%w", err)
-	}
 
-	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	file, err := openCoreLogFile(w.path, w.access)
 	if err != nil {
-		return fmt.Errorf("打开核心日志文件失败：%w", err)
-	}
-	if err := applyCoreLogFileAccess(w.path, w.access); err != nil {
-		_ = file.Close()
 		return err
 	}
 	w.file = file
@@ -137,53 +129,33 @@ func (w *boundedLogWriter) enforceLimitLocked() error {
 
 	targetBytes := max(int64(float64(w.maxBytes)*logTrimLowWatermarkRatio), 1)
 
-	if err := w.file.Close(); err != nil {
-		w.file = nil
-		return fmt.Errorf("关闭核心日志文件失败：%w", err)
-	}
-	w.file = nil
-
-	content, err := readLogTail(w.path, info.Size(), targetBytes)
+	content, err := readLogTail(w.file, info.Size(), targetBytes)
 	if err != nil {
-		_ = w.reopenLocked()
 		return err
 	}
 
-	if err := os.WriteFile(w.path, content, 0o600); err != nil {
-		_ = w.reopenLocked()
+	// 文件以 O_APPEND 打开，截断后写入的内容会从文件开头开始
+	if err := w.file.Truncate(0); err != nil {
 		return fmt.Errorf("裁剪核心日志文件失败：%w", err)
 	}
-	if err := applyCoreLogFileAccess(w.path, w.access); err != nil {
-		_ = w.reopenLocked()
-		return err
+	if _, err := w.file.Write(content); err != nil {
+		return fmt.Errorf("裁剪核心日志文件失败：%w", err)
 	}
-
-	return w.reopenLocked()
+	return nil
 }
 
-func readLogTail(path string, fileSize int64, targetBytes int64) ([]byte, error) {
-	if fileSize <= targetBytes {
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return nil, fmt.Errorf("读取核心日志文件失败：%w", err)
-		}
-		return content, nil
-	}
-
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, fmt.Errorf("打开核心日志文件失败：%w", err)
-	}
-	defer file.Close()
-
-	offset := fileSize - targetBytes
-	content := make([]byte, targetBytes)
+func readLogTail(file *os.File, fileSize int64, targetBytes int64) ([]byte, error) {
+	offset := max(fileSize-targetBytes, 0)
+	content := make([]byte, fileSize-offset)
 	n, err := file.ReadAt(content, offset)
 	if err != nil && err != io.EOF {
 		return nil, fmt.Errorf("读取核心日志文件尾部失败：%w", err)
 	}
 	content = content[:n]
 
+	if offset == 0 {
+		return content, nil
+	}
 	if index := bytes.IndexByte(content, '\n'); index >= 0 && index < len(content)-1 {
 		content = content[index+1:]
 	}
@@ -191,19 +163,6 @@ func readLogTail(path string, fileSize int64, targetBytes int64) ([]byte, error)
 	return content, nil
 }
 
-func (w *boundedLogWriter) reopenLocked() error {
-	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
-	if err != nil {
-		return fmt.Errorf("重新打开核心日志文件失败：%w", err)
-	}
-	if err := applyCoreLogFileAccess(w.path, w.access); err != nil {
-		_ = file.Close()
-		return err
-	}
-	w.file = file
-	return nil
-}
-
 func (w *boundedLogWriter) closeFileLocked() error {
 	if w.file == nil {
 		return nil
@@ -5,8 +5,9 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"os"
 	"os/exec"
-	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -60,6 +61,7 @@ type CoreManager struct {
 	pid                    atomic.Int32
 	mutex                  sync.Mutex
 	stopChan               chan struct{}
+	canary                 *CoreCanary
 	trafficMonitorPipeSDDL string
 }
 
@@ -298,6 +300,7 @@ type ProcessInfo struct {
 	Uptime       string    `json:"uptime"`
 	LaunchMode   string    `json:"launch_mode,omitempty"`
 	Executable   string    `json:"executable,omitempty"`
+	SHA256       string    `json:"sha256,omitempty"`
 }
 
 type CoreManagerOption func(*CoreManager)
@@ -333,7 +336,9 @@ func (cm *CoreManager) startCoreLocked(profile *LaunchProfile, options launchOpt
 	if !cm.isRunning.CompareAndSwap(false, true) {
 		return fmt.Errorf("核心进程已在运行中")
 	}
-	cm.emitCoreEvent(CoreEventStarting, "核心正在启动", nil)
+	starting := cm.newCoreEvent(CoreEventStarting, "核心正在启动", nil, 0, 0)
+	starting.Cause = options.cause
+	cm.publishCoreEvent(starting)
 
 	cm.stopChan = make(chan struct{})
 
@@ -361,8 +366,13 @@ func (cm *CoreManager) startProcessLocked(profile *LaunchProfile, options launch
 	})
 	launch.logWriter = logWriter
 
+	launcher, fallback := newCoreLauncher(launch.sandboxMode)
+	if fallback != nil {
+		cm.emitSandboxFallback(fallback)
+	}
+
 	controller := newProcessController()
-	cmd, err := newCoreLauncher().Command(launch)
+	cmd, err := launcher.Command(launch)
 	if err != nil {
 		if closeErr := logWriter.Close(); closeErr != nil {
 			log.Printf("关闭核心日志文件失败: %v", closeErr)
@@ -382,10 +392,25 @@ func (cm *CoreManager) startProcessLocked(profile *LaunchProfile, options launch
 	})
 	logEventWatcher := newCoreLogEventWatcher(cm)
 	launch.addCleanup(logEventWatcher.Stop)
-	cmd.Stdout = io.MultiWriter(startupWatcher, logEventWatcher, logWriter)
-	cmd.Stderr = io.MultiWriter(errBuffer, startupWatcher, logEventWatcher, logWriter)
+	if launch.detach {
+		if err := attachCoreOutput(cmd, launch, io.MultiWriter(errBuffer, startupWatcher, logEventWatcher, logWriter)); err != nil {
+			controller.Close()
+			launch.cleanupNow()
+			cm.monitoring.Store(false)
+			cm.signalStopLocked()
+			cm.isRunning.Store(false)
+			cm.emitCoreEvent(CoreEventFailed, "核心启动失败", err)
+			return err
+		}
+	} else {
+		cmd.Stdout = io.MultiWriter(startupWatcher, logEventWatcher, logWriter)
+		cmd.Stderr = io.MultiWriter(errBuffer, startupWatcher, logEventWatcher, logWriter)
+	}
 
-	if err := cmd.Start(); err != nil {
+	err = cmd.Start()
+	// 核心已继承 FIFO 写端，service 不再持有
+	launch.closeOutputWriter()
+	if err != nil {
 		controller.Close()
 		launch.cleanupNow()
 		cm.monitoring.Store(false)
@@ -412,6 +437,20 @@ func (cm *CoreManager) startProcessLocked(profile *LaunchProfile, options launch
 	if err := setProcessPriority(pid, launch.cpuPriority); err != nil {
 		log.Printf("设置核心进程优先级失败: %v", err)
 	}
+	if err := applyProcessScheduling(pid, launch.profile.Scheduling); err != nil {
+		log.Printf("设置核心进程调度参数失败: %v", err)
+	}
+	if launch.detach {
+		if err := moveToDetachedCoreCgroup(pid); err != nil {
+			log.Printf("核心进程未能移出 service cgroup，服务停止时可能被一并结束: %v", err)
+		}
+	}
+
+	if proc, err := process.NewProcess(pid); err == nil {
+		if createTime, err := proc.CreateTime(); err == nil {
+			launch.createTime = createTime
+		}
+	}
 
 	cm.cmd = cmd
 	cm.controller = controller
@@ -450,15 +489,31 @@ func (cm *CoreManager) startProcessLocked(profile *LaunchProfile, options launch
 	if launch.readyNotify != nil {
 		go cm.monitorStartupNotifications(launch, cm.stopChan)
 	}
-	cm.emitCoreEvent(CoreEventStarted, "核心已启动", nil)
+	started := cm.newCoreEvent(CoreEventStarted, "核心已启动", nil, 0, 0)
+	started.Cause = options.cause
+	if launch.binaryHash != "" {
+		started.Data = map[string]string{"sha256": launch.binaryHash}
+	}
+	cm.publishCoreEvent(started)
 
 	return nil
 }
 
+func (cm *CoreManager) emitSandboxFallback(fallback *coreLauncherFallback) {
+	log.Printf("核心沙盒模式 %s 不可用，已回退到 %s: %v", fallback.requested, fallback.launcher, fallback.err)
+	event := cm.newCoreEvent(CoreEventSandboxFallback, "核心沙盒模式不可用，已回退", fallback.err, 0, 0)
+	event.Data = map[string]string{
+		"requested": fallback.requested,
+		"launcher":  fallback.launcher,
+	}
+	cm.publishCoreEvent(event)
+}
+
 func (cm *CoreManager) StopCore() error {
 	cm.mutex.Lock()
 	defer cm.mutex.Unlock()
 
+	cm.cancelCanaryLocked()
 	return cm.stopCoreLocked()
 }
 
@@ -488,13 +543,18 @@ func (cm *CoreManager) RestartCoreWithProfile(profile *LaunchProfile, options ..
 	cm.mutex.Lock()
 	defer cm.mutex.Unlock()
 
+	cm.cancelCanaryLocked()
+	return cm.restartCoreLocked(profile, collectLaunchOptions(options))
+}
+
+func (cm *CoreManager) restartCoreLocked(profile *LaunchProfile, options launchOptions) error {
 	cm.emitCoreEvent(CoreEventRestarting, "核心正在重启", nil)
 	if err := cm.stopCoreLocked(); err != nil {
 		log.Printf("停止进程时出错: %v", err)
 	}
 
 	time.Sleep(100 * time.Millisecond)
-	return cm.startCoreLocked(profile, collectLaunchOptions(options))
+	return cm.startCoreLocked(profile, options)
 }
 
 func (cm *CoreManager) ApplyLaunchProfile(profile LaunchProfile, options ...LaunchOption) {
@@ -660,6 +720,16 @@ func (cm *CoreManager) handleProcessExit() {
 		return
 	}
 
+	if canary := cm.canary; canary != nil {
+		// 灰度期间异常退出不再按原配置重试，直接交由灰度回滚
+		cm.monitoring.Store(false)
+		cm.signalStopLocked()
+		cm.cleanupLocked()
+		cm.mutex.Unlock()
+		canary.fail(fmt.Errorf("核心进程在灰度期间异常退出"))
+		return
+	}
+
 	profile := LaunchProfile{}
 	access := fileAccess{}
 	if cm.launch != nil {
@@ -769,6 +839,7 @@ func findManagedCorePID(controller processController, oldPID int32, launch *laun
 	return bestPID, bestPID != 0
 }
 
+// 只接管本次启动之后创建、属于 service 用户且执行启动时校验过的同一核心文件的进程，不按进程名匹配
 func isCoreProcessCandidate(pid int32, launch *launchSession) bool {
 	if launch == nil {
 		return false
@@ -778,17 +849,50 @@ func isCoreProcessCandidate(pid int32, launch *launchSession) bool {
 	if err != nil {
 		return false
 	}
+	createTime, err := proc.CreateTime()
+	if err != nil || createTime < launch.createTime {
+		return false
+	}
+	if !isServiceUserProcess(proc) {
+		return false
+	}
 
-	expectedName := strings.ToLower(filepath.Base(launch.executablePath))
-	if name, err := proc.Name(); err == nil && strings.ToLower(name) == expectedName {
-		return true
+	if launch.binary != nil && launch.binary.File != nil {
+		expected, err := launch.binary.File.Stat()
+		if err != nil {
+			return false
+		}
+		info, err := statProcessExecutable(pid, proc)
+		return err == nil && os.SameFile(expected, info)
+	}
+
+	// 重新接管的核心没有已打开的核心文件，按脱离时记录的可执行文件路径匹配
+	exe, err := proc.Exe()
+	if err != nil {
+		return false
 	}
+	return (launch.coreExe != "" && strings.EqualFold(exe, launch.coreExe)) || strings.EqualFold(exe, launch.executablePath)
+}
 
-	if exe, err := proc.Exe(); err == nil && strings.EqualFold(exe, launch.executablePath) {
+func isServiceUserProcess(proc *process.Process) bool {
+	if runtime.GOOS == "windows" {
+		// Windows 上候选进程已限定在核心的 Job 对象内
 		return true
 	}
+	uids, err := proc.Uids()
+	return err == nil && len(uids) > 1 && int(uids[1]) == os.Geteuid()
+}
 
-	return false
+func statProcessExecutable(pid int32, proc *process.Process) (os.FileInfo, error) {
+	if runtime.GOOS == "linux" {
+		// 通过描述符执行或位于沙盒内时路径与启动路径不一致，直接取进程实际执行的文件
+		return os.Stat(fmt.Sprintf("/proc/%d/exe", pid))
+	}
+	exe, err := proc.Exe()
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(exe)
 }
 
 func (cm *CoreManager) updateStartTimeFromPIDLocked(pid int32) {
@@ -957,6 +1061,7 @@ func (cm *CoreManager) GetProcessInfo() (*ProcessInfo, error) {
 	if launch != nil {
 		info.LaunchMode = "managed"
 		info.Executable = launch.sourcePath
+		info.SHA256 = launch.binaryHash
 	}
 
 	if memInfo, err := proc.MemoryInfo(); err == nil {
@@ -0,0 +1,89 @@
+//go:build !windows
+
+package core
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/UruhaLushia/sparkle-service/core/security"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+func TestIsCoreProcessCandidate(t *testing.T) {
+	sleepPath, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep not found")
+	}
+	cmd := exec.Command(sleepPath, "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	pid := int32(cmd.Process.Pid)
+
+	proc, err := process.NewProcess(pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createTime, err := proc.CreateTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	openBinary := func(path string) *security.VerifiedBinary {
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { file.Close() })
+		return &security.VerifiedBinary{Path: path, File: file}
+	}
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		launch *launchSession
+		want   bool
+	}{
+		{name: "same binary", launch: &launchSession{binary: openBinary(sleepPath), createTime: createTime}, want: true},
+		{name: "created before launch", launch: &launchSession{binary: openBinary(sleepPath), createTime: createTime + int64(time.Hour/time.Millisecond)}},
+		{name: "different binary", launch: &launchSession{binary: openBinary(self), executablePath: sleepPath, createTime: createTime}},
+		{name: "reattached by exe path", launch: &launchSession{executablePath: "/nonexistent/sleep", coreExe: mustExe(t, proc), createTime: createTime}, want: true},
+		{name: "reattached name only", launch: &launchSession{executablePath: "/nonexistent/" + mustName(t, proc), createTime: createTime}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isCoreProcessCandidate(pid, tt.launch); got != tt.want {
+				t.Fatalf("isCoreProcessCandidate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func mustExe(t *testing.T, proc *process.Process) string {
+	t.Helper()
+	exe, err := proc.Exe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return exe
+}
+
+func mustName(t *testing.T, proc *process.Process) string {
+	t.Helper()
+	name, err := proc.Name()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
@@ -0,0 +1,263 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const maxLaunchProfileRevisions = 20
+
+var (
+	profileHistoryMu sync.Mutex
+
+	ErrLaunchProfileRevisionNotFound = errors.New("启动配置历史版本不存在")
+)
+
+type ProfileSaveOption func(*profileSaveOptions)
+
+type profileSaveOptions struct {
+	principal string
+	keyID     string
+	ifMatch   string
+}
+
+func WithProfileAuthor(principal string, keyID string) ProfileSaveOption {
+	return func(options *profileSaveOptions) {
+		options.principal = principal
+		options.keyID = keyID
+	}
+}
+
+func collectProfileSaveOptions(options []ProfileSaveOption) profileSaveOptions {
+	var collected profileSaveOptions
+	for _, option := range options {
+		if option != nil {
+			option(&collected)
+		}
+	}
+	return collected
+}
+
+type LaunchProfileRevision struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name,omitempty"`
+	SavedAt   time.Time     `json:"saved_at"`
+	Principal string        `json:"principal,omitempty"`
+	KeyID     string        `json:"key_id,omitempty"`
+	Profile   LaunchProfile `json:"profile"`
+}
+
+type LaunchProfileChange struct {
+	Path string          `json:"path"`
+	Op   string          `json:"op"`
+	Old  json.RawMessage `json:"old,omitempty"`
+	New  json.RawMessage `json:"new,omitempty"`
+}
+
+type LaunchProfileDiff struct {
+	From    string                `json:"from"`
+	To      string                `json:"to"`
+	Changes []LaunchProfileChange `json:"changes"`
+}
+
+func LaunchProfileHistory() ([]LaunchProfileRevision, error) {
+	profileHistoryMu.Lock()
+	defer profileHistoryMu.Unlock()
+
+	return loadLaunchProfileHistoryLocked()
+}
+
+func LaunchProfileRevisionByID(id string) (*LaunchProfileRevision, error) {
+	history, err := LaunchProfileHistory()
+	if err != nil {
+		return nil, err
+	}
+	for i := range history {
+		if history[i].ID == id {
+			return &history[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrLaunchProfileRevisionNotFound, id)
+}
+
+func DiffLaunchProfileRevision(id string) (*LaunchProfileDiff, error) {
+	revision, err := LaunchProfileRevisionByID(id)
+	if err != nil {
+		return nil, err
+	}
+	current, err := LoadNamedLaunchProfile(revisionProfileName(revision))
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := diffLaunchProfiles(revision.Profile, current)
+	if err != nil {
+		return nil, err
+	}
+	return &LaunchProfileDiff{From: revision.ID, To: "current", Changes: changes}, nil
+}
+
+func RollbackLaunchProfile(id string, options ...ProfileSaveOption) (string, LaunchProfile, error) {
+	revision, err := LaunchProfileRevisionByID(id)
+	if err != nil {
+		return "", LaunchProfile{}, err
+	}
+	name := revisionProfileName(revision)
+	if err := SaveNamedLaunchProfile(name, revision.Profile, options...); err != nil {
+		return "", LaunchProfile{}, err
+	}
+	profile, err := LoadNamedLaunchProfile(name)
+	return name, profile, err
+}
+
+// 该版本所属的命名配置，旧版本记录中缺省为 default
+func (revision *LaunchProfileRevision) ProfileName() string {
+	return revisionProfileName(revision)
+}
+
+func revisionProfileName(revision *LaunchProfileRevision) string {
+	if revision.Name == "" {
+		return defaultLaunchProfileName
+	}
+	return revision.Name
+}
+
+func recordLaunchProfileRevision(name string, profile LaunchProfile, options profileSaveOptions) error {
+	profileHistoryMu.Lock()
+	defer profileHistoryMu.Unlock()
+
+	history, err := loadLaunchProfileHistoryLocked()
+	if err != nil {
+		return err
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		if revisionProfileName(&history[i]) != name {
+			continue
+		}
+		if reflect.DeepEqual(history[i].Profile, profile) {
+			return nil
+		}
+		break
+	}
+
+	nextID := 1
+	if len(history) > 0 {
+		lastID, _ := strconv.Atoi(history[len(history)-1].ID)
+		nextID = lastID + 1
+	}
+	history = append(history, LaunchProfileRevision{
+		ID:        strconv.Itoa(nextID),
+		Name:      name,
+		SavedAt:   time.Now(),
+		Principal: options.principal,
+		KeyID:     options.keyID,
+		Profile:   profile,
+	})
+	if len(history) > maxLaunchProfileRevisions {
+		history = history[len(history)-maxLaunchProfileRevisions:]
+	}
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化核心启动配置历史失败：%w", err)
+	}
+	path := launchProfileHistoryPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("创建核心配置目录失败：%w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("保存核心启动配置历史失败：%w", err)
+	}
+	return nil
+}
+
+func loadLaunchProfileHistoryLocked() ([]LaunchProfileRevision, error) {
+	data, err := os.ReadFile(launchProfileHistoryPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取核心启动配置历史失败：%w", err)
+	}
+
+	var history []LaunchProfileRevision
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("解析核心启动配置历史失败：%w", err)
+	}
+	return history, nil
+}
+
+func diffLaunchProfiles(from LaunchProfile, to LaunchProfile) ([]LaunchProfileChange, error) {
+	oldFields, err := flattenLaunchProfile(from)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := flattenLaunchProfile(to)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := slices.Sorted(maps.Keys(oldFields))
+	for path := range newFields {
+		if _, ok := oldFields[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+
+	changes := []LaunchProfileChange{}
+	for _, path := range paths {
+		oldValue, hadOld := oldFields[path]
+		newValue, hasNew := newFields[path]
+		switch {
+		case !hadOld:
+			changes = append(changes, LaunchProfileChange{Path: path, Op: "add", New: newValue})
+		case !hasNew:
+			changes = append(changes, LaunchProfileChange{Path: path, Op: "remove", Old: oldValue})
+		case string(oldValue) != string(newValue):
+			changes = append(changes, LaunchProfileChange{Path: path, Op: "replace", Old: oldValue, New: newValue})
+		}
+	}
+	return changes, nil
+}
+
+func flattenLaunchProfile(profile LaunchProfile) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return nil, fmt.Errorf("序列化核心启动配置失败：%w", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("解析核心启动配置失败：%w", err)
+	}
+
+	flattened := make(map[string]json.RawMessage, len(fields))
+	for key, value := range fields {
+		if key != "env" {
+			flattened[key] = value
+			continue
+		}
+		var env map[string]json.RawMessage
+		if err := json.Unmarshal(value, &env); err != nil {
+			return nil, fmt.Errorf("解析核心启动配置失败：%w", err)
+		}
+		for name, envValue := range env {
+			flattened["env."+name] = envValue
+		}
+	}
+	return flattened, nil
+}
+
+func launchProfileHistoryPath() string {
+	return filepath.Join(serviceConfigDir(), "sparkle", "core", "launch_profile_history.json")
+}
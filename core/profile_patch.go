@@ -0,0 +1,129 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	launchProfileMu sync.Mutex
+
+	ErrLaunchProfilePreconditionFailed = errors.New("启动配置已被修改")
+)
+
+func WithProfileIfMatch(ifMatch string) ProfileSaveOption {
+	return func(options *profileSaveOptions) {
+		options.ifMatch = strings.TrimSpace(ifMatch)
+	}
+}
+
+func LaunchProfileETag(profile LaunchProfile) string {
+	data, _ := json.Marshal(profile)
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func PatchLaunchProfile(patch []byte, options ...ProfileSaveOption) (LaunchProfile, error) {
+	return PatchNamedLaunchProfile(ActiveLaunchProfileName(), patch, options...)
+}
+
+func PatchNamedLaunchProfile(name string, patch []byte, options ...ProfileSaveOption) (LaunchProfile, error) {
+	launchProfileMu.Lock()
+	defer launchProfileMu.Unlock()
+
+	saveOptions := collectProfileSaveOptions(options)
+	if err := checkLaunchProfileIfMatchLocked(name, saveOptions.ifMatch); err != nil {
+		return LaunchProfile{}, err
+	}
+
+	current, err := LoadNamedLaunchProfile(name)
+	if err != nil {
+		return LaunchProfile{}, err
+	}
+	patched, err := mergePatchLaunchProfile(current, patch)
+	if err != nil {
+		return LaunchProfile{}, err
+	}
+	if err := saveNamedLaunchProfileLocked(name, patched, saveOptions); err != nil {
+		return LaunchProfile{}, err
+	}
+	return LoadNamedLaunchProfile(name)
+}
+
+func checkLaunchProfileIfMatchLocked(name string, ifMatch string) error {
+	if ifMatch == "" {
+		return nil
+	}
+
+	current, err := LoadNamedLaunchProfile(name)
+	if err != nil {
+		return err
+	}
+	etag := LaunchProfileETag(current)
+	for candidate := range strings.SplitSeq(ifMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == etag || (candidate == "*" && !isZeroLaunchProfile(current)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w，当前 ETag 为 %s", ErrLaunchProfilePreconditionFailed, etag)
+}
+
+func mergePatchLaunchProfile(profile LaunchProfile, patch []byte) (LaunchProfile, error) {
+	var patchValue any
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return LaunchProfile{}, fmt.Errorf("解析合并补丁失败：%w", err)
+	}
+	if _, ok := patchValue.(map[string]any); !ok {
+		return LaunchProfile{}, fmt.Errorf("合并补丁必须是 JSON 对象")
+	}
+
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return LaunchProfile{}, fmt.Errorf("序列化核心启动配置失败：%w", err)
+	}
+	var target any
+	if err := json.Unmarshal(data, &target); err != nil {
+		return LaunchProfile{}, fmt.Errorf("解析核心启动配置失败：%w", err)
+	}
+
+	merged, err := json.Marshal(applyMergePatch(target, patchValue))
+	if err != nil {
+		return LaunchProfile{}, fmt.Errorf("序列化核心启动配置失败：%w", err)
+	}
+
+	var patched LaunchProfile
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		return LaunchProfile{}, fmt.Errorf("应用合并补丁失败：%w", err)
+	}
+	return patched, nil
+}
+
+// RFC 7396：对象逐键合并，null 删除键，其余值整体替换
+func applyMergePatch(target any, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = applyMergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
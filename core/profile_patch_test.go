@@ -0,0 +1,118 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	// RFC 7396 附录 A 的示例
+	tests := []struct {
+		target string
+		patch  string
+		want   string
+	}{
+		{target: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{target: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{target: `{"a":"b"}`, patch: `{"a":null}`, want: `{}`},
+		{target: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{target: `{"a":["b"]}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{target: `{"a":"c"}`, patch: `{"a":["b"]}`, want: `{"a":["b"]}`},
+		{target: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, want: `{"a":{"b":"d"}}`},
+		{target: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		{target: `["a","b"]`, patch: `["c","d"]`, want: `["c","d"]`},
+		{target: `{"a":"b"}`, patch: `["c"]`, want: `["c"]`},
+		{target: `{"e":null}`, patch: `{"a":1}`, want: `{"a":1,"e":null}`},
+		{target: `[1,2]`, patch: `{"a":"b","c":null}`, want: `{"a":"b"}`},
+		{target: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, want: `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target+" "+tt.patch, func(t *testing.T) {
+			var target, patch, want any
+			for _, item := range []struct {
+				data  string
+				value *any
+			}{{tt.target, &target}, {tt.patch, &patch}, {tt.want, &want}} {
+				if err := json.Unmarshal([]byte(item.data), item.value); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := applyMergePatch(target, patch); !reflect.DeepEqual(got, want) {
+				t.Fatalf("applyMergePatch() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestPatchLaunchProfile(t *testing.T) {
+	t.Setenv("SPARKLE_CONFIG_DIR", t.TempDir())
+	corePath, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveLaunchProfile(LaunchProfile{CorePath: corePath, Args: []string{"-d", "/etc/mihomo"}, MaxLogFileSizeMB: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		patch   string
+		ifMatch func(etag string) string
+		wantErr string
+		check   func(LaunchProfile) bool
+	}{
+		{name: "replace field", patch: `{"max_log_file_size_mb":20}`, check: func(p LaunchProfile) bool { return p.MaxLogFileSizeMB == 20 && len(p.Args) == 2 }},
+		{name: "null removes field", patch: `{"args":null}`, check: func(p LaunchProfile) bool { return p.Args == nil && p.MaxLogFileSizeMB == 20 }},
+		{name: "matching etag", patch: `{"max_log_file_size_mb":30}`, ifMatch: func(etag string) string { return `"stale", ` + etag }, check: func(p LaunchProfile) bool { return p.MaxLogFileSizeMB == 30 }},
+		{name: "wildcard etag", patch: `{"max_log_file_size_mb":40}`, ifMatch: func(string) string { return "*" }, check: func(p LaunchProfile) bool { return p.MaxLogFileSizeMB == 40 }},
+		{name: "stale etag", patch: `{"max_log_file_size_mb":50}`, ifMatch: func(string) string { return `"stale"` }, wantErr: ErrLaunchProfilePreconditionFailed.Error()},
+		{name: "unknown field", patch: `{"core_args":[]}`, wantErr: "应用合并补丁失败"},
+		{name: "not an object", patch: `[]`, wantErr: "必须是 JSON 对象"},
+		{name: "invalid json", patch: `{`, wantErr: "解析合并补丁失败"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, err := LoadLaunchProfile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var options []ProfileSaveOption
+			if tt.ifMatch != nil {
+				options = append(options, WithProfileIfMatch(tt.ifMatch(LaunchProfileETag(before))))
+			}
+
+			patched, err := PatchLaunchProfile([]byte(tt.patch), options...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("PatchLaunchProfile() error = %v, want %q", err, tt.wantErr)
+				}
+				if tt.wantErr == ErrLaunchProfilePreconditionFailed.Error() && !errors.Is(err, ErrLaunchProfilePreconditionFailed) {
+					t.Fatalf("PatchLaunchProfile() error = %v, want ErrLaunchProfilePreconditionFailed", err)
+				}
+				after, err := LoadLaunchProfile()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if LaunchProfileETag(after) != LaunchProfileETag(before) {
+					t.Fatal("rejected patch changed the profile")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PatchLaunchProfile() error = %v", err)
+			}
+			if !tt.check(patched) {
+				t.Fatalf("patched profile = %+v", patched)
+			}
+			if LaunchProfileETag(patched) == LaunchProfileETag(before) {
+				t.Fatal("ETag unchanged after patch")
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const defaultLaunchProfileName = "default"
+
+var (
+	launchProfileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9_.-]{0,63}$`)
+	launchProfileMigrateMu   sync.Mutex
+
+	ErrLaunchProfileNotFound = errors.New("启动配置不存在")
+	ErrLaunchProfileActive   = errors.New("不能删除当前激活的启动配置")
+)
+
+type LaunchProfileList struct {
+	Active   string   `json:"active"`
+	Profiles []string `json:"profiles"`
+}
+
+func ListLaunchProfiles() (*LaunchProfileList, error) {
+	migrateLegacyLaunchProfile()
+
+	entries, err := os.ReadDir(launchProfilesDir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取启动配置列表失败：%w", err)
+	}
+
+	list := &LaunchProfileList{Active: ActiveLaunchProfileName(), Profiles: []string{}}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() || validateLaunchProfileName(name) != nil {
+			continue
+		}
+		list.Profiles = append(list.Profiles, name)
+	}
+	slices.Sort(list.Profiles)
+	return list, nil
+}
+
+func NamedLaunchProfileExists(name string) (bool, error) {
+	path, err := namedLaunchProfilePath(name)
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("读取启动配置失败：%w", err)
+	}
+	return true, nil
+}
+
+func DeleteNamedLaunchProfile(name string) error {
+	path, err := namedLaunchProfilePath(name)
+	if err != nil {
+		return err
+	}
+	if name == ActiveLaunchProfileName() {
+		return ErrLaunchProfileActive
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrLaunchProfileNotFound, name)
+		}
+		return fmt.Errorf("删除启动配置失败：%w", err)
+	}
+	return nil
+}
+
+func ActiveLaunchProfileName() string {
+	data, err := os.ReadFile(activeLaunchProfilePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("读取当前启动配置名称失败: %v", err)
+		}
+		return defaultLaunchProfileName
+	}
+
+	name := strings.TrimSpace(string(data))
+	if validateLaunchProfileName(name) != nil {
+		return defaultLaunchProfileName
+	}
+	return name
+}
+
+func SetActiveLaunchProfile(name string) error {
+	exists, err := NamedLaunchProfileExists(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrLaunchProfileNotFound, name)
+	}
+
+	path := activeLaunchProfilePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("创建核心配置目录失败：%w", err)
+	}
+	if err := os.WriteFile(path, []byte(name+"\n"), 0o600); err != nil {
+		return fmt.Errorf("保存当前启动配置名称失败：%w", err)
+	}
+	return nil
+}
+
+func validateLaunchProfileName(name string) error {
+	if !launchProfileNamePattern.MatchString(name) {
+		return fmt.Errorf("无效的启动配置名称: %q", name)
+	}
+	return nil
+}
+
+func namedLaunchProfilePath(name string) (string, error) {
+	if err := validateLaunchProfileName(name); err != nil {
+		return "", err
+	}
+	migrateLegacyLaunchProfile()
+	return filepath.Join(launchProfilesDir(), name+".json"), nil
+}
+
+// 旧版本只有一个 launch_profile.json，首次访问时迁移为 default 配置
+func migrateLegacyLaunchProfile() {
+	launchProfileMigrateMu.Lock()
+	defer launchProfileMigrateMu.Unlock()
+
+	legacyPath := filepath.Join(serviceConfigDir(), "sparkle", "core", "launch_profile.json")
+	if _, err := os.Stat(legacyPath); err != nil {
+		return
+	}
+	target := filepath.Join(launchProfilesDir(), defaultLaunchProfileName+".json")
+	if _, err := os.Stat(target); err == nil {
+		return
+	}
+	if err := os.MkdirAll(launchProfilesDir(), 0o755); err != nil {
+		log.Printf("迁移核心启动配置失败: %v", err)
+		return
+	}
+	if err := os.Rename(legacyPath, target); err != nil {
+		log.Printf("迁移核心启动配置失败: %v", err)
+	}
+}
+
+func launchProfilesDir() string {
+	return filepath.Join(serviceConfigDir(), "sparkle", "core", "profiles")
+}
+
+func activeLaunchProfilePath() string {
+	return filepath.Join(serviceConfigDir(), "sparkle", "core", "active_profile")
+}
@@ -0,0 +1,213 @@
+package core
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	sandboxSweepInterval = 10 * time.Minute
+	sandboxStaleGrace    = time.Minute
+)
+
+const (
+	SandboxRootKindSandbox    = "sandbox"
+	SandboxRootKindController = "controller"
+	SandboxRootKindReady      = "ready"
+	SandboxRootKindOutput     = "output"
+)
+
+type SandboxRoot struct {
+	Path    string    `json:"path"`
+	Kind    string    `json:"kind"`
+	Active  bool      `json:"active"`
+	Mounts  int       `json:"mounts"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+type SandboxSweep struct {
+	Time    time.Time `json:"time"`
+	Removed []string  `json:"removed,omitempty"`
+	Errors  []string  `json:"errors,omitempty"`
+}
+
+type SandboxReport struct {
+	BaseDir   string        `json:"base_dir,omitempty"`
+	Roots     []SandboxRoot `json:"roots"`
+	LastSweep *SandboxSweep `json:"last_sweep,omitempty"`
+}
+
+type sandboxRootPattern struct {
+	kind    string
+	pattern string
+}
+
+var sandboxRoots = struct {
+	mutex     sync.Mutex
+	active    map[string]struct{}
+	lastSweep *SandboxSweep
+	sweepMu   sync.Mutex
+}{
+	active: make(map[string]struct{}),
+}
+
+func registerActiveSandboxRoot(path string) func() {
+	if path == "" {
+		return func() {}
+	}
+	path = filepath.Clean(path)
+
+	sandboxRoots.mutex.Lock()
+	sandboxRoots.active[path] = struct{}{}
+	sandboxRoots.mutex.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			sandboxRoots.mutex.Lock()
+			delete(sandboxRoots.active, path)
+			sandboxRoots.mutex.Unlock()
+		})
+	}
+}
+
+func isActiveSandboxRoot(path string) bool {
+	sandboxRoots.mutex.Lock()
+	defer sandboxRoots.mutex.Unlock()
+	_, ok := sandboxRoots.active[filepath.Clean(path)]
+	return ok
+}
+
+func SandboxInventory() SandboxReport {
+	report := SandboxReport{
+		BaseDir: sandboxBaseDir(),
+		Roots:   listSandboxRoots(),
+	}
+
+	sandboxRoots.mutex.Lock()
+	if sandboxRoots.lastSweep != nil {
+		sweep := *sandboxRoots.lastSweep
+		report.LastSweep = &sweep
+	}
+	sandboxRoots.mutex.Unlock()
+
+	return report
+}
+
+func SweepSandboxRoots() SandboxSweep {
+	sandboxRoots.sweepMu.Lock()
+	defer sandboxRoots.sweepMu.Unlock()
+
+	sweep := SandboxSweep{Time: time.Now()}
+	for _, root := range listSandboxRoots() {
+		if root.Active || sweep.Time.Sub(root.ModTime) < sandboxStaleGrace {
+			continue
+		}
+		if isActiveSandboxRoot(root.Path) {
+			continue
+		}
+		if err := cleanupSandboxRoot(root); err != nil {
+			logSandboxCleanupError(err)
+			sweep.Errors = append(sweep.Errors, err.Error())
+			continue
+		}
+		sweep.Removed = append(sweep.Removed, root.Path)
+	}
+
+	sandboxRoots.mutex.Lock()
+	sandboxRoots.lastSweep = &sweep
+	sandboxRoots.mutex.Unlock()
+
+	if len(sweep.Removed) > 0 {
+		log.Printf("已清理残留核心临时目录: %v", sweep.Removed)
+	}
+	return sweep
+}
+
+func StartSandboxJanitor() func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		SweepSandboxRoots()
+		ticker := time.NewTicker(sandboxSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				SweepSandboxRoots()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stop)
+			<-done
+		})
+	}
+}
+
+func listSandboxRoots() []SandboxRoot {
+	roots := make([]SandboxRoot, 0)
+	seen := make(map[string]struct{})
+	for _, pattern := range sandboxRootPatterns() {
+		matches, err := filepath.Glob(pattern.pattern)
+		if err != nil {
+			logSandboxCleanupError(err)
+			continue
+		}
+		for _, path := range matches {
+			path = filepath.Clean(path)
+			if _, ok := seen[path]; ok {
+				continue
+			}
+			info, err := os.Lstat(path)
+			if err != nil || !info.IsDir() {
+				continue
+			}
+			// 临时目录中同名的目录可能属于其他用户，只处理 service 自己创建的私有目录
+			if !isServicePrivateDir(info) {
+				continue
+			}
+			seen[path] = struct{}{}
+			roots = append(roots, SandboxRoot{
+				Path:    path,
+				Kind:    pattern.kind,
+				Active:  isActiveSandboxRoot(path),
+				Mounts:  sandboxMountCount(path),
+				ModTime: info.ModTime(),
+			})
+		}
+	}
+
+	slices.SortFunc(roots, func(a, b SandboxRoot) int {
+		return a.ModTime.Compare(b.ModTime)
+	})
+	return roots
+}
+
+func cleanupSandboxRoot(root SandboxRoot) error {
+	if root.Kind == SandboxRootKindSandbox {
+		return cleanupSandboxRootMounts(root.Path)
+	}
+	return os.RemoveAll(root.Path)
+}
+
+func tempSandboxRootPatterns() []sandboxRootPattern {
+	return []sandboxRootPattern{
+		{kind: SandboxRootKindController, pattern: filepath.Join(os.TempDir(), "sparkle-mihomo-controller-*")},
+		{kind: SandboxRootKindReady, pattern: filepath.Join(os.TempDir(), "sparkle-core-ready-*")},
+		{kind: SandboxRootKindOutput, pattern: filepath.Join(os.TempDir(), "sparkle-core-output-*")},
+	}
+}
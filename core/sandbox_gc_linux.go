@@ -0,0 +1,55 @@
+//go:build linux
+
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+const linuxSandboxBaseDir = "/run/sparkle/sandbox"
+
+func sandboxBaseDir() string {
+	return linuxSandboxBaseDir
+}
+
+func sandboxRootPatterns() []sandboxRootPattern {
+	return append([]sandboxRootPattern{
+		{kind: SandboxRootKindSandbox, pattern: filepath.Join(linuxSandboxBaseDir, "sparkle-core-sandbox-*")},
+		{kind: SandboxRootKindSandbox, pattern: filepath.Join(os.TempDir(), "sparkle-core-sandbox-*")},
+	}, tempSandboxRootPatterns()...)
+}
+
+func sandboxMountCount(path string) int {
+	return len(linuxMountPointsUnder(path))
+}
+
+func cleanupSandboxRootMounts(root string) error {
+	return cleanupLinuxSandboxRoot(root)
+}
+
+func ensureSandboxBaseDir() (string, error) {
+	if err := os.MkdirAll(linuxSandboxBaseDir, 0o700); err != nil {
+		return "", fmt.Errorf("创建核心沙盒根目录失败：%w", err)
+	}
+
+	info, err := os.Lstat(linuxSandboxBaseDir)
+	if err != nil {
+		return "", fmt.Errorf("读取核心沙盒根目录失败：%w", err)
+	}
+	if !info.IsDir() || info.Mode()&os.ModeSymlink != 0 {
+		return "", fmt.Errorf("核心沙盒根目录不是普通目录: %s", linuxSandboxBaseDir)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Geteuid() {
+		return "", fmt.Errorf("核心沙盒根目录所有者不是 service: %s", linuxSandboxBaseDir)
+	}
+	if info.Mode().Perm() != 0o700 {
+		if err := os.Chmod(linuxSandboxBaseDir, 0o700); err != nil {
+			return "", fmt.Errorf("设置核心沙盒根目录权限失败：%w", err)
+		}
+	}
+
+	return linuxSandboxBaseDir, nil
+}
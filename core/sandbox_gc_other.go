@@ -0,0 +1,30 @@
+//go:build !linux
+
+package core
+
+import (
+	"log"
+	"os"
+)
+
+func sandboxBaseDir() string {
+	return ""
+}
+
+func sandboxRootPatterns() []sandboxRootPattern {
+	return tempSandboxRootPatterns()
+}
+
+func sandboxMountCount(_ string) int {
+	return 0
+}
+
+func cleanupSandboxRootMounts(root string) error {
+	return os.RemoveAll(root)
+}
+
+func logSandboxCleanupError(err error) {
+	if err != nil {
+		log.Printf("清理核心沙盒失败：%v", err)
+	}
+}
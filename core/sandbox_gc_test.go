@@ -0,0 +1,70 @@
+//go:build !windows
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSweepSandboxRootsSkipsForeignDirs(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	stale := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name    string
+		dir     string
+		mode    os.FileMode
+		uid     int
+		removed bool
+	}{
+		{name: "private", dir: "sparkle-core-ready-own", mode: 0o700, uid: -1, removed: true},
+		{name: "shared mode", dir: "sparkle-mihomo-controller-shared", mode: 0o755, uid: -1},
+		{name: "other owner", dir: "sparkle-core-output-other", mode: 0o700, uid: 65534},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(tmp, tt.dir)
+		if err := os.Mkdir(path, tt.mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, tt.mode); err != nil {
+			t.Fatal(err)
+		}
+		if tt.uid >= 0 {
+			if os.Geteuid() != 0 {
+				continue
+			}
+			if err := os.Chown(path, tt.uid, tt.uid); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.Chtimes(path, stale, stale); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sweep := SweepSandboxRoots()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tmp, tt.dir)
+			_, err := os.Stat(path)
+			if tt.removed {
+				if !os.IsNotExist(err) || !slices.Contains(sweep.Removed, path) {
+					t.Fatalf("%s was not removed", path)
+				}
+				return
+			}
+			if err != nil && !os.IsNotExist(err) {
+				t.Fatal(err)
+			}
+			if slices.Contains(sweep.Removed, path) {
+				t.Fatalf("%s was removed", path)
+			}
+		})
+	}
+}
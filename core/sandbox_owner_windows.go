@@ -0,0 +1,10 @@
+//go:build windows
+
+package core
+
+import "os"
+
+// Windows 下核心控制器与启动通知使用命名管道，临时目录中不会有 service 创建的残留目录
+func isServicePrivateDir(_ os.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,184 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+const (
+	IOClassNone       = "none"
+	IOClassRealtime   = "realtime"
+	IOClassBestEffort = "best-effort"
+	IOClassIdle       = "idle"
+)
+
+var (
+	ErrCoreNotRunning       = errors.New("核心进程未运行")
+	ErrSchedulingNotApplied = errors.New("调度参数未能生效，启动配置保持不变")
+)
+
+type LaunchScheduling struct {
+	Nice        *int   `json:"nice,omitempty"`
+	IOClass     string `json:"io_class,omitempty"`
+	IOLevel     *int   `json:"io_level,omitempty"`
+	CPUAffinity []int  `json:"cpu_affinity,omitempty"`
+	OOMScoreAdj *int   `json:"oom_score_adj,omitempty"`
+}
+
+type SchedulingResult struct {
+	PIDs       []int32           `json:"pids"`
+	Scheduling *LaunchScheduling `json:"scheduling,omitempty"`
+}
+
+func normalizeLaunchScheduling(scheduling *LaunchScheduling) (*LaunchScheduling, error) {
+	if scheduling == nil {
+		return nil, nil
+	}
+
+	normalized := &LaunchScheduling{
+		IOClass: strings.ToLower(strings.TrimSpace(scheduling.IOClass)),
+	}
+	if scheduling.Nice != nil {
+		nice := *scheduling.Nice
+		if nice < -20 || nice > 19 {
+			return nil, fmt.Errorf("nice 必须在 -20 到 19 之间")
+		}
+		normalized.Nice = &nice
+	}
+	switch normalized.IOClass {
+	case "", IOClassNone, IOClassRealtime, IOClassBestEffort, IOClassIdle:
+	default:
+		return nil, fmt.Errorf("不支持的 IO 调度类别: %s", scheduling.IOClass)
+	}
+	if scheduling.IOLevel != nil {
+		level := *scheduling.IOLevel
+		if level < 0 || level > 7 {
+			return nil, fmt.Errorf("io_level 必须在 0 到 7 之间")
+		}
+		if normalized.IOClass == "" {
+			return nil, fmt.Errorf("设置 io_level 时必须指定 io_class")
+		}
+		normalized.IOLevel = &level
+	}
+	if len(scheduling.CPUAffinity) > 0 {
+		affinity := slices.Clone(scheduling.CPUAffinity)
+		slices.Sort(affinity)
+		normalized.CPUAffinity = slices.Compact(affinity)
+	}
+	if scheduling.OOMScoreAdj != nil {
+		adj := *scheduling.OOMScoreAdj
+		if adj < -1000 || adj > 1000 {
+			return nil, fmt.Errorf("oom_score_adj 必须在 -1000 到 1000 之间")
+		}
+		normalized.OOMScoreAdj = &adj
+	}
+
+	if isZeroLaunchScheduling(normalized) {
+		return nil, nil
+	}
+	if err := validatePlatformScheduling(normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+func isZeroLaunchScheduling(scheduling *LaunchScheduling) bool {
+	return scheduling == nil ||
+		scheduling.Nice == nil &&
+			scheduling.IOClass == "" &&
+			scheduling.IOLevel == nil &&
+			len(scheduling.CPUAffinity) == 0 &&
+			scheduling.OOMScoreAdj == nil
+}
+
+// 将合并补丁应用到当前启动配置的调度参数：先对运行中的核心生效，成功后才保存，
+// 调整失败时已保存的配置保持不变。核心未运行时仅保存并返回 ErrCoreNotRunning
+func (cm *CoreManager) PatchScheduling(patch []byte, options ...ProfileSaveOption) (LaunchProfile, *SchedulingResult, error) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+	launchProfileMu.Lock()
+	defer launchProfileMu.Unlock()
+
+	name := ActiveLaunchProfileName()
+	saveOptions := collectProfileSaveOptions(options)
+	if err := checkLaunchProfileIfMatchLocked(name, saveOptions.ifMatch); err != nil {
+		return LaunchProfile{}, nil, err
+	}
+	current, err := LoadNamedLaunchProfile(name)
+	if err != nil {
+		return LaunchProfile{}, nil, err
+	}
+	patched, err := mergePatchLaunchProfile(current, patch)
+	if err != nil {
+		return LaunchProfile{}, nil, err
+	}
+	normalized, err := normalizeLaunchProfile(patched)
+	if err != nil {
+		return LaunchProfile{}, nil, err
+	}
+	if !isZeroLaunchProfile(normalized) {
+		if err := enforceLaunchPolicy(normalized); err != nil {
+			return LaunchProfile{}, nil, err
+		}
+	}
+
+	result, applyErr := cm.applySchedulingLocked(normalized.Scheduling)
+	if applyErr != nil && !errors.Is(applyErr, ErrCoreNotRunning) {
+		return LaunchProfile{}, nil, fmt.Errorf("%w: %w", ErrSchedulingNotApplied, applyErr)
+	}
+	if err := saveNamedLaunchProfileLocked(name, normalized, saveOptions); err != nil {
+		return LaunchProfile{}, nil, fmt.Errorf("调度参数已对运行中的核心生效，但保存启动配置失败：%w", err)
+	}
+	profile, err := LoadNamedLaunchProfile(name)
+	if err != nil {
+		return LaunchProfile{}, nil, err
+	}
+	return profile, result, applyErr
+}
+
+// 调整运行中核心及其子进程的调度参数，未设置的字段保持不变
+func (cm *CoreManager) applySchedulingLocked(scheduling *LaunchScheduling) (*SchedulingResult, error) {
+	pid := cm.pid.Load()
+	if pid <= 0 || !cm.isRunning.Load() {
+		return nil, ErrCoreNotRunning
+	}
+
+	pids := coreProcessTree(pid)
+	result := &SchedulingResult{PIDs: pids, Scheduling: scheduling}
+	if isZeroLaunchScheduling(scheduling) {
+		return result, nil
+	}
+	for _, target := range pids {
+		if err := applyProcessScheduling(target, scheduling); err != nil {
+			return nil, fmt.Errorf("调整核心进程 %d 调度参数失败：%w", target, err)
+		}
+	}
+	if cm.launch != nil {
+		cm.launch.profile.Scheduling = scheduling
+	}
+	return result, nil
+}
+
+func coreProcessTree(pid int32) []int32 {
+	pids := []int32{pid}
+	for i := 0; i < len(pids); i++ {
+		proc, err := process.NewProcess(pids[i])
+		if err != nil {
+			continue
+		}
+		children, err := proc.Children()
+		if err != nil {
+			continue
+		}
+		for _, child := range children {
+			if !slices.Contains(pids, child.Pid) {
+				pids = append(pids, child.Pid)
+			}
+		}
+	}
+	return pids
+}
@@ -0,0 +1,102 @@
+//go:build linux
+
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+)
+
+var ioprioClasses = map[string]int{
+	IOClassNone:       0,
+	IOClassRealtime:   1,
+	IOClassBestEffort: 2,
+	IOClassIdle:       3,
+}
+
+func validatePlatformScheduling(scheduling *LaunchScheduling) error {
+	if len(scheduling.CPUAffinity) == 0 {
+		return nil
+	}
+
+	var allowed unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &allowed); err != nil {
+		return fmt.Errorf("读取可用 CPU 失败：%w", err)
+	}
+	for _, cpu := range scheduling.CPUAffinity {
+		if cpu < 0 || !allowed.IsSet(cpu) {
+			return fmt.Errorf("CPU %d 不可用", cpu)
+		}
+	}
+	return nil
+}
+
+func applyProcessScheduling(pid int32, scheduling *LaunchScheduling) error {
+	if isZeroLaunchScheduling(scheduling) {
+		return nil
+	}
+	// nice、IO 优先级与 CPU 亲和性在 Linux 上按线程生效，需要逐个线程设置
+	tids, err := processThreadIDs(pid)
+	if err != nil {
+		return err
+	}
+
+	for _, tid := range tids {
+		if scheduling.Nice != nil {
+			if err := unix.Setpriority(unix.PRIO_PROCESS, tid, *scheduling.Nice); err != nil {
+				return fmt.Errorf("设置 nice 失败：%w", err)
+			}
+		}
+		if scheduling.IOClass != "" {
+			level := 0
+			if scheduling.IOLevel != nil {
+				level = *scheduling.IOLevel
+			}
+			value := ioprioClasses[scheduling.IOClass]<<ioprioClassShift | level
+			if _, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), uintptr(value)); errno != 0 {
+				return fmt.Errorf("设置 IO 优先级失败：%w", errno)
+			}
+		}
+		if len(scheduling.CPUAffinity) > 0 {
+			var set unix.CPUSet
+			for _, cpu := range scheduling.CPUAffinity {
+				set.Set(cpu)
+			}
+			if err := unix.SchedSetaffinity(tid, &set); err != nil {
+				return fmt.Errorf("设置 CPU 亲和性失败：%w", err)
+			}
+		}
+	}
+
+	if scheduling.OOMScoreAdj != nil {
+		path := filepath.Join("/proc", strconv.Itoa(int(pid)), "oom_score_adj")
+		if err := os.WriteFile(path, []byte(strconv.Itoa(*scheduling.OOMScoreAdj)), 0o644); err != nil {
+			return fmt.Errorf("设置 oom_score_adj 失败：%w", err)
+		}
+	}
+	return nil
+}
+
+func processThreadIDs(pid int32) ([]int, error) {
+	entries, err := os.ReadDir(filepath.Join("/proc", strconv.Itoa(int(pid)), "task"))
+	if err != nil {
+		return nil, fmt.Errorf("读取核心进程线程失败：%w", err)
+	}
+
+	tids := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		if tid, err := strconv.Atoi(entry.Name()); err == nil {
+			tids = append(tids, tid)
+		}
+	}
+	return tids, nil
+}
@@ -0,0 +1,25 @@
+//go:build !linux && !windows
+
+package core
+
+import (
+	"fmt"
+	"syscall"
+)
+
+func validatePlatformScheduling(scheduling *LaunchScheduling) error {
+	if scheduling.IOClass != "" || scheduling.IOLevel != nil || len(scheduling.CPUAffinity) > 0 || scheduling.OOMScoreAdj != nil {
+		return fmt.Errorf("当前平台仅支持调整 nice")
+	}
+	return nil
+}
+
+func applyProcessScheduling(pid int32, scheduling *LaunchScheduling) error {
+	if scheduling == nil || scheduling.Nice == nil {
+		return nil
+	}
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, int(pid), *scheduling.Nice); err != nil {
+		return fmt.Errorf("设置 nice 失败：%w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+//go:build linux
+
+package core
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+)
+
+func TestPatchScheduling(t *testing.T) {
+	t.Setenv("SPARKLE_CONFIG_DIR", t.TempDir())
+
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Skipf("true not available: %v", err)
+	}
+	if err := SaveLaunchProfile(LaunchProfile{CorePath: exited.Path}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		pid      int32
+		patch    string
+		wantErr  error
+		wantNice *int
+	}{
+		{name: "not running saves", patch: `{"scheduling":{"nice":5}}`, wantErr: ErrCoreNotRunning, wantNice: intPtr(5)},
+		{name: "apply failure keeps profile", pid: int32(exited.Process.Pid), patch: `{"scheduling":{"nice":10}}`, wantErr: ErrSchedulingNotApplied, wantNice: intPtr(5)},
+		{name: "invalid value keeps profile", patch: `{"scheduling":{"nice":100}}`, wantNice: intPtr(5)},
+		{name: "null removes field", patch: `{"scheduling":{"nice":null}}`, wantErr: ErrCoreNotRunning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := NewCoreManager()
+			if tt.pid > 0 {
+				cm.pid.Store(tt.pid)
+				cm.isRunning.Store(true)
+			}
+
+			_, _, err := cm.PatchScheduling([]byte(tt.patch))
+			switch {
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+				t.Fatalf("PatchScheduling() error = %v, want %v", err, tt.wantErr)
+			case tt.wantErr == nil && err == nil:
+				t.Fatal("PatchScheduling() succeeded, want validation error")
+			}
+
+			profile, err := LoadLaunchProfile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var nice *int
+			if profile.Scheduling != nil {
+				nice = profile.Scheduling.Nice
+			}
+			if (nice == nil) != (tt.wantNice == nil) || nice != nil && *nice != *tt.wantNice {
+				t.Fatalf("saved nice = %v, want %v", nice, tt.wantNice)
+			}
+		})
+	}
+}
+
+func intPtr(value int) *int {
+	return &value
+}
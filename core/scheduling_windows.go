@@ -0,0 +1,23 @@
+//go:build windows
+
+package core
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+func validatePlatformScheduling(scheduling *LaunchScheduling) error {
+	if scheduling != nil && !isZeroLaunchScheduling(scheduling) {
+		return fmt.Errorf("Windows 不支持调整调度参数，请使用 mihomo_cpu_priority")
+	}
+	return nil
+}
+
+func applyProcessScheduling(_ int32, scheduling *LaunchScheduling) error {
+	if isZeroLaunchScheduling(scheduling) {
+		return nil
+	}
+	return fmt.Errorf("Windows 不支持调整调度参数：%w", windows.ERROR_NOT_SUPPORTED)
+}
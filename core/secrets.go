@@ -0,0 +1,239 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	secretNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,128}$`)
+	secretsMu         sync.Mutex
+)
+
+type LaunchEnvValue struct {
+	Value  string
+	Secret string
+}
+
+type launchEnvSecretRef struct {
+	Secret string `json:"secret"`
+}
+
+func (v LaunchEnvValue) MarshalJSON() ([]byte, error) {
+	if v.Secret != "" {
+		return json.Marshal(launchEnvSecretRef{Secret: v.Secret})
+	}
+	return json.Marshal(v.Value)
+}
+
+func (v *LaunchEnvValue) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var ref launchEnvSecretRef
+		if err := json.Unmarshal(data, &ref); err != nil {
+			return err
+		}
+		if ref.Secret == "" {
+			return fmt.Errorf("环境变量的 secret 引用不能为空")
+		}
+		*v = LaunchEnvValue{Secret: ref.Secret}
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*v = LaunchEnvValue{Value: value}
+	return nil
+}
+
+type SecretInfo struct {
+	Name      string    `json:"name"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type storedSecret struct {
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func ListSecrets() ([]SecretInfo, error) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	secrets, err := loadSecretsLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]SecretInfo, 0, len(secrets))
+	for name, secret := range secrets {
+		infos = append(infos, SecretInfo{Name: name, UpdatedAt: secret.UpdatedAt})
+	}
+	slices.SortFunc(infos, func(a, b SecretInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return infos, nil
+}
+
+func SetSecret(name string, value string) error {
+	if err := validateSecretName(name); err != nil {
+		return err
+	}
+
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	secrets, err := loadSecretsLocked()
+	if err != nil {
+		return err
+	}
+	secrets[name] = storedSecret{Value: value, UpdatedAt: time.Now()}
+	return saveSecretsLocked(secrets)
+}
+
+func DeleteSecret(name string) (bool, error) {
+	if err := validateSecretName(name); err != nil {
+		return false, err
+	}
+
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	secrets, err := loadSecretsLocked()
+	if err != nil {
+		return false, err
+	}
+	if _, ok := secrets[name]; !ok {
+		return false, nil
+	}
+	delete(secrets, name)
+	return true, saveSecretsLocked(secrets)
+}
+
+// 预览启动计划时只检查引用的 secret 是否存在，不读取其值
+func resolveLaunchEnv(env map[string]LaunchEnvValue, dryRun bool) (map[string]string, error) {
+	resolved := make(map[string]string, len(env))
+	var secrets map[string]storedSecret
+	for key, value := range env {
+		if value.Secret == "" {
+			resolved[key] = value.Value
+			continue
+		}
+		if secrets == nil {
+			loaded, err := loadLaunchEnvSecrets(dryRun)
+			if err != nil {
+				return nil, err
+			}
+			secrets = loaded
+		}
+		secret, ok := secrets[value.Secret]
+		if !ok {
+			return nil, fmt.Errorf("环境变量 %s 引用的 secret %s 不存在", key, value.Secret)
+		}
+		if dryRun {
+			resolved[key] = launchPlanRedacted
+			continue
+		}
+		resolved[key] = secret.Value
+	}
+	return resolved, nil
+}
+
+// namesOnly 时返回的 secret 不含值
+func loadLaunchEnvSecrets(namesOnly bool) (map[string]storedSecret, error) {
+	if !namesOnly {
+		secretsMu.Lock()
+		defer secretsMu.Unlock()
+		return loadSecretsLocked()
+	}
+
+	infos, err := ListSecrets()
+	if err != nil {
+		return nil, err
+	}
+	secrets := make(map[string]storedSecret, len(infos))
+	for _, info := range infos {
+		secrets[info.Name] = storedSecret{UpdatedAt: info.UpdatedAt}
+	}
+	return secrets, nil
+}
+
+func validateSecretName(name string) error {
+	if !secretNamePattern.MatchString(name) {
+		return fmt.Errorf("无效的 secret 名称: %q", name)
+	}
+	return nil
+}
+
+func loadSecretsLocked() (map[string]storedSecret, error) {
+	path := secretsPath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]storedSecret{}, nil
+		}
+		return nil, fmt.Errorf("读取 secret 存储失败：%w", err)
+	}
+	if err := checkSecretsFile(path); err != nil {
+		return nil, err
+	}
+
+	secrets := map[string]storedSecret{}
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("解析 secret 存储失败：%w", err)
+	}
+	return secrets, nil
+}
+
+func saveSecretsLocked(secrets map[string]storedSecret) error {
+	path := secretsPath()
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("创建 secret 存储目录失败：%w", err)
+	}
+	if err := restrictSecretsPath(dir, true); err != nil {
+		return fmt.Errorf("加固 secret 存储目录失败：%w", err)
+	}
+
+	data, err := json.MarshalIndent(secrets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化 secret 存储失败：%w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".secrets-*.json")
+	if err != nil {
+		return fmt.Errorf("写入 secret 存储失败：%w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if err := restrictSecretsPath(tmpPath, false); err != nil {
+		tmp.Close()
+		return fmt.Errorf("加固 secret 存储失败：%w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入 secret 存储失败：%w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("写入 secret 存储失败：%w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("写入 secret 存储失败：%w", err)
+	}
+	return nil
+}
+
+func secretsPath() string {
+	return filepath.Join(serviceConfigDir(), "sparkle", "secrets", "secrets.json")
+}
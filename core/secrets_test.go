@@ -0,0 +1,104 @@
+package core
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLaunchEnvValueJSON(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    LaunchEnvValue
+		wantErr bool
+	}{
+		{data: `"plain"`, want: LaunchEnvValue{Value: "plain"}},
+		{data: `""`, want: LaunchEnvValue{}},
+		{data: `{"secret":"tailscale"}`, want: LaunchEnvValue{Secret: "tailscale"}},
+		{data: ` {"secret":"tailscale"}`, want: LaunchEnvValue{Secret: "tailscale"}},
+		{data: `{"secret":""}`, wantErr: true},
+		{data: `{"value":"plain"}`, wantErr: true},
+		{data: `1`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			var got LaunchEnvValue
+			err := json.Unmarshal([]byte(tt.data), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Unmarshal() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Unmarshal() = %+v, want %+v", got, tt.want)
+			}
+
+			data, err := json.Marshal(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := strings.TrimSpace(tt.data); string(data) != want {
+				t.Fatalf("Marshal() = %s, want %s", data, want)
+			}
+		})
+	}
+}
+
+func TestResolveLaunchEnv(t *testing.T) {
+	t.Setenv("SPARKLE_CONFIG_DIR", t.TempDir())
+	if err := SetSecret("tailscale", "tskey-secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]LaunchEnvValue
+		dryRun  bool
+		want    map[string]string
+		wantErr string
+	}{
+		{name: "plain value", env: map[string]LaunchEnvValue{"A": {Value: "1"}}, want: map[string]string{"A": "1"}},
+		{name: "secret", env: map[string]LaunchEnvValue{"TS_AUTHKEY": {Secret: "tailscale"}}, want: map[string]string{"TS_AUTHKEY": "tskey-secret"}},
+		{name: "dry run does not resolve", env: map[string]LaunchEnvValue{"TS_AUTHKEY": {Secret: "tailscale"}}, dryRun: true, want: map[string]string{"TS_AUTHKEY": launchPlanRedacted}},
+		{name: "missing secret", env: map[string]LaunchEnvValue{"TOKEN": {Secret: "missing"}}, wantErr: "环境变量 TOKEN 引用的 secret missing 不存在"},
+		{name: "dry run missing secret", env: map[string]LaunchEnvValue{"TOKEN": {Secret: "missing"}}, dryRun: true, wantErr: "secret missing 不存在"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveLaunchEnv(tt.env, tt.dryRun)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveLaunchEnv() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveLaunchEnv() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("resolveLaunchEnv() = %v, want %v", got, tt.want)
+			}
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Fatalf("resolveLaunchEnv()[%s] = %q, want %q", key, got[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestLaunchPlanEnvRedactsValues(t *testing.T) {
+	got := launchPlanEnv([]string{"TS_AUTHKEY=tskey-secret", "EMPTY=", "PATH=/usr/bin"})
+	want := map[string]string{"TS_AUTHKEY": launchPlanRedacted, "EMPTY": "", "PATH": launchPlanRedacted}
+	for key, value := range want {
+		if got[key] != value {
+			t.Fatalf("launchPlanEnv()[%s] = %q, want %q", key, got[key], value)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+
+package core
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+func restrictSecretsPath(path string, dir bool) error {
+	mode := os.FileMode(0o600)
+	if dir {
+		mode = 0o700
+	}
+	return os.Chmod(path, mode)
+}
+
+func checkSecretsFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("读取 secret 存储失败：%w", err)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); !ok || (stat.Uid != 0 && int(stat.Uid) != os.Geteuid()) {
+		return fmt.Errorf("secret 存储 %s 的所有者不是 root 或 service 用户", path)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf("secret 存储 %s 可被其他用户访问 (mode=%04o)", path, info.Mode().Perm())
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+//go:build !windows
+
+package core
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCheckSecretsFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    os.FileMode
+		uid     int
+		wantErr string
+	}{
+		{name: "owner only", mode: 0o600, uid: -1},
+		{name: "group readable", mode: 0o640, uid: -1, wantErr: "可被其他用户访问"},
+		{name: "world readable", mode: 0o604, uid: -1, wantErr: "可被其他用户访问"},
+		{name: "other owner", mode: 0o600, uid: 65534, wantErr: "所有者不是 root 或 service 用户"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.uid >= 0 && os.Geteuid() != 0 {
+				t.Skip("需要 root 权限修改文件所有者")
+			}
+			t.Setenv("SPARKLE_CONFIG_DIR", t.TempDir())
+			if err := SetSecret("tailscale", "tskey-secret"); err != nil {
+				t.Fatal(err)
+			}
+			path := secretsPath()
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0o600 {
+				t.Fatalf("secret store mode = %04o, want 0600", info.Mode().Perm())
+			}
+			if err := os.Chmod(path, tt.mode); err != nil {
+				t.Fatal(err)
+			}
+			if tt.uid >= 0 {
+				if err := os.Chown(path, tt.uid, -1); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err = ListSecrets()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ListSecrets() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ListSecrets() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+//go:build windows
+
+package core
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/UruhaLushia/sparkle-service/core/security"
+
+	"golang.org/x/sys/windows"
+)
+
+func restrictSecretsPath(path string, dir bool) error {
+	inheritance := uint32(windows.NO_INHERITANCE)
+	if dir {
+		inheritance = windows.SUB_CONTAINERS_AND_OBJECTS_INHERIT
+	}
+	return security.RestrictPath(path, inheritance)
+}
+
+func checkSecretsFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("读取 secret 存储失败：%w", err)
+	}
+	if err := security.CheckAdminOwned(path, info); err != nil {
+		return fmt.Errorf("secret 存储不可信：%w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package security
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+type VerifiedBinary struct {
+	Path   string
+	SHA256 string
+	File   *os.File
+}
+
+type BinaryOption func(*binaryOptions)
+
+type binaryOptions struct {
+	trustedOwners []uint32
+}
+
+// 除 root 与 service 用户外，允许这些用户拥有核心文件及其上级目录
+func WithTrustedOwners(uids ...uint32) BinaryOption {
+	return func(options *binaryOptions) {
+		options.trustedOwners = append(options.trustedOwners, uids...)
+	}
+}
+
+func collectBinaryOptions(options []BinaryOption) binaryOptions {
+	var collected binaryOptions
+	for _, option := range options {
+		if option != nil {
+			option(&collected)
+		}
+	}
+	return collected
+}
+
+func newVerifiedBinary(path string, file *os.File) (*VerifiedBinary, error) {
+	digest, err := FileSHA256(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &VerifiedBinary{
+		Path:   path,
+		SHA256: digest,
+		File:   file,
+	}, nil
+}
+
+func FileSHA256(file *os.File) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, 1<<62)); err != nil {
+		return "", fmt.Errorf("计算核心文件哈希失败：%w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (b *VerifiedBinary) Close() error {
+	if b == nil || b.File == nil {
+		return nil
+	}
+	err := b.File.Close()
+	b.File = nil
+	return err
+}
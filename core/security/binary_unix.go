@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"syscall"
 )
 
 func SecureBinary(corePath string) error {
@@ -23,6 +25,130 @@ func SecureBinary(corePath string) error {
 	return nil
 }
 
+func OpenVerifiedBinary(corePath string, options ...BinaryOption) (*VerifiedBinary, error) {
+	binaryOptions := collectBinaryOptions(options)
+	resolved, err := filepath.EvalSymlinks(corePath)
+	if err != nil {
+		return nil, fmt.Errorf("解析核心文件路径失败：%w", err)
+	}
+	resolved, err = filepath.Abs(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("解析核心文件路径失败：%w", err)
+	}
+
+	verify := os.Getenv("SPARKLE_SKIP_CORE_ACL_HARDENING") != "1"
+	var expected os.FileInfo
+	if verify {
+		// 先确认整条路径的所有者可信再加固权限，避免以 root 身份修改其他用户控制的路径
+		if _, err := verifyPathChain(resolved, binaryOptions, false); err != nil {
+			return nil, err
+		}
+		if err := SecureBinary(resolved); err != nil {
+			return nil, err
+		}
+		if expected, err = verifyPathChain(resolved, binaryOptions, true); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.OpenFile(resolved, os.O_RDONLY|syscall.O_NOFOLLOW|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("打开核心文件失败：%w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("读取核心文件信息失败：%w", err)
+	}
+	if !info.Mode().IsRegular() {
+		file.Close()
+		return nil, fmt.Errorf("核心文件 %s 不是普通文件", resolved)
+	}
+	if verify {
+		if !os.SameFile(expected, info) {
+			file.Close()
+			return nil, fmt.Errorf("核心文件 %s 在校验后被替换", resolved)
+		}
+		if err := checkTrustedEntry(resolved, info, binaryOptions); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	return newVerifiedBinary(resolved, file)
+}
+
+// 以非阻塞方式打开并确认是普通文件，避免 FIFO 或设备文件阻塞读取
+func OpenRegularFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		file.Close()
+		return nil, fmt.Errorf("%s 不是普通文件", path)
+	}
+	return file, nil
+}
+
+func verifyPathChain(path string, options binaryOptions, checkWritable bool) (os.FileInfo, error) {
+	var target os.FileInfo
+	current := path
+	for {
+		info, err := os.Lstat(current)
+		if err != nil {
+			return nil, fmt.Errorf("读取路径 %s 信息失败：%w", current, err)
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return nil, fmt.Errorf("路径 %s 在校验期间变为符号链接", current)
+		}
+		if target == nil {
+			target = info
+		}
+
+		if err := checkTrustedOwner(current, info, options); err != nil {
+			return nil, err
+		}
+		// 带粘滞位的公共目录（如 /tmp）只允许所有者重命名其中的条目，子路径已确认可信
+		if checkWritable && info.Mode().Perm()&0o022 != 0 && !(info.IsDir() && info.Mode()&os.ModeSticky != 0) {
+			return nil, fmt.Errorf("路径 %s 可被其他用户写入 (mode=%04o)，无法保证核心文件不被替换", current, info.Mode().Perm())
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return target, nil
+		}
+		current = parent
+	}
+}
+
+func checkTrustedEntry(path string, info os.FileInfo, options binaryOptions) error {
+	if err := checkTrustedOwner(path, info, options); err != nil {
+		return err
+	}
+	if info.Mode().Perm()&0o022 != 0 {
+		return fmt.Errorf("路径 %s 可被其他用户写入 (mode=%04o)，无法保证核心文件不被替换", path, info.Mode().Perm())
+	}
+	return nil
+}
+
+func checkTrustedOwner(path string, info os.FileInfo, options binaryOptions) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("读取路径 %s 所有者失败", path)
+	}
+	if stat.Uid == 0 || int(stat.Uid) == os.Geteuid() || slices.Contains(options.trustedOwners, stat.Uid) {
+		return nil
+	}
+	return fmt.Errorf("路径 %s 的所有者 (uid=%d) 不是 root 或 service 用户，如需使用该用户安装的核心，请在核心启动策略的 core_owner_uids 中加入 %d", path, stat.Uid, stat.Uid)
+}
+
 func removeGroupAndOtherWrite(path string) error {
 	info, err := os.Stat(path)
 	if err != nil {
@@ -36,3 +162,17 @@ func removeGroupAndOtherWrite(path string) error {
 
 	return os.Chmod(path, mode&^0o022)
 }
+
+func CheckAdminOwned(path string, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("读取路径 %s 所有者失败", path)
+	}
+	if stat.Uid != 0 {
+		return fmt.Errorf("路径 %s 的所有者 (uid=%d) 不是 root", path, stat.Uid)
+	}
+	if info.Mode().Perm()&0o022 != 0 {
+		return fmt.Errorf("路径 %s 可被其他用户写入 (mode=%04o)", path, info.Mode().Perm())
+	}
+	return nil
+}
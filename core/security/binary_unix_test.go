@@ -0,0 +1,114 @@
+//go:build !windows
+
+package security
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestOpenVerifiedBinaryOwners(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing file owners requires root")
+	}
+	const otherUID = 65534
+
+	tests := []struct {
+		name      string
+		owner     int
+		options   []BinaryOption
+		wantErr   string
+		wantPerm  os.FileMode
+		parentDir os.FileMode
+	}{
+		{name: "root owned file is hardened", owner: 0, wantPerm: 0o755, parentDir: 0o755},
+		{name: "root owned writable dir is hardened", owner: 0, wantPerm: 0o755, parentDir: 0o777},
+		{name: "untrusted owner is not touched", owner: otherUID, wantErr: "core_owner_uids", wantPerm: 0o777, parentDir: 0o777},
+		{name: "owner allowed by policy", owner: otherUID, options: []BinaryOption{WithTrustedOwners(otherUID)}, wantPerm: 0o755, parentDir: 0o777},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := t.TempDir()
+			if err := os.Chmod(base, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			dir := filepath.Join(base, "core")
+			if err := os.Mkdir(dir, tt.parentDir); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, "mihomo")
+			if err := os.WriteFile(path, []byte("core"), 0o777); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(dir, tt.parentDir); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(path, 0o777); err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range []string{dir, path} {
+				if err := os.Chown(p, tt.owner, tt.owner); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			binary, err := OpenVerifiedBinary(path, tt.options...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				binary.Close()
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != tt.wantPerm {
+				t.Fatalf("mode = %04o, want %04o", info.Mode().Perm(), tt.wantPerm)
+			}
+		})
+	}
+}
+
+func TestOpenRegularFile(t *testing.T) {
+	dir := t.TempDir()
+	regular := filepath.Join(dir, "regular")
+	if err := os.WriteFile(regular, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fifo := filepath.Join(dir, "fifo")
+	if err := syscall.Mkfifo(fifo, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "regular", path: regular},
+		{name: "fifo", path: fifo, wantErr: true},
+		{name: "directory", path: dir, wantErr: true},
+		{name: "device", path: "/dev/zero", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := OpenRegularFile(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if file != nil {
+				file.Close()
+			}
+		})
+	}
+}
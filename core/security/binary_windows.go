@@ -25,6 +25,39 @@ func SecureBinary(corePath string) error {
 	return nil
 }
 
+func OpenVerifiedBinary(corePath string, _ ...BinaryOption) (*VerifiedBinary, error) {
+	resolved, err := filepath.Abs(corePath)
+	if err != nil {
+		return nil, fmt.Errorf("解析核心文件路径失败：%w", err)
+	}
+	if err := SecureBinary(resolved); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("打开核心文件失败：%w", err)
+	}
+	return newVerifiedBinary(resolved, file)
+}
+
+func OpenRegularFile(path string) (*os.File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		file.Close()
+		return nil, fmt.Errorf("%s 不是普通文件", path)
+	}
+	return file, nil
+}
+
 func RestrictPath(path string, inheritance uint32) error {
 	currentSID, err := CurrentProcessSID()
 	if err != nil {
@@ -81,3 +114,18 @@ func CurrentProcessSID() (*windows.SID, error) {
 
 	return user.User.Sid, nil
 }
+
+func CheckAdminOwned(path string, _ os.FileInfo) error {
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION)
+	if err != nil {
+		return fmt.Errorf("读取路径 %s 所有者失败：%w", path, err)
+	}
+	owner, _, err := sd.Owner()
+	if err != nil {
+		return fmt.Errorf("读取路径 %s 所有者失败：%w", path, err)
+	}
+	if !owner.IsWellKnown(windows.WinLocalSystemSid) && !owner.IsWellKnown(windows.WinBuiltinAdministratorsSid) {
+		return fmt.Errorf("路径 %s 的所有者 (%s) 不是 SYSTEM 或 Administrators", path, owner.String())
+	}
+	return nil
+}
@@ -35,7 +35,29 @@ func createNativeStartupHook(token string) (*coreStartupHook, error) {
 		return nil, err
 	}
 
+	unregister := registerActiveSandboxRoot(socketDir)
 	return newCoreStartupHook(listener, token, socketPath, postUpCommand, noopShellCommand(), func() {
 		_ = os.RemoveAll(socketDir)
+		unregister()
+	}), nil
+}
+
+func plannedStartupHookPath() string {
+	return filepath.Join(os.TempDir(), "sparkle-core-ready-*", "<token>.sock")
+}
+
+func reopenCoreStartupHook(socketPath string, token string) (*coreStartupHook, error) {
+	socketDir := filepath.Dir(socketPath)
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("清理核心启动通知 UDS 失败：%w", err)
+	}
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("创建核心启动通知 UDS 失败：%w", err)
+	}
+	_ = os.Chmod(socketPath, 0o600)
+
+	return newReadyCoreStartupHook(listener, token, socketPath, func() {
+		_ = os.RemoveAll(socketDir)
 	}), nil
 }
@@ -19,6 +19,14 @@ func createNativeStartupHook(token string) (*coreStartupHook, error) {
 	return newCoreStartupHook(listener, token, pipePath, "echo "+token+" > "+pipePath, noopShellCommand(), nil), nil
 }
 
+func plannedStartupHookPath() string {
+	return `\\.\pipe\sparkle\core-ready-<token>`
+}
+
+func reopenCoreStartupHook(_ string, _ string) (*coreStartupHook, error) {
+	return nil, fmt.Errorf("当前平台不支持恢复核心启动通知")
+}
+
 func currentProcessPipeSDDL() string {
 	sid, err := security.CurrentProcessSID()
 	if err != nil {
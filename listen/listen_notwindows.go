@@ -11,3 +11,8 @@ func ListenNamedPipe(path string, sddl string) (net.Listener, error) {
 	_, _ = path, sddl
 	return nil, os.ErrInvalid
 }
+
+func SetNamedPipeSDDL(l net.Listener, sddl string) error {
+	_, _ = l, sddl
+	return os.ErrInvalid
+}
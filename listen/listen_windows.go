@@ -15,14 +15,18 @@ import (
 // It provides read/write access to all users and the local system.
 const DefaultNamedPipeSDDL = "D:PAI(A;OICI;GWGR;;;BU)(A;OICI;GWGR;;;SY)"
 
-func ListenNamedPipe(path string, sddl string) (net.Listener, error) {
+func namedPipeSecurityDescriptor(sddl string) (*windows.SECURITY_DESCRIPTOR, error) {
 	if override := os.Getenv("LISTEN_NAMEDPIPE_SDDL"); override != "" {
 		sddl = override
 	}
 	if sddl == "" {
 		sddl = DefaultNamedPipeSDDL
 	}
-	securityDescriptor, err := windows.SecurityDescriptorFromString(sddl)
+	return windows.SecurityDescriptorFromString(sddl)
+}
+
+func ListenNamedPipe(path string, sddl string) (net.Listener, error) {
+	securityDescriptor, err := namedPipeSecurityDescriptor(sddl)
 	if err != nil {
 		return nil, err
 	}
@@ -33,3 +37,19 @@ func ListenNamedPipe(path string, sddl string) (net.Listener, error) {
 	}
 	return namedpipeLC.Listen(path)
 }
+
+// SetNamedPipeSDDL replaces the access control list of a listener returned by
+// ListenNamedPipe without recreating the pipe.
+func SetNamedPipeSDDL(l net.Listener, sddl string) error {
+	pipe, ok := l.(interface {
+		SetSecurityDescriptor(*windows.SECURITY_DESCRIPTOR) error
+	})
+	if !ok {
+		return os.ErrInvalid
+	}
+	securityDescriptor, err := namedPipeSecurityDescriptor(sddl)
+	if err != nil {
+		return err
+	}
+	return pipe.SetSecurityDescriptor(securityDescriptor)
+}
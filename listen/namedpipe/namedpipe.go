@@ -14,6 +14,7 @@ import (
 	"net"
 	"os"
 	"runtime"
+	"sync"
 	"sync/atomic"
 	"time"
 	"unsafe"
@@ -227,6 +228,7 @@ type acceptResponse struct {
 }
 
 type pipeListener struct {
+	handleMu    sync.Mutex
 	firstHandle windows.Handle
 	path        string
 	config      ListenConfig
@@ -288,7 +290,8 @@ func makeServerPipeHandle(path string, sd *windows.SECURITY_DESCRIPTOR, c *Liste
 		// By not asking for read or write access, the named pipe file system
 		// will put this pipe into an initially disconnected state, blocking
 		// client connections until the next call with isFirstPipe == false.
-		access = windows.SYNCHRONIZE
+		// WRITE_DAC allows SetSecurityDescriptor to replace the DACL later.
+		access = windows.SYNCHRONIZE | windows.WRITE_DAC
 	}
 
 	timeout := int64(-50 * 10000) // 50ms
@@ -375,8 +378,10 @@ func (l *pipeListener) listenerRoutine() {
 			closed = err == net.ErrClosed
 		}
 	}
+	l.handleMu.Lock()
 	windows.Close(l.firstHandle)
 	l.firstHandle = 0
+	l.handleMu.Unlock()
 	// Notify Close and Accept callers that the handle has been closed.
 	close(l.doneCh)
 }
@@ -481,6 +486,26 @@ func (l *pipeListener) Close() error {
 	return nil
 }
 
+// SetSecurityDescriptor replaces the DACL of the listening pipe. The new DACL
+// applies to subsequent client connections; connected clients are unaffected.
+func (l *pipeListener) SetSecurityDescriptor(sd *windows.SECURITY_DESCRIPTOR) error {
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return err
+	}
+	info := windows.SECURITY_INFORMATION(windows.DACL_SECURITY_INFORMATION)
+	if control, _, err := sd.Control(); err == nil && control&windows.SE_DACL_PROTECTED != 0 {
+		info |= windows.PROTECTED_DACL_SECURITY_INFORMATION
+	}
+
+	l.handleMu.Lock()
+	defer l.handleMu.Unlock()
+	if l.firstHandle == 0 {
+		return net.ErrClosed
+	}
+	return windows.SetSecurityInfo(l.firstHandle, windows.SE_KERNEL_OBJECT, info, nil, nil, dacl, nil)
+}
+
 func (l *pipeListener) Addr() net.Addr {
 	return pipeAddress(l.path)
 }
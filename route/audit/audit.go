@@ -0,0 +1,298 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/UruhaLushia/sparkle-service/log"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+
+	// 启动时发现日志末尾存在无法解析的记录，新记录从最后一条有效记录之后接续
+	EventChainBreak = "chain_break"
+
+	// 单次查询最多返回的条目数，更多条目可按 seq 继续查询
+	MaxQueryEntries = 1000
+)
+
+// 首条记录的 prev_hash
+var genesisHash = strings.Repeat("0", sha256.Size*2)
+
+type Peer struct {
+	Type  string `json:"type,omitempty"`
+	Value string `json:"value,omitempty"`
+	PID   int    `json:"pid,omitempty"`
+	Exe   string `json:"exe,omitempty"`
+	// 可执行文件的 SHA-256，与 exe 一同经 pidfd 确认
+	ExeSHA256 string `json:"exe_sha256,omitempty"`
+}
+
+type Entry struct {
+	Seq           int64     `json:"seq"`
+	Time          time.Time `json:"time"`
+	Event         string    `json:"event,omitempty"`
+	Method        string    `json:"method"`
+	Route         string    `json:"route,omitempty"`
+	Path          string    `json:"path"`
+	Peer          Peer      `json:"peer"`
+	KeyID         string    `json:"key_id,omitempty"`
+	SessionID     string    `json:"session_id,omitempty"`
+	RequestSHA256 string    `json:"request_sha256"`
+	Status        int       `json:"status"`
+	Outcome       string    `json:"outcome"`
+	Error         string    `json:"error,omitempty"`
+	PrevHash      string    `json:"prev_hash"`
+	// 只在查询结果中填充，文件中保存在记录外层
+	Hash string `json:"hash,omitempty"`
+}
+
+// 日志文件每行一条记录，hash 为 entry 原始字节的 SHA-256
+type record struct {
+	Entry json.RawMessage `json:"entry"`
+	Hash  string          `json:"hash"`
+}
+
+type VerifyResult struct {
+	Path     string `json:"path"`
+	Entries  int64  `json:"entries"`
+	Valid    bool   `json:"valid"`
+	LastHash string `json:"last_hash,omitempty"`
+	// 第一处校验失败的行号（从 1 开始）
+	BrokenLine int    `json:"broken_line,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+type logger struct {
+	mu       sync.Mutex
+	path     string
+	seq      int64
+	lastHash string
+	// 文件末尾存在未写完的记录时，下次追加前先补换行
+	partial bool
+}
+
+var auditLogger = &logger{}
+
+func Path(configDir string) string {
+	return filepath.Join(configDir, "sparkle", "audit", "audit.log")
+}
+
+// 读取已有日志末尾的序号与摘要，新记录接在其后
+func Init(configDir string) error {
+	path := Path(configDir)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("创建审计日志目录失败： %w", err)
+	}
+
+	l := auditLogger
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.path = path
+	l.seq = 0
+	l.lastHash = genesisHash
+	l.partial = false
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("读取审计日志失败： %w", err)
+	}
+	l.partial = len(data) > 0 && data[len(data)-1] != '\n'
+
+	data = bytes.TrimRight(data, "\n")
+	if len(data) == 0 {
+		return nil
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	broken := 0
+	for i := len(lines) - 1; i >= 0; i-- {
+		entry, hash, err := parseRecord(lines[i])
+		if err != nil {
+			broken++
+			continue
+		}
+		l.seq = entry.Seq
+		l.lastHash = hash
+		break
+	}
+	if broken == 0 {
+		return nil
+	}
+
+	// 不静默接续，先写入一条断链记录，说明被跳过的行数与接续的位置
+	log.Printf("警告：审计日志末尾有 %d 行记录无法解析，将从第 %d 条记录之后接续", broken, l.seq)
+	return l.appendLocked(Entry{
+		Event:   EventChainBreak,
+		Outcome: OutcomeFailure,
+		Error:   fmt.Sprintf("日志末尾有 %d 行记录无法解析，新记录接在第 %d 条记录之后", broken, l.seq),
+	})
+}
+
+func parseRecord(line []byte) (Entry, string, error) {
+	var rec record
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return Entry{}, "", fmt.Errorf("解析记录失败： %w", err)
+	}
+	var entry Entry
+	if err := json.Unmarshal(rec.Entry, &entry); err != nil {
+		return Entry{}, "", fmt.Errorf("解析记录内容失败： %w", err)
+	}
+	sum := sha256.Sum256(rec.Entry)
+	if hex.EncodeToString(sum[:]) != rec.Hash {
+		return Entry{}, "", fmt.Errorf("记录摘要不匹配")
+	}
+	entry.Hash = rec.Hash
+	return entry, rec.Hash, nil
+}
+
+func Append(entry Entry) error {
+	l := auditLogger
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.path == "" {
+		return errors.New("审计日志未初始化")
+	}
+	return l.appendLocked(entry)
+}
+
+func (l *logger) appendLocked(entry Entry) error {
+	entry.Seq = l.seq + 1
+	entry.PrevHash = l.lastHash
+	entry.Hash = ""
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("序列化审计记录失败： %w", err)
+	}
+	sum := sha256.Sum256(raw)
+	hash := hex.EncodeToString(sum[:])
+	line, err := json.Marshal(record{Entry: raw, Hash: hash})
+	if err != nil {
+		return fmt.Errorf("序列化审计记录失败： %w", err)
+	}
+	if l.partial {
+		line = append([]byte("\n"), line...)
+	}
+	line = append(line, '\n')
+
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("打开审计日志失败： %w", err)
+	}
+	defer file.Close()
+	// 一次写入整行，避免并发追加时记录交错
+	if _, err := file.Write(line); err != nil {
+		return fmt.Errorf("写入审计日志失败： %w", err)
+	}
+
+	l.seq = entry.Seq
+	l.lastHash = hash
+	l.partial = false
+	return nil
+}
+
+// 返回 seq 大于 afterSeq 且时间不早于 since 的记录，最多 MaxQueryEntries 条
+func Entries(afterSeq int64, since time.Time) ([]Entry, error) {
+	l := auditLogger
+	l.mu.Lock()
+	path := l.path
+	l.mu.Unlock()
+	if path == "" {
+		return nil, errors.New("审计日志未初始化")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Entry{}, nil
+		}
+		return nil, fmt.Errorf("读取审计日志失败： %w", err)
+	}
+	defer file.Close()
+
+	entries := make([]Entry, 0)
+	scanner := newLineScanner(file)
+	for scanner.Scan() && len(entries) < MaxQueryEntries {
+		entry, _, err := parseRecord(scanner.Bytes())
+		if err != nil {
+			continue
+		}
+		if entry.Seq <= afterSeq || entry.Time.Before(since) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取审计日志失败： %w", err)
+	}
+	return entries, nil
+}
+
+// 逐条校验记录摘要、序号与 prev_hash 链，返回第一处被修改、删除或插入的位置
+func Verify(configDir string) (VerifyResult, error) {
+	result := VerifyResult{Path: Path(configDir), Valid: true}
+
+	file, err := os.Open(result.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return result, fmt.Errorf("读取审计日志失败： %w", err)
+	}
+	defer file.Close()
+
+	prevHash := genesisHash
+	var seq int64
+	line := 0
+	scanner := newLineScanner(file)
+	for scanner.Scan() {
+		line++
+		entry, hash, err := parseRecord(scanner.Bytes())
+		switch {
+		case err != nil:
+		case entry.Seq != seq+1:
+			err = fmt.Errorf("序号不连续：期望 %d，实际 %d", seq+1, entry.Seq)
+		case entry.PrevHash != prevHash:
+			err = fmt.Errorf("prev_hash 与上一条记录不匹配")
+		}
+		if err != nil {
+			result.Valid = false
+			result.BrokenLine = line
+			result.Error = err.Error()
+			break
+		}
+		seq = entry.Seq
+		prevHash = hash
+		result.Entries++
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("读取审计日志失败： %w", err)
+	}
+	if result.Valid && seq > 0 {
+		result.LastHash = prevHash
+	}
+	return result, nil
+}
+
+func newLineScanner(file *os.File) *bufio.Scanner {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return scanner
+}
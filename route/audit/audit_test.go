@@ -0,0 +1,181 @@
+package audit
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func initTestLog(t *testing.T, entries int) string {
+	t.Helper()
+	configDir := t.TempDir()
+	if err := Init(configDir); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < entries; i++ {
+		if err := Append(Entry{Method: http.MethodPost, Path: "/core/start", Status: http.StatusOK, Outcome: OutcomeSuccess}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return configDir
+}
+
+func readLogLines(t *testing.T, configDir string) [][]byte {
+	t.Helper()
+	data, err := os.ReadFile(Path(configDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n"))
+}
+
+func writeLogLines(t *testing.T, configDir string, lines [][]byte) {
+	t.Helper()
+	data := append(bytes.Join(lines, []byte("\n")), '\n')
+	if err := os.WriteFile(Path(configDir), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name       string
+		tamper     func([][]byte) [][]byte
+		wantValid  bool
+		wantBroken int
+		wantError  string
+	}{
+		{name: "intact", tamper: func(lines [][]byte) [][]byte { return lines }, wantValid: true},
+		{name: "modified entry", tamper: func(lines [][]byte) [][]byte {
+			lines[1] = bytes.Replace(lines[1], []byte("/core/start"), []byte("/core/stop!"), 1)
+			return lines
+		}, wantBroken: 2, wantError: "摘要不匹配"},
+		{name: "deleted entry", tamper: func(lines [][]byte) [][]byte {
+			return append(lines[:1:1], lines[2:]...)
+		}, wantBroken: 2, wantError: "序号不连续"},
+		{name: "reordered entries", tamper: func(lines [][]byte) [][]byte {
+			lines[1], lines[2] = lines[2], lines[1]
+			return lines
+		}, wantBroken: 2, wantError: "序号不连续"},
+		{name: "garbage line", tamper: func(lines [][]byte) [][]byte {
+			return append(lines[:2:2], append([][]byte{[]byte("{")}, lines[2:]...)...)
+		}, wantBroken: 3, wantError: "解析记录失败"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configDir := initTestLog(t, 3)
+			writeLogLines(t, configDir, tt.tamper(readLogLines(t, configDir)))
+
+			result, err := Verify(configDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Valid != tt.wantValid || result.BrokenLine != tt.wantBroken {
+				t.Fatalf("Verify() = valid %v, broken line %d; want %v, %d", result.Valid, result.BrokenLine, tt.wantValid, tt.wantBroken)
+			}
+			if !strings.Contains(result.Error, tt.wantError) {
+				t.Fatalf("Verify() error = %q, want %q", result.Error, tt.wantError)
+			}
+			if tt.wantValid && (result.Entries != 3 || result.LastHash == "") {
+				t.Fatalf("Verify() = %d entries, last hash %q", result.Entries, result.LastHash)
+			}
+		})
+	}
+}
+
+func TestInitCorruptTail(t *testing.T) {
+	tests := []struct {
+		name      string
+		tail      string
+		wantBreak bool
+	}{
+		{name: "clean"},
+		{name: "partial record", tail: `{"entry":{"seq":4`, wantBreak: true},
+		{name: "garbage lines", tail: "garbage\n{}\n", wantBreak: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configDir := initTestLog(t, 3)
+			file, err := os.OpenFile(Path(configDir), os.O_WRONLY|os.O_APPEND, 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = file.WriteString(tt.tail)
+			file.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := Init(configDir); err != nil {
+				t.Fatal(err)
+			}
+			if err := Append(Entry{Method: http.MethodPost, Path: "/core/stop", Status: http.StatusOK, Outcome: OutcomeSuccess}); err != nil {
+				t.Fatal(err)
+			}
+
+			entries, err := Entries(3, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"/core/stop"}
+			if tt.wantBreak {
+				want = []string{"", "/core/stop"}
+			}
+			if len(entries) != len(want) {
+				t.Fatalf("got %d new entries, want %d", len(entries), len(want))
+			}
+			for i, entry := range entries {
+				if entry.Path != want[i] || entry.Seq != int64(4+i) {
+					t.Fatalf("entry %d = seq %d path %q", i, entry.Seq, entry.Path)
+				}
+			}
+			if tt.wantBreak && (entries[0].Event != EventChainBreak || entries[0].Outcome != OutcomeFailure) {
+				t.Fatalf("first entry after corrupt tail = %+v, want chain break", entries[0])
+			}
+		})
+	}
+}
+
+func TestMiddlewareRecordsAuthFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		status    int
+		wantEntry bool
+	}{
+		{name: "rejected read", method: http.MethodGet, status: http.StatusUnauthorized, wantEntry: true},
+		{name: "rejected write", method: http.MethodPost, status: http.StatusForbidden, wantEntry: true},
+		{name: "unauthenticated success", method: http.MethodGet, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			initTestLog(t, 0)
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(`{"status":"error","message":"缺少 V2 认证信息"}`))
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, "/core/profile", strings.NewReader("{}")))
+
+			entries, err := Entries(0, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (len(entries) == 1) != tt.wantEntry {
+				t.Fatalf("got %d entries, want entry %v", len(entries), tt.wantEntry)
+			}
+			if tt.wantEntry {
+				entry := entries[0]
+				if entry.Status != tt.status || entry.Outcome != OutcomeFailure || entry.KeyID != "" || entry.Error != "缺少 V2 认证信息" {
+					t.Fatalf("entry = %+v", entry)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/UruhaLushia/sparkle-service/log"
+	"github.com/UruhaLushia/sparkle-service/route/auth"
+	"github.com/UruhaLushia/sparkle-service/route/httphelper"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// 错误响应只保留开头部分用于提取错误信息
+const maxCapturedErrorBody = 4 << 10
+
+// 记录所有修改类请求与未通过认证的请求，需要放在认证中间件之外，
+// 通过 auth.WithIdentitySlot 在请求结束后取得公钥与会话信息
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startedAt := time.Now().UTC()
+		mutating := true
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			mutating = false
+		}
+
+		// 可执行文件需在请求方进程仍存活时读取
+		var peer Peer
+		if mutating {
+			peer = requestPeer(r)
+		}
+		r, identity := auth.WithIdentitySlot(r)
+		if r.Body == nil {
+			r.Body = http.NoBody
+		}
+		body := &hashingBody{ReadCloser: r.Body, hash: sha256.New()}
+		r.Body = body
+		recorder := &responseRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(recorder, r)
+
+		authenticated := identity.KeyID != ""
+		if !mutating && (authenticated || recorder.statusCode() < http.StatusBadRequest) {
+			return
+		}
+		if !mutating {
+			peer = requestPeer(r)
+		}
+
+		// 处理器未读完的请求体也计入摘要；未通过认证的请求体不再继续读取
+		var drainErr error
+		if authenticated {
+			_, drainErr = io.Copy(io.Discard, body)
+		}
+
+		entry := Entry{
+			Time:          startedAt,
+			Method:        r.Method,
+			Path:          r.URL.Path,
+			Peer:          peer,
+			KeyID:         identity.KeyID,
+			SessionID:     identity.SessionID,
+			RequestSHA256: hex.EncodeToString(body.hash.Sum(nil)),
+			Status:        recorder.statusCode(),
+			Outcome:       OutcomeSuccess,
+		}
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			entry.Route = rctx.RoutePattern()
+		}
+		if entry.Status >= http.StatusBadRequest {
+			entry.Outcome = OutcomeFailure
+			entry.Error = recorder.errorMessage()
+		}
+		if drainErr != nil && entry.Error == "" {
+			entry.Error = drainErr.Error()
+		}
+		if err := Append(entry); err != nil {
+			log.Printf("写入审计日志失败：%v", err)
+		}
+	})
+}
+
+// 可执行文件只取经 pidfd 确认的结果，无法确认时留空
+func requestPeer(r *http.Request) Peer {
+	requestPeer := auth.RequestPeerFrom(r)
+	peer := Peer{Type: requestPeer.Type, Value: requestPeer.Value, PID: requestPeer.PID}
+	if executable, err := auth.RequestExecutableFrom(r); err == nil && executable.PID == peer.PID {
+		peer.Exe = executable.Path
+		peer.ExeSHA256 = executable.SHA256
+	}
+	return peer
+}
+
+type hashingBody struct {
+	io.ReadCloser
+	hash hash.Hash
+}
+
+func (b *hashingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.hash.Write(p[:n])
+	return n, err
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status   int
+	hijacked bool
+	body     bytes.Buffer
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if w.status == 0 && status >= http.StatusOK {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.status >= http.StatusBadRequest && w.body.Len() < maxCapturedErrorBody {
+		w.body.Write(p[:min(len(p), maxCapturedErrorBody-w.body.Len())])
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *responseRecorder) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *responseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseRecorder) statusCode() int {
+	switch {
+	case w.hijacked:
+		return http.StatusSwitchingProtocols
+	case w.status == 0:
+		return http.StatusOK
+	default:
+		return w.status
+	}
+}
+
+func (w *responseRecorder) errorMessage() string {
+	var resp httphelper.Response
+	if err := json.Unmarshal(w.body.Bytes(), &resp); err == nil && resp.Message != "" {
+		return resp.Message
+	}
+	return http.StatusText(w.statusCode())
+}
@@ -0,0 +1,9 @@
+package route
+
+import "github.com/UruhaLushia/sparkle-service/route/audit"
+
+type AuditVerifyResult = audit.VerifyResult
+
+func VerifyAuditLog() (AuditVerifyResult, error) {
+	return audit.Verify(GetConfigDir())
+}
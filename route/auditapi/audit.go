@@ -0,0 +1,46 @@
+package auditapi
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/UruhaLushia/sparkle-service/route/audit"
+	"github.com/UruhaLushia/sparkle-service/route/auth"
+	"github.com/UruhaLushia/sparkle-service/route/httphelper"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func Router() http.Handler {
+	r := chi.NewRouter()
+	r.Use(auth.RequireScope(auth.ScopeAuditRead))
+	r.Get("/", auditEntries)
+	return r
+}
+
+// since 可以是序号（返回之后的记录）或 RFC 3339 时间
+func auditEntries(w http.ResponseWriter, r *http.Request) {
+	var (
+		afterSeq int64
+		since    time.Time
+	)
+	if value := r.URL.Query().Get("since"); value != "" {
+		if seq, err := strconv.ParseInt(value, 10, 64); err == nil {
+			afterSeq = seq
+		} else if t, err := time.Parse(time.RFC3339, value); err == nil {
+			since = t
+		} else {
+			httphelper.SendError(w, httphelper.BadRequest("since 必须是序号或 RFC 3339 时间"))
+			return
+		}
+	}
+
+	entries, err := audit.Entries(afterSeq, since)
+	if err != nil {
+		httphelper.SendError(w, err)
+		return
+	}
+	render.JSON(w, r, entries)
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInitKeyManagerRefusesOverwrite(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encodedKey := base64.StdEncoding.EncodeToString(der)
+
+	tests := []struct {
+		name           string
+		principals     string
+		keys           string
+		wantPrincipals bool
+		wantKeys       bool
+	}{
+		{name: "fresh install", wantPrincipals: true, wantKeys: true},
+		{name: "corrupt principals", principals: "{", wantKeys: true},
+		{name: "corrupt keys", keys: `{"keys":[{"public_key":"!"}]}`, wantPrincipals: true},
+		{name: "empty principal list", principals: `{"principals":[]}`, wantPrincipals: true, wantKeys: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := map[string]string{"authorized_principals.json": tt.principals, "public_keys.json": tt.keys}
+			for name, content := range files {
+				if content == "" {
+					continue
+				}
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_ = InitKeyManager(dir)
+			km := GetKeyManager()
+			if got := km.LoadError() == nil; got != (tt.wantPrincipals && tt.wantKeys) {
+				t.Fatalf("LoadError() = %v", km.LoadError())
+			}
+
+			_, err := km.AddAuthorizedPrincipal(AuthorizedPrincipal{Type: PrincipalTypeSID, Value: "S-1-5-21-1-2-3-1001", Role: RoleAdmin})
+			if (err == nil) != tt.wantPrincipals {
+				t.Fatalf("AddAuthorizedPrincipal() error = %v, want success %v", err, tt.wantPrincipals)
+			}
+			_, err = km.SetPublicKey(encodedKey)
+			if (err == nil) != tt.wantKeys {
+				t.Fatalf("SetPublicKey() error = %v, want success %v", err, tt.wantKeys)
+			}
+
+			for name, content := range files {
+				if content == "" || name == "authorized_principals.json" && tt.wantPrincipals || name == "public_keys.json" && tt.wantKeys {
+					continue
+				}
+				data, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil || string(data) != content {
+					t.Fatalf("%s was overwritten: %q", name, data)
+				}
+			}
+		})
+	}
+}
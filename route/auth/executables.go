@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 允许访问服务的客户端可执行文件，path 与 sha256 同时设置时须同时匹配
+type AllowedExecutable struct {
+	Path   string `json:"path,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+type allowedExecutablesFile struct {
+	Executables []AllowedExecutable `json:"executables"`
+}
+
+type PeerExecutable struct {
+	PID    int    `json:"pid"`
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+func normalizeAllowedExecutable(executable AllowedExecutable) (AllowedExecutable, error) {
+	executable.Path = strings.TrimSpace(executable.Path)
+	executable.SHA256 = strings.ToLower(strings.TrimSpace(executable.SHA256))
+	if executable.Path == "" && executable.SHA256 == "" {
+		return executable, fmt.Errorf("可执行文件条目必须设置 path 或 sha256")
+	}
+	if executable.Path != "" {
+		if !filepath.IsAbs(executable.Path) {
+			return executable, fmt.Errorf("可执行文件路径必须是绝对路径: %s", executable.Path)
+		}
+		executable.Path = filepath.Clean(executable.Path)
+	}
+	if executable.SHA256 != "" {
+		if digest, err := hex.DecodeString(executable.SHA256); err != nil || len(digest) != 32 {
+			return executable, fmt.Errorf("无效的 SHA-256: %s", executable.SHA256)
+		}
+	}
+	return executable, nil
+}
+
+func (executable AllowedExecutable) matches(peer PeerExecutable) bool {
+	if executable.Path != "" && executable.Path != peer.Path {
+		return false
+	}
+	if executable.SHA256 != "" && executable.SHA256 != peer.SHA256 {
+		return false
+	}
+	return true
+}
+
+// 按修改时间缓存允许列表，文件修改后无需重启服务；文件不存在或列表为空时不限制
+func (km *KeyManager) allowedExecutables() ([]AllowedExecutable, error) {
+	km.executablesMu.Lock()
+	defer km.executablesMu.Unlock()
+
+	info, err := os.Stat(km.executablesPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			km.executables = nil
+			km.executablesModTime = time.Time{}
+			km.executablesSize = 0
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取可执行文件允许列表失败： %w", err)
+	}
+	if info.ModTime().Equal(km.executablesModTime) && info.Size() == km.executablesSize {
+		return km.executables, nil
+	}
+
+	data, err := os.ReadFile(km.executablesPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取可执行文件允许列表失败： %w", err)
+	}
+	var file allowedExecutablesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析可执行文件允许列表失败： %w", err)
+	}
+	executables := make([]AllowedExecutable, 0, len(file.Executables))
+	for _, executable := range file.Executables {
+		normalized, err := normalizeAllowedExecutable(executable)
+		if err != nil {
+			return nil, fmt.Errorf("可执行文件允许列表无效： %w", err)
+		}
+		executables = append(executables, normalized)
+	}
+
+	km.executables = executables
+	km.executablesModTime = info.ModTime()
+	km.executablesSize = info.Size()
+	return executables, nil
+}
+
+// 通过 pidfd 确认过的请求方可执行文件，不支持的平台返回错误
+func RequestExecutableFrom(r *http.Request) (PeerExecutable, error) {
+	return getRequestExecutable(r)
+}
+
+// 配置了允许列表时校验请求方进程的可执行文件，拒绝时错误信息包含实际的路径与摘要
+func (km *KeyManager) verifyRequestExecutable(r *http.Request) error {
+	allowed, err := km.allowedExecutables()
+	if err != nil {
+		return err
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	peer, err := getRequestExecutable(r)
+	if err != nil {
+		return fmt.Errorf("无法校验请求方可执行文件： %w", err)
+	}
+	if !slices.ContainsFunc(allowed, func(executable AllowedExecutable) bool {
+		return executable.matches(peer)
+	}) {
+		return fmt.Errorf("请求方可执行文件不在允许列表中: %s (sha256 %s, pid %d)", peer.Path, peer.SHA256, peer.PID)
+	}
+	return nil
+}
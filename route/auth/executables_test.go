@@ -0,0 +1,127 @@
+//go:build !windows
+
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAllowedExecutables(t *testing.T) {
+	digest := strings.Repeat("ab", 32)
+
+	tests := []struct {
+		name    string
+		content string
+		want    []AllowedExecutable
+		wantErr string
+	}{
+		{name: "missing file"},
+		{name: "empty list", content: `{"executables":[]}`, want: []AllowedExecutable{}},
+		{name: "normalized", content: `{"executables":[{"path":" /opt/sparkle/../sparkle/sparkle "},{"sha256":"` + strings.ToUpper(digest) + `"}]}`, want: []AllowedExecutable{{Path: "/opt/sparkle/sparkle"}, {SHA256: digest}}},
+		{name: "relative path", content: `{"executables":[{"path":"sparkle"}]}`, wantErr: "绝对路径"},
+		{name: "invalid digest", content: `{"executables":[{"sha256":"abcd"}]}`, wantErr: "无效的 SHA-256"},
+		{name: "empty entry", content: `{"executables":[{}]}`, wantErr: "必须设置 path 或 sha256"},
+		{name: "invalid json", content: `{`, wantErr: "解析可执行文件允许列表失败"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			km := newTestKeyManager(t)
+			km.executablesPath = filepath.Join(t.TempDir(), "client_executables.json")
+			if tt.content != "" {
+				if err := os.WriteFile(km.executablesPath, []byte(tt.content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := km.allowedExecutables()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("allowedExecutables() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("allowedExecutables() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("allowedExecutables() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowedExecutablesReloadsOnChange(t *testing.T) {
+	km := newTestKeyManager(t)
+	km.executablesPath = filepath.Join(t.TempDir(), "client_executables.json")
+	write := func(path string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(km.executablesPath, []byte(`{"executables":[{"path":"`+path+`"}]}`), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(km.executablesPath, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	load := func() string {
+		t.Helper()
+		executables, err := km.allowedExecutables()
+		if err != nil || len(executables) != 1 {
+			t.Fatalf("allowedExecutables() = %v, %v", executables, err)
+		}
+		return executables[0].Path
+	}
+
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	write("/opt/a", modTime)
+	if got := load(); got != "/opt/a" {
+		t.Fatalf("path = %s, want /opt/a", got)
+	}
+	// 大小与修改时间均未变化时使用缓存
+	write("/opt/b", modTime)
+	if got := load(); got != "/opt/a" {
+		t.Fatalf("path = %s, want cached /opt/a", got)
+	}
+	write("/opt/b", modTime.Add(time.Second))
+	if got := load(); got != "/opt/b" {
+		t.Fatalf("path = %s, want reloaded /opt/b", got)
+	}
+
+	if err := os.Remove(km.executablesPath); err != nil {
+		t.Fatal(err)
+	}
+	if executables, err := km.allowedExecutables(); err != nil || executables != nil {
+		t.Fatalf("allowedExecutables() after removal = %v, %v", executables, err)
+	}
+}
+
+func TestAllowedExecutableMatches(t *testing.T) {
+	digest := strings.Repeat("ab", 32)
+	peer := PeerExecutable{PID: 1, Path: "/opt/sparkle/sparkle", SHA256: digest}
+
+	tests := []struct {
+		name       string
+		executable AllowedExecutable
+		want       bool
+	}{
+		{name: "path", executable: AllowedExecutable{Path: peer.Path}, want: true},
+		{name: "other path", executable: AllowedExecutable{Path: "/usr/bin/curl"}},
+		{name: "digest", executable: AllowedExecutable{SHA256: digest}, want: true},
+		{name: "other digest", executable: AllowedExecutable{SHA256: strings.Repeat("cd", 32)}},
+		{name: "path and digest", executable: AllowedExecutable{Path: peer.Path, SHA256: digest}, want: true},
+		{name: "path with other digest", executable: AllowedExecutable{Path: peer.Path, SHA256: strings.Repeat("cd", 32)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.executable.matches(peer); got != tt.want {
+				t.Fatalf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+)
+
+type RequestIdentity struct {
+	Principal string   `json:"principal,omitempty"`
+	KeyID     string   `json:"key_id,omitempty"`
+	SessionID string   `json:"session_id,omitempty"`
+	Role      string   `json:"role,omitempty"`
+	Scopes    []string `json:"scopes,omitempty"`
+}
+
+type requestIdentityContextKey struct{}
+
+type identitySlotContextKey struct{}
+
+func RequestIdentityFrom(r *http.Request) RequestIdentity {
+	identity, _ := r.Context().Value(requestIdentityContextKey{}).(RequestIdentity)
+	return identity
+}
+
+func withRequestIdentity(r *http.Request, keyID string, sessionID string, role string, scopes []string) *http.Request {
+	identity := RequestIdentity{KeyID: keyID, SessionID: sessionID, Role: role, Scopes: scopes}
+	if principalType, principalValue, ok, err := getRequestPrincipal(r); err == nil && ok {
+		identity.Principal = principalType + ":" + principalValue
+	}
+	if slot, ok := r.Context().Value(identitySlotContextKey{}).(*RequestIdentity); ok {
+		*slot = identity
+	}
+	return r.WithContext(context.WithValue(r.Context(), requestIdentityContextKey{}, identity))
+}
+
+// 供放在认证中间件之外的中间件（如审计）在请求结束后取得认证结果，未通过认证时保持为空
+func WithIdentitySlot(r *http.Request) (*http.Request, *RequestIdentity) {
+	slot := &RequestIdentity{}
+	return r.WithContext(context.WithValue(r.Context(), identitySlotContextKey{}, slot)), slot
+}
+
+// 传输层识别出的请求方，不代表请求已通过认证
+type RequestPeer struct {
+	Type  string `json:"type,omitempty"`
+	Value string `json:"value,omitempty"`
+	PID   int    `json:"pid,omitempty"`
+}
+
+func RequestPeerFrom(r *http.Request) RequestPeer {
+	var peer RequestPeer
+	if principalType, principalValue, ok, err := getRequestPrincipal(r); err == nil && ok {
+		peer.Type = principalType
+		peer.Value = principalValue
+	}
+	if pid, ok := getRequestPID(r); ok {
+		peer.PID = pid
+	}
+	return peer
+}
@@ -0,0 +1,456 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 最近使用时间按该间隔落盘，避免每个请求都写文件
+const keyLastUsedInterval = time.Minute
+
+var (
+	ErrKeyNotFound     = errors.New("公钥不存在")
+	ErrKeyRevoked      = errors.New("公钥已撤销")
+	ErrLastActiveKey   = errors.New("至少需要保留一个有效公钥")
+	ErrKeyExpiryLocked = errors.New("已注册公钥的有效期不能修改")
+	errKeyExpiryInPast = errors.New("公钥有效期必须晚于当前时间")
+
+	errKeysNotInitialized = errors.New("未初始化")
+)
+
+type PublicKeyInfo struct {
+	KeyID      string     `json:"key_id"`
+	PublicKey  string     `json:"public_key"`
+	Label      string     `json:"label,omitempty"`
+	Scopes     []string   `json:"scopes,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Revoked    bool       `json:"revoked,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type publicKeyRegistry struct {
+	Keys []PublicKeyInfo `json:"keys"`
+}
+
+// 旧版本只保存 current 与 previous 两个公钥
+type legacyKeyRing struct {
+	Current  *PublicKeyInfo `json:"current,omitempty"`
+	Previous *PublicKeyInfo `json:"previous,omitempty"`
+}
+
+type KeyOption func(*PublicKeyInfo)
+
+func WithKeyLabel(label string) KeyOption {
+	return func(key *PublicKeyInfo) {
+		key.Label = label
+	}
+}
+
+// 未设置 scopes 时该公钥不额外限制权限范围
+func WithKeyScopes(scopes ...string) KeyOption {
+	return func(key *PublicKeyInfo) {
+		key.Scopes = scopes
+	}
+}
+
+// 零值表示永不过期
+func WithKeyExpiry(expiresAt time.Time) KeyOption {
+	return func(key *PublicKeyInfo) {
+		if expiresAt.IsZero() {
+			key.ExpiresAt = nil
+			return
+		}
+		expiresAt = expiresAt.UTC()
+		key.ExpiresAt = &expiresAt
+	}
+}
+
+func (key PublicKeyInfo) Active(now time.Time) bool {
+	return !key.Revoked && (key.ExpiresAt == nil || now.Before(*key.ExpiresAt))
+}
+
+func normalizePublicKeyInfo(key PublicKeyInfo) (PublicKeyInfo, error) {
+	normalizedPublicKey, _, err := parsePublicKey(key.PublicKey)
+	if err != nil {
+		return PublicKeyInfo{}, err
+	}
+
+	computedKeyID, err := computeKeyID(normalizedPublicKey)
+	if err != nil {
+		return PublicKeyInfo{}, err
+	}
+
+	keyID := strings.TrimSpace(key.KeyID)
+	if keyID == "" {
+		keyID = computedKeyID
+	} else {
+		keyID, err = validateKeyID(keyID)
+		if err != nil {
+			return PublicKeyInfo{}, err
+		}
+		if keyID != computedKeyID {
+			return PublicKeyInfo{}, fmt.Errorf("密钥 ID 与公钥不匹配")
+		}
+	}
+
+	label := strings.TrimSpace(key.Label)
+	if utf8.RuneCountInString(label) > 64 {
+		return PublicKeyInfo{}, fmt.Errorf("公钥标签过长")
+	}
+
+	scopes, err := normalizeScopes(key.Scopes)
+	if err != nil {
+		return PublicKeyInfo{}, err
+	}
+
+	key.KeyID = keyID
+	key.PublicKey = normalizedPublicKey
+	key.Label = label
+	key.Scopes = scopes
+	return key, nil
+}
+
+func sameTime(left *time.Time, right *time.Time) bool {
+	if left == nil || right == nil {
+		return left == right
+	}
+	return left.Equal(*right)
+}
+
+func samePublicKeyInfo(left PublicKeyInfo, right PublicKeyInfo) bool {
+	return left.KeyID == right.KeyID &&
+		left.Label == right.Label &&
+		slices.Equal(left.Scopes, right.Scopes) &&
+		sameTime(left.ExpiresAt, right.ExpiresAt) &&
+		left.Revoked == right.Revoked
+}
+
+func (km *KeyManager) findKeyLocked(keyID string) int {
+	return slices.IndexFunc(km.keys, func(key PublicKeyInfo) bool {
+		return key.KeyID == keyID
+	})
+}
+
+func (km *KeyManager) refreshPublicKeysLocked() error {
+	keys := make(map[string]ed25519.PublicKey, len(km.keys))
+	for _, key := range km.keys {
+		_, publicKey, err := parsePublicKey(key.PublicKey)
+		if err != nil {
+			return err
+		}
+		keys[key.KeyID] = publicKey
+	}
+
+	km.publicKeys = keys
+	return nil
+}
+
+func (km *KeyManager) savePublicKeysLocked() error {
+	data, err := json.MarshalIndent(publicKeyRegistry{Keys: km.keys}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化公钥失败： %w", err)
+	}
+
+	if err := os.WriteFile(km.keyRingPath, data, 0o600); err != nil {
+		return fmt.Errorf("保存公钥失败： %w", err)
+	}
+
+	return nil
+}
+
+func (km *KeyManager) updatePublicKeys(update func([]PublicKeyInfo, time.Time) ([]PublicKeyInfo, bool, error)) (bool, error) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	if km.keysLoadErr != nil {
+		return false, fmt.Errorf("现有公钥文件无法读取，拒绝覆盖： %w", km.keysLoadErr)
+	}
+
+	now := time.Now().UTC()
+	previous := km.keys
+	next, changed, err := update(slices.Clone(previous), now)
+	if err != nil || !changed {
+		return false, err
+	}
+
+	km.keys = next
+	if err := km.refreshPublicKeysLocked(); err != nil {
+		km.keys = previous
+		_ = km.refreshPublicKeysLocked()
+		return false, err
+	}
+	if err := km.savePublicKeysLocked(); err != nil {
+		km.keys = previous
+		_ = km.refreshPublicKeysLocked()
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (km *KeyManager) loadLegacyPublicKeyLocked() (PublicKeyInfo, error) {
+	pubKeyPEM, err := os.ReadFile(km.legacyKeyPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return PublicKeyInfo{}, fmt.Errorf("公钥文件不存在（%w）", errKeysNotInitialized)
+		}
+		return PublicKeyInfo{}, fmt.Errorf("读取公钥文件失败： %w", err)
+	}
+
+	block, _ := pem.Decode(pubKeyPEM)
+	if block == nil {
+		return PublicKeyInfo{}, fmt.Errorf("无效的 PEM 格式")
+	}
+
+	if _, err := x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+		return PublicKeyInfo{}, fmt.Errorf("解析公钥失败： %w", err)
+	}
+
+	return PublicKeyInfo{PublicKey: base64.StdEncoding.EncodeToString(block.Bytes)}, nil
+}
+
+func (km *KeyManager) loadPublicKeys() error {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	var stored []PublicKeyInfo
+	data, err := os.ReadFile(km.keyRingPath)
+	switch {
+	case err == nil:
+		var registry struct {
+			publicKeyRegistry
+			legacyKeyRing
+		}
+		if err := json.Unmarshal(data, &registry); err != nil {
+			return fmt.Errorf("解析公钥失败： %w", err)
+		}
+		stored = registry.Keys
+		// 兼容旧版本的 current/previous 格式，previous 已被轮换淘汰，迁移时直接撤销
+		if registry.Current != nil {
+			stored = append(stored, *registry.Current)
+		}
+		if previous := registry.Previous; previous != nil && !previous.Revoked {
+			now := time.Now().UTC()
+			previous.Revoked = true
+			previous.RevokedAt = &now
+			stored = append(stored, *previous)
+		}
+	case !os.IsNotExist(err):
+		return fmt.Errorf("读取公钥文件失败： %w", err)
+	default:
+		legacyKey, err := km.loadLegacyPublicKeyLocked()
+		if err != nil {
+			return err
+		}
+		stored = []PublicKeyInfo{legacyKey}
+	}
+
+	keys := make([]PublicKeyInfo, 0, len(stored))
+	for _, key := range stored {
+		normalized, err := normalizePublicKeyInfo(key)
+		if err != nil {
+			return err
+		}
+		if slices.ContainsFunc(keys, func(existing PublicKeyInfo) bool {
+			return existing.KeyID == normalized.KeyID
+		}) {
+			continue
+		}
+		if normalized.CreatedAt.IsZero() {
+			normalized.CreatedAt = time.Now().UTC()
+		}
+		keys = append(keys, normalized)
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("公钥列表为空（%w）", errKeysNotInitialized)
+	}
+
+	km.keys = keys
+	return km.refreshPublicKeysLocked()
+}
+
+func PublicKeyID(pubKeyBase64 string) (string, error) {
+	normalizedPublicKey, _, err := parsePublicKey(pubKeyBase64)
+	if err != nil {
+		return "", err
+	}
+	return computeKeyID(normalizedPublicKey)
+}
+
+// 注册公钥，已注册时只按给出的选项更新其标签与权限范围，有效期不能再修改
+func (km *KeyManager) SetPublicKey(pubKeyBase64 string, opts ...KeyOption) (bool, error) {
+	candidate, err := normalizePublicKeyInfo(PublicKeyInfo{PublicKey: pubKeyBase64})
+	if err != nil {
+		return false, err
+	}
+
+	return km.updatePublicKeys(func(keys []PublicKeyInfo, now time.Time) ([]PublicKeyInfo, bool, error) {
+		index := slices.IndexFunc(keys, func(key PublicKeyInfo) bool {
+			return key.KeyID == candidate.KeyID
+		})
+
+		key := candidate
+		key.CreatedAt = now
+		if index >= 0 {
+			key = keys[index]
+			if key.Revoked {
+				return nil, false, ErrKeyRevoked
+			}
+		}
+		for _, opt := range opts {
+			opt(&key)
+		}
+
+		key, err := normalizePublicKeyInfo(key)
+		if err != nil {
+			return nil, false, err
+		}
+		if index >= 0 && !sameTime(keys[index].ExpiresAt, key.ExpiresAt) {
+			return nil, false, ErrKeyExpiryLocked
+		}
+		if key.ExpiresAt != nil && !key.ExpiresAt.After(now) {
+			return nil, false, errKeyExpiryInPast
+		}
+
+		if index < 0 {
+			return append(keys, key), true, nil
+		}
+		if samePublicKeyInfo(keys[index], key) {
+			return keys, false, nil
+		}
+		keys[index] = key
+		return keys, true, nil
+	})
+}
+
+// 撤销公钥，撤销后的公钥保留在列表中但不能再用于认证
+func (km *KeyManager) RevokePublicKey(keyID string) (bool, error) {
+	changed, err := km.updatePublicKeys(func(keys []PublicKeyInfo, now time.Time) ([]PublicKeyInfo, bool, error) {
+		index := slices.IndexFunc(keys, func(key PublicKeyInfo) bool {
+			return key.KeyID == keyID
+		})
+		if index < 0 {
+			return nil, false, ErrKeyNotFound
+		}
+		if keys[index].Revoked {
+			return keys, false, nil
+		}
+
+		keys[index].Revoked = true
+		keys[index].RevokedAt = &now
+		if !slices.ContainsFunc(keys, func(key PublicKeyInfo) bool {
+			return key.Active(now)
+		}) {
+			return nil, false, ErrLastActiveKey
+		}
+		return keys, true, nil
+	})
+	if err == nil {
+		RevokeKeySessions(keyID)
+	}
+	return changed, err
+}
+
+func (km *KeyManager) PublicKeys() []PublicKeyInfo {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+	return slices.Clone(km.keys)
+}
+
+func (km *KeyManager) PublicKey(keyID string) (PublicKeyInfo, bool) {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+
+	index := km.findKeyLocked(keyID)
+	if index < 0 {
+		return PublicKeyInfo{}, false
+	}
+	return km.keys[index], true
+}
+
+func (km *KeyManager) VerifySignature(keyID string, message string, signature string) error {
+	normalizedKeyID, err := validateKeyID(keyID)
+	if err != nil {
+		return err
+	}
+
+	km.mu.RLock()
+	publicKey := km.publicKeys[normalizedKeyID]
+	index := km.findKeyLocked(normalizedKeyID)
+	var key PublicKeyInfo
+	if index >= 0 {
+		key = km.keys[index]
+	}
+	km.mu.RUnlock()
+
+	if publicKey == nil || index < 0 {
+		return fmt.Errorf("密钥 ID 未注册")
+	}
+	now := time.Now().UTC()
+	if key.Revoked {
+		return ErrKeyRevoked
+	}
+	if !key.Active(now) {
+		return fmt.Errorf("公钥已过期")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("签名解码失败： %w", err)
+	}
+
+	if !ed25519.Verify(publicKey, []byte(message), sig) {
+		return fmt.Errorf("签名验证失败")
+	}
+
+	km.touchPublicKey(normalizedKeyID, now)
+	return nil
+}
+
+func (km *KeyManager) touchPublicKey(keyID string, now time.Time) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	index := km.findKeyLocked(keyID)
+	if index < 0 {
+		return
+	}
+	if lastUsed := km.keys[index].LastUsedAt; lastUsed != nil && now.Sub(*lastUsed) < keyLastUsedInterval {
+		return
+	}
+
+	km.keys = slices.Clone(km.keys)
+	km.keys[index].LastUsedAt = &now
+	_ = km.savePublicKeysLocked()
+}
+
+func (km *KeyManager) KeyScopes(keyID string) []string {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+
+	index := km.findKeyLocked(keyID)
+	if index < 0 {
+		return nil
+	}
+	return slices.Clone(km.keys[index].Scopes)
+}
+
+func (km *KeyManager) IsInitialized() bool {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+	return slices.ContainsFunc(km.keys, func(key PublicKeyInfo) bool {
+		return !key.Revoked
+	})
+}
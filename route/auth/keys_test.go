@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadPublicKeysMigratesLegacyFormats(t *testing.T) {
+	current := newTestPublicKey(t)
+	previous := newTestPublicKey(t)
+	der, err := base64.StdEncoding.DecodeString(current.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring, err := json.Marshal(legacyKeyRing{Current: &PublicKeyInfo{PublicKey: current.PublicKey}, Previous: &PublicKeyInfo{PublicKey: previous.PublicKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		ring        []byte
+		pem         []byte
+		wantActive  []string
+		wantRevoked []string
+	}{
+		{name: "current and previous", ring: ring, wantActive: []string{current.KeyID}, wantRevoked: []string{previous.KeyID}},
+		{name: "public_key.pem", pem: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), wantActive: []string{current.KeyID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			km := newTestKeyManager(t)
+			km.legacyKeyPath = filepath.Join(filepath.Dir(km.keyRingPath), "public_key.pem")
+			for path, data := range map[string][]byte{km.keyRingPath: tt.ring, km.legacyKeyPath: tt.pem} {
+				if data == nil {
+					continue
+				}
+				if err := os.WriteFile(path, data, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := km.loadPublicKeys(); err != nil {
+				t.Fatalf("loadPublicKeys() error = %v", err)
+			}
+			now := time.Now()
+			var active, revoked []string
+			for _, key := range km.PublicKeys() {
+				if key.CreatedAt.IsZero() || key.ExpiresAt != nil {
+					t.Fatalf("migrated key = %+v", key)
+				}
+				switch {
+				case key.Active(now):
+					active = append(active, key.KeyID)
+				case key.Revoked && key.RevokedAt != nil:
+					revoked = append(revoked, key.KeyID)
+				}
+			}
+			if !slices.Equal(active, tt.wantActive) || !slices.Equal(revoked, tt.wantRevoked) {
+				t.Fatalf("active = %v, revoked = %v, want %v and %v", active, revoked, tt.wantActive, tt.wantRevoked)
+			}
+		})
+	}
+}
+
+func TestSetPublicKeyUpdatesGivenFields(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name        string
+		opts        []KeyOption
+		revoked     bool
+		wantChanged bool
+		wantErr     error
+		want        func(PublicKeyInfo) bool
+	}{
+		{name: "no options keeps fields", want: func(key PublicKeyInfo) bool {
+			return key.Label == "laptop" && slices.Equal(key.Scopes, []string{ScopeCoreRead}) && key.ExpiresAt.Equal(expiresAt)
+		}},
+		{name: "label only", opts: []KeyOption{WithKeyLabel("desktop")}, wantChanged: true, want: func(key PublicKeyInfo) bool {
+			return key.Label == "desktop" && slices.Equal(key.Scopes, []string{ScopeCoreRead})
+		}},
+		{name: "empty scopes clear restriction", opts: []KeyOption{WithKeyScopes()}, wantChanged: true, want: func(key PublicKeyInfo) bool {
+			return key.Label == "laptop" && len(key.Scopes) == 0
+		}},
+		{name: "same expiry", opts: []KeyOption{WithKeyExpiry(expiresAt)}, want: func(key PublicKeyInfo) bool { return key.ExpiresAt.Equal(expiresAt) }},
+		{name: "extend expiry", opts: []KeyOption{WithKeyExpiry(expiresAt.Add(time.Hour))}, wantErr: ErrKeyExpiryLocked},
+		{name: "remove expiry", opts: []KeyOption{WithKeyExpiry(time.Time{})}, wantErr: ErrKeyExpiryLocked},
+		{name: "revoked key", revoked: true, opts: []KeyOption{WithKeyLabel("desktop")}, wantErr: ErrKeyRevoked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			km, _, _ := newTestSigningKeyManager(t)
+			key := newTestPublicKey(t)
+			if _, err := km.SetPublicKey(key.PublicKey, WithKeyLabel("laptop"), WithKeyScopes(ScopeCoreRead), WithKeyExpiry(expiresAt)); err != nil {
+				t.Fatal(err)
+			}
+			if tt.revoked {
+				if _, err := km.RevokePublicKey(key.KeyID); err != nil {
+					t.Fatal(err)
+				}
+			}
+			before, _ := km.PublicKey(key.KeyID)
+
+			changed, err := km.SetPublicKey(key.PublicKey, tt.opts...)
+			after, _ := km.PublicKey(key.KeyID)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("SetPublicKey() error = %v, want %v", err, tt.wantErr)
+				}
+				if !samePublicKeyInfo(before, after) {
+					t.Fatalf("rejected update changed the key: %+v", after)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetPublicKey() error = %v", err)
+			}
+			if changed != tt.wantChanged || !tt.want(after) {
+				t.Fatalf("SetPublicKey() changed = %v, key = %+v", changed, after)
+			}
+		})
+	}
+}
+
+func TestSetPublicKeyRejectsPastExpiry(t *testing.T) {
+	km := newTestKeyManager(t)
+	key := newTestPublicKey(t)
+	if _, err := km.SetPublicKey(key.PublicKey, WithKeyExpiry(time.Now().Add(-time.Minute))); !errors.Is(err, errKeyExpiryInPast) {
+		t.Fatalf("SetPublicKey() error = %v, want %v", err, errKeyExpiryInPast)
+	}
+	if len(km.PublicKeys()) != 0 {
+		t.Fatal("rejected key was registered")
+	}
+}
+
+func TestRevokeAndExpirePublicKey(t *testing.T) {
+	km, keyID, privateKey := newTestSigningKeyManager(t)
+	other := newTestPublicKey(t)
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, []byte("message")))
+
+	if _, err := km.RevokePublicKey(keyID); !errors.Is(err, ErrLastActiveKey) {
+		t.Fatalf("revoke last key error = %v, want %v", err, ErrLastActiveKey)
+	}
+	if _, err := km.RevokePublicKey(strings.Repeat("0", 64)); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("revoke unknown key error = %v, want %v", err, ErrKeyNotFound)
+	}
+
+	if _, err := km.SetPublicKey(other.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := km.RevokePublicKey(keyID); err != nil || !changed {
+		t.Fatalf("RevokePublicKey() = %v, %v", changed, err)
+	}
+	if changed, err := km.RevokePublicKey(keyID); err != nil || changed {
+		t.Fatalf("revoke twice = %v, %v, want unchanged", changed, err)
+	}
+	if _, err := km.SetPublicKey(km.keys[0].PublicKey); !errors.Is(err, ErrKeyRevoked) {
+		t.Fatalf("re-adding revoked key error = %v, want %v", err, ErrKeyRevoked)
+	}
+	if err := km.VerifySignature(keyID, "message", signature); !errors.Is(err, ErrKeyRevoked) {
+		t.Fatalf("VerifySignature() with revoked key error = %v, want %v", err, ErrKeyRevoked)
+	}
+
+	// 过期判断基于当前时间，直接修改内存中的有效期模拟到期
+	km.mu.Lock()
+	km.keys[0].Revoked, km.keys[0].RevokedAt = false, nil
+	expired := time.Now().Add(-time.Second)
+	km.keys[0].ExpiresAt = &expired
+	km.mu.Unlock()
+	if err := km.VerifySignature(keyID, "message", signature); err == nil || !strings.Contains(err.Error(), "公钥已过期") {
+		t.Fatalf("VerifySignature() with expired key error = %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -20,6 +21,8 @@ import (
 const (
 	authVersionV2       = "2"
 	maxTimestampDriftV2 = 30 * time.Second
+	// V2 请求体整体读入内存计算摘要，更大的请求体应使用 V3
+	MaxV2BodySize int64 = 16 << 20
 )
 
 type nonceStore struct {
@@ -90,22 +93,45 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		if err := km.VerifyRequestPrincipal(r); err != nil {
+		principal, scopes, err := km.AuthorizeRequestPrincipal(r)
+		if err != nil {
 			httphelper.SendError(w, httphelper.Forbidden(fmt.Sprintf("请求方未授权: %v", err)))
 			return
 		}
 
-		if r.Header.Get("X-Auth-Version") != authVersionV2 {
-			httphelper.SendError(w, httphelper.Unauthorized("仅支持 Auth V2"))
+		// 会话令牌的权限范围在使用时与授权主体、公钥的当前权限范围再次取交集
+		if token := r.Header.Get(SessionTokenHeader); token != "" {
+			session, err := authenticateSession(r, km, token)
+			if err != nil {
+				httphelper.SendError(w, err)
+				return
+			}
+			scopes = intersectScopes(intersectScopes(scopes, km.KeyScopes(session.KeyID)), session.Scopes)
+			bindResponseSession(r, session.ID)
+			next.ServeHTTP(w, withRequestIdentity(r, session.KeyID, session.ID, principal.Role, scopes))
 			return
 		}
-		err := authenticateV2(r, km)
+
+		switch r.Header.Get("X-Auth-Version") {
+		case authVersionV2:
+			err = authenticateV2(w, r, km)
+		case authVersionV3:
+			var body io.Closer
+			body, err = authenticateV3(r, km)
+			if body != nil {
+				defer body.Close()
+			}
+		default:
+			err = httphelper.Unauthorized("仅支持 Auth V2 与 Auth V3")
+		}
 		if err != nil {
 			httphelper.SendError(w, err)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		keyID := r.Header.Get("X-Key-Id")
+		scopes = intersectScopes(scopes, km.KeyScopes(keyID))
+		next.ServeHTTP(w, withRequestIdentity(r, keyID, "", principal.Role, scopes))
 	})
 }
 
@@ -113,7 +139,7 @@ func RequireAuth(next http.Handler) http.Handler {
 	return AuthMiddleware(next)
 }
 
-func authenticateV2(r *http.Request, km *KeyManager) error {
+func authenticateV2(w http.ResponseWriter, r *http.Request, km *KeyManager) error {
 	timestamp := r.Header.Get("X-Timestamp")
 	keyID := r.Header.Get("X-Key-Id")
 	nonce := r.Header.Get("X-Nonce")
@@ -124,20 +150,12 @@ func authenticateV2(r *http.Request, km *KeyManager) error {
 		return httphelper.Unauthorized("缺少 V2 认证信息")
 	}
 
-	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	now, err := checkRequestTimestamp(timestamp)
 	if err != nil {
-		return httphelper.BadRequest("无效的时间戳格式")
-	}
-
-	requestTime := time.UnixMilli(ts)
-	now := time.Now()
-	timeDiff := now.Sub(requestTime)
-
-	if timeDiff < -maxTimestampDriftV2 || timeDiff > maxTimestampDriftV2 {
-		return httphelper.Unauthorized("请求已过期或时间戳无效")
+		return err
 	}
 
-	bodyHash, err := hashRequestBody(r)
+	bodyHash, err := hashRequestBody(w, r)
 	if err != nil {
 		return err
 	}
@@ -162,12 +180,30 @@ func authenticateV2(r *http.Request, km *KeyManager) error {
 	return nil
 }
 
-func hashRequestBody(r *http.Request) (string, error) {
+func checkRequestTimestamp(timestamp string) (time.Time, error) {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, httphelper.BadRequest("无效的时间戳格式")
+	}
+
+	now := time.Now()
+	timeDiff := now.Sub(time.UnixMilli(ts))
+	if timeDiff < -maxTimestampDriftV2 || timeDiff > maxTimestampDriftV2 {
+		return time.Time{}, httphelper.Unauthorized("请求已过期或时间戳无效")
+	}
+	return now, nil
+}
+
+func hashRequestBody(w http.ResponseWriter, r *http.Request) (string, error) {
 	var body []byte
 
 	if r.Body != nil {
-		rawBody, err := io.ReadAll(r.Body)
+		rawBody, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxV2BodySize))
 		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return "", httphelper.NewError(http.StatusRequestEntityTooLarge, fmt.Sprintf("V2 请求体不能超过 %d 字节，请改用 Auth V3", MaxV2BodySize))
+			}
 			return "", fmt.Errorf("读取请求体失败： %w", err)
 		}
 		body = rawBody
@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UruhaLushia/sparkle-service/route/httphelper"
+)
+
+func TestBuildCanonicalRequest(t *testing.T) {
+	const bodyHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	tests := []struct {
+		name      string
+		method    string
+		target    string
+		wantPath  string
+		wantQuery string
+	}{
+		{name: "no query", method: "get", target: "/core/status", wantPath: "/core/status"},
+		{name: "sorted keys and values", method: "POST", target: "/core/logs?b=2&a=3&a=1", wantPath: "/core/logs", wantQuery: "a=1&a=3&b=2"},
+		{name: "escaped query", method: "GET", target: "/core?name=a+b&x=%2F", wantPath: "/core", wantQuery: "name=a+b&x=%2F"},
+		{name: "escaped path", method: "DELETE", target: "/auth/keys/a%2Fb", wantPath: "/auth/keys/a%2Fb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+
+			v2, err := buildCanonicalRequestV2(r, "1700000000000", "nonce", "key", bodyHash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantV2 := strings.Join([]string{"SPARKLE-AUTH-V2", "1700000000000", "nonce", "key", strings.ToUpper(tt.method), tt.wantPath, tt.wantQuery, bodyHash}, "\n")
+			if v2 != wantV2 {
+				t.Fatalf("V2 canonical = %q, want %q", v2, wantV2)
+			}
+
+			v3, err := buildCanonicalRequestV3(r, "1700000000000", "nonce", "key", 42, bodyHash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantV3 := strings.Join([]string{"SPARKLE-AUTH-V3", "1700000000000", "nonce", "key", strings.ToUpper(tt.method), tt.wantPath, tt.wantQuery, "42", bodyHash}, "\n")
+			if v3 != wantV3 {
+				t.Fatalf("V3 canonical = %q, want %q", v3, wantV3)
+			}
+		})
+	}
+
+	if _, err := buildCanonicalRequestV2(httptest.NewRequest("GET", "/core?a=%zz", nil), "1", "n", "k", bodyHash); err == nil {
+		t.Fatal("invalid query accepted")
+	}
+}
+
+func TestAuthenticateV3Body(t *testing.T) {
+	km, keyID, privateKey := newTestSigningKeyManager(t)
+	large := bytes.Repeat([]byte("x"), int(maxInMemoryBodySize)+1)
+
+	tests := []struct {
+		name       string
+		body       []byte
+		declared   []byte
+		wantStatus int
+	}{
+		{name: "empty body", body: nil},
+		{name: "in memory", body: []byte(`{"a":1}`)},
+		{name: "spooled", body: large},
+		{name: "digest mismatch", body: []byte(`{"a":2}`), declared: []byte(`{"a":1}`), wantStatus: http.StatusBadRequest},
+		{name: "short body", body: []byte(`{"a"`), declared: []byte(`{"a":1}`), wantStatus: http.StatusBadRequest},
+		{name: "long body", body: []byte(`{"a":1}  `), declared: []byte(`{"a":1}`), wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			declared := tt.declared
+			if declared == nil {
+				declared = tt.body
+			}
+			r := newSignedV3Request(t, keyID, privateKey, tt.body, declared)
+
+			closer, err := authenticateV3(r, km)
+			if tt.wantStatus != 0 {
+				var httpErr *httphelper.HTTPError
+				if !errors.As(err, &httpErr) || httpErr.StatusCode != tt.wantStatus {
+					t.Fatalf("authenticateV3() error = %v, want status %d", err, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("authenticateV3() error = %v", err)
+			}
+
+			got, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.body) {
+				t.Fatalf("handler read %d bytes, want %d", len(got), len(tt.body))
+			}
+
+			spooled, isFile := closer.(*spooledBody)
+			if isFile != (int64(len(tt.body)) > maxInMemoryBodySize) {
+				t.Fatalf("spooled to file = %v for %d bytes", isFile, len(tt.body))
+			}
+			if err := closer.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if isFile {
+				if _, err := os.Stat(spooled.Name()); !errors.Is(err, os.ErrNotExist) {
+					t.Fatalf("temporary body file not removed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestHashRequestBodyLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int64
+		wantStatus int
+	}{
+		{name: "at limit", size: MaxV2BodySize},
+		{name: "over limit", size: MaxV2BodySize + 1, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := bytes.Repeat([]byte("x"), int(tt.size))
+			r := httptest.NewRequest(http.MethodPost, "/core/profile", bytes.NewReader(body))
+
+			hash, err := hashRequestBody(httptest.NewRecorder(), r)
+			if tt.wantStatus != 0 {
+				var httpErr *httphelper.HTTPError
+				if !errors.As(err, &httpErr) || httpErr.StatusCode != tt.wantStatus {
+					t.Fatalf("hashRequestBody() error = %v, want status %d", err, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			sum := sha256.Sum256(body)
+			if hash != hex.EncodeToString(sum[:]) {
+				t.Fatal("body hash mismatch")
+			}
+		})
+	}
+}
+
+func newTestSigningKeyManager(t *testing.T) (*KeyManager, string, ed25519.PrivateKey) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := normalizePublicKeyInfo(PublicKeyInfo{PublicKey: base64.StdEncoding.EncodeToString(der)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	km := newTestKeyManager(t)
+	km.keys = []PublicKeyInfo{key}
+	if err := km.refreshPublicKeysLocked(); err != nil {
+		t.Fatal(err)
+	}
+	return km, key.KeyID, privateKey
+}
+
+func newSignedV3Request(t *testing.T, keyID string, privateKey ed25519.PrivateKey, body []byte, declared []byte) *http.Request {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/core/profile?b=1&a=2", io.NopCloser(bytes.NewReader(body)))
+	r.ContentLength = -1
+
+	sum := sha256.Sum256(declared)
+	contentHash := hex.EncodeToString(sum[:])
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	nonce := hex.EncodeToString(sum[:8]) + strconv.FormatInt(time.Now().UnixNano(), 10)
+	canonical, err := buildCanonicalRequestV3(r, timestamp, nonce, keyID, int64(len(declared)), contentHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Header.Set("X-Auth-Version", authVersionV3)
+	r.Header.Set("X-Timestamp", timestamp)
+	r.Header.Set("X-Key-Id", keyID)
+	r.Header.Set("X-Nonce", nonce)
+	r.Header.Set("X-Content-SHA256", contentHash)
+	r.Header.Set("X-Content-Length", strconv.Itoa(len(declared)))
+	r.Header.Set("X-Signature", base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, []byte(canonical))))
+	return r
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/UruhaLushia/sparkle-service/route/httphelper"
+)
+
+const (
+	authVersionV3 = "3"
+	// V3 请求体的长度上限，足以容纳核心文件与配置包
+	MaxStreamingBodySize int64 = 256 << 20
+	// 不超过该长度的 V3 请求体在内存中校验
+	maxInMemoryBodySize int64 = 1 << 20
+)
+
+var (
+	ErrBodyLength = errors.New("请求体长度与声明不一致")
+	ErrBodyDigest = errors.New("请求体摘要不匹配")
+)
+
+// V3 签名覆盖声明的请求体长度与摘要，请求体在调用处理器前完整接收并校验，
+// 较大的请求体写入临时文件而不是留在内存中。返回值需在处理器结束后关闭
+func authenticateV3(r *http.Request, km *KeyManager) (io.Closer, error) {
+	timestamp := r.Header.Get("X-Timestamp")
+	keyID := r.Header.Get("X-Key-Id")
+	nonce := r.Header.Get("X-Nonce")
+	contentHash := strings.ToLower(r.Header.Get("X-Content-SHA256"))
+	contentLength := r.Header.Get("X-Content-Length")
+	signature := r.Header.Get("X-Signature")
+
+	if timestamp == "" || keyID == "" || nonce == "" || contentHash == "" || contentLength == "" || signature == "" {
+		return nil, httphelper.Unauthorized("缺少 V3 认证信息")
+	}
+
+	now, err := checkRequestTimestamp(timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	declaredLength, err := strconv.ParseInt(contentLength, 10, 64)
+	if err != nil || declaredLength < 0 {
+		return nil, httphelper.BadRequest("无效的请求体长度")
+	}
+	if declaredLength > MaxStreamingBodySize {
+		return nil, httphelper.NewError(http.StatusRequestEntityTooLarge, fmt.Sprintf("请求体不能超过 %d 字节", MaxStreamingBodySize))
+	}
+	if r.ContentLength >= 0 && r.ContentLength != declaredLength {
+		return nil, httphelper.BadRequest(ErrBodyLength.Error())
+	}
+	digest, err := hex.DecodeString(contentHash)
+	if err != nil || len(digest) != sha256.Size {
+		return nil, httphelper.BadRequest("无效的请求体摘要")
+	}
+
+	canonical, err := buildCanonicalRequestV3(r, timestamp, nonce, keyID, declaredLength, contentHash)
+	if err != nil {
+		return nil, httphelper.BadRequest(err.Error())
+	}
+
+	if err := km.VerifySignature(keyID, canonical, signature); err != nil {
+		return nil, httphelper.Unauthorized(err.Error())
+	}
+
+	nonceKey := keyID + ":" + timestamp + ":" + nonce
+	if !requestNonceStore.Remember(nonceKey, now) {
+		return nil, httphelper.Conflict("请求已重放")
+	}
+
+	body, err := spoolVerifiedBody(r.Body, declaredLength, digest)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = body
+	return body, nil
+}
+
+func buildCanonicalRequestV3(r *http.Request, timestamp string, nonce string, keyID string, contentLength int64, bodyHash string) (string, error) {
+	query, err := canonicalizeQuery(r.URL.RawQuery)
+	if err != nil {
+		return "", fmt.Errorf("规范化请求参数失败： %w", err)
+	}
+
+	path := r.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	return strings.Join([]string{
+		"SPARKLE-AUTH-V3",
+		timestamp,
+		nonce,
+		keyID,
+		strings.ToUpper(r.Method),
+		path,
+		query,
+		strconv.FormatInt(contentLength, 10),
+		bodyHash,
+	}, "\n"), nil
+}
+
+// 读完请求体并校验长度与摘要，处理器读到的内容一定与签名一致；
+// 不超过 maxInMemoryBodySize 的请求体留在内存中，更大的写入临时文件
+func spoolVerifiedBody(body io.ReadCloser, declared int64, digest []byte) (io.ReadCloser, error) {
+	if body == nil {
+		body = http.NoBody
+	}
+	defer body.Close()
+
+	hash := sha256.New()
+	reader := io.TeeReader(io.LimitReader(body, declared+1), hash)
+
+	var spooled io.ReadCloser
+	var read int64
+	var err error
+	if declared <= maxInMemoryBodySize {
+		var data []byte
+		data, err = io.ReadAll(reader)
+		read = int64(len(data))
+		spooled = io.NopCloser(bytes.NewReader(data))
+	} else {
+		file, createErr := os.CreateTemp("", "sparkle-request-*")
+		if createErr != nil {
+			return nil, fmt.Errorf("创建请求体临时文件失败： %w", createErr)
+		}
+		spooled = &spooledBody{File: file}
+		read, err = io.Copy(file, reader)
+		if err == nil {
+			_, err = file.Seek(0, io.SeekStart)
+		}
+	}
+
+	switch {
+	case err != nil:
+		err = httphelper.BadRequest(fmt.Sprintf("读取请求体失败： %v", err))
+	case read != declared:
+		err = httphelper.BadRequest(ErrBodyLength.Error())
+	case subtle.ConstantTimeCompare(hash.Sum(nil), digest) != 1:
+		err = httphelper.BadRequest(ErrBodyDigest.Error())
+	}
+	if err != nil {
+		_ = spooled.Close()
+		return nil, err
+	}
+	return spooled, nil
+}
+
+// 关闭时删除临时文件
+type spooledBody struct {
+	*os.File
+}
+
+func (b *spooledBody) Close() error {
+	err := b.File.Close()
+	if removeErr := os.Remove(b.Name()); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) && err == nil {
+		err = removeErr
+	}
+	return err
+}
@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPairingTTL  = 10 * time.Minute
+	maxPairingFailures = 5
+	pairingCodeLength  = 8
+	// 去掉了容易混淆的 0/O、1/I/L
+	pairingCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+)
+
+var (
+	ErrPairingClosed          = errors.New("当前未处于配对模式")
+	ErrPairingCode            = errors.New("配对码错误")
+	ErrPairingLocked          = errors.New("配对码错误次数过多，请等待管理员重新开启配对")
+	ErrPairingPrincipalExists = errors.New("请求方已是授权主体，不能通过配对修改其角色")
+	ErrPairingKeyExists       = errors.New("公钥已注册，不能通过配对重复登记")
+)
+
+type pairingSession struct {
+	CodeHash  string    `json:"code_sha256"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// 按请求方身份分别计数，某个本地用户猜错不会关闭其他用户的配对
+	PeerFailures map[string]int `json:"peer_failures,omitempty"`
+}
+
+type PairingResult struct {
+	Principal AuthorizedPrincipal `json:"principal"`
+	KeyID     string              `json:"key_id"`
+}
+
+func hashPairingCode(code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func generatePairingCode() (string, error) {
+	buf := make([]byte, pairingCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成配对码失败： %w", err)
+	}
+
+	code := make([]byte, 0, pairingCodeLength+1)
+	for i, b := range buf {
+		if i == pairingCodeLength/2 {
+			code = append(code, '-')
+		}
+		code = append(code, pairingCodeAlphabet[int(b)%len(pairingCodeAlphabet)])
+	}
+	return string(code), nil
+}
+
+// 开启配对模式并返回配对码，文件中只保存配对码的摘要
+func (km *KeyManager) OpenPairing(ttl time.Duration, role string) (string, time.Time, error) {
+	if ttl <= 0 {
+		ttl = DefaultPairingTTL
+	}
+	role, err := normalizeRole(role)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	code, err := generatePairingCode()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	session := pairingSession{
+		CodeHash:  hashPairingCode(code),
+		Role:      role,
+		ExpiresAt: time.Now().UTC().Add(ttl),
+	}
+	km.pairingMu.Lock()
+	defer km.pairingMu.Unlock()
+	if err := km.savePairingLocked(session); err != nil {
+		return "", time.Time{}, err
+	}
+	return code, session.ExpiresAt, nil
+}
+
+func (km *KeyManager) savePairingLocked(session pairingSession) error {
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化配对信息失败： %w", err)
+	}
+	if err := os.WriteFile(km.pairingPath, data, 0o600); err != nil {
+		return fmt.Errorf("保存配对信息失败： %w", err)
+	}
+	return nil
+}
+
+func (km *KeyManager) loadPairingLocked() (*pairingSession, error) {
+	data, err := os.ReadFile(km.pairingPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取配对信息失败： %w", err)
+	}
+
+	var session pairingSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("解析配对信息失败： %w", err)
+	}
+	if !time.Now().Before(session.ExpiresAt) {
+		km.closePairingLocked()
+		return nil, nil
+	}
+	return &session, nil
+}
+
+func (km *KeyManager) closePairingLocked() {
+	_ = os.Remove(km.pairingPath)
+}
+
+func (km *KeyManager) PairingOpen() bool {
+	km.pairingMu.Lock()
+	defer km.pairingMu.Unlock()
+
+	session, err := km.loadPairingLocked()
+	return err == nil && session != nil
+}
+
+// 校验配对码，并将请求方的本地身份与提交的公钥登记为授权主体，成功后关闭配对模式。
+// 公钥与授权主体在同一次加锁中写入，任何一步失败都不会留下半完成的登记，配对码也不会失效
+func (km *KeyManager) Pair(r *http.Request, code string, pubKeyBase64 string) (PairingResult, error) {
+	key, err := normalizePublicKeyInfo(PublicKeyInfo{PublicKey: pubKeyBase64, Label: "paired"})
+	if err != nil {
+		return PairingResult{}, err
+	}
+
+	principalType, principalValue, ok, err := getRequestPrincipal(r)
+	if err != nil {
+		return PairingResult{}, err
+	}
+	if !ok {
+		return PairingResult{}, fmt.Errorf("当前请求未携带可识别的本地身份信息")
+	}
+	if err := km.verifyRequestExecutable(r); err != nil {
+		return PairingResult{}, err
+	}
+
+	km.pairingMu.Lock()
+	defer km.pairingMu.Unlock()
+
+	session, err := km.checkPairingCodeLocked(code, principalType+":"+principalValue)
+	if err != nil {
+		return PairingResult{}, err
+	}
+
+	principal := AuthorizedPrincipal{Type: principalType, Value: principalValue, Role: session.Role}
+	if err := validateAuthorizedPrincipal(&principal); err != nil {
+		return PairingResult{}, err
+	}
+	if err := km.addPairedIdentity(key, principal); err != nil {
+		return PairingResult{}, err
+	}
+
+	km.closePairingLocked()
+	return PairingResult{Principal: principal, KeyID: key.KeyID}, nil
+}
+
+// 校验配对码，错误时为该请求方记一次失败，达到上限后只拒绝该请求方
+func (km *KeyManager) checkPairingCodeLocked(code string, peer string) (*pairingSession, error) {
+	session, err := km.loadPairingLocked()
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrPairingClosed
+	}
+	if session.PeerFailures[peer] >= maxPairingFailures {
+		return nil, ErrPairingLocked
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashPairingCode(code)), []byte(session.CodeHash)) != 1 {
+		if session.PeerFailures == nil {
+			session.PeerFailures = make(map[string]int)
+		}
+		session.PeerFailures[peer]++
+		if err := km.savePairingLocked(*session); err != nil {
+			return nil, err
+		}
+		return nil, ErrPairingCode
+	}
+	return session, nil
+}
+
+// 在同一次加锁中登记公钥与授权主体，写入授权主体失败时恢复公钥文件
+func (km *KeyManager) addPairedIdentity(key PublicKeyInfo, principal AuthorizedPrincipal) error {
+	km.mu.Lock()
+	if err := km.checkPairedIdentityLocked(key, principal); err != nil {
+		km.mu.Unlock()
+		return err
+	}
+	principals := append(slices.Clone(km.authorizedPrincipals), principal)
+	if !hasAdminPrincipal(principals) {
+		km.mu.Unlock()
+		return ErrLastAdminPrincipal
+	}
+	if err := checkSocketPrincipals(principals); err != nil {
+		km.mu.Unlock()
+		return err
+	}
+
+	previous := km.keys
+	key.CreatedAt = time.Now().UTC()
+	km.keys = append(slices.Clone(previous), key)
+	err := km.refreshPublicKeysLocked()
+	if err == nil {
+		err = km.savePublicKeysLocked()
+		if err == nil {
+			err = km.saveAuthorizedPrincipalsLocked(principals)
+		}
+	}
+	if err != nil {
+		km.keys = previous
+		_ = km.refreshPublicKeysLocked()
+		_ = km.savePublicKeysLocked()
+		km.mu.Unlock()
+		return err
+	}
+	km.authorizedPrincipals = principals
+	handler := km.principalsChanged
+	km.mu.Unlock()
+
+	if handler != nil {
+		handler()
+	}
+	return nil
+}
+
+func (km *KeyManager) checkPairedIdentityLocked(key PublicKeyInfo, principal AuthorizedPrincipal) error {
+	if km.keysLoadErr != nil {
+		return fmt.Errorf("现有公钥文件无法读取，拒绝覆盖： %w", km.keysLoadErr)
+	}
+	if km.principalsLoadErr != nil {
+		return fmt.Errorf("现有授权主体文件无法读取，拒绝覆盖： %w", km.principalsLoadErr)
+	}
+	if km.findKeyLocked(key.KeyID) >= 0 {
+		return ErrPairingKeyExists
+	}
+	if slices.ContainsFunc(km.authorizedPrincipals, func(existing AuthorizedPrincipal) bool {
+		return samePrincipalSubject(existing, principal)
+	}) {
+		return ErrPairingPrincipalExists
+	}
+	return nil
+}
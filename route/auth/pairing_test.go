@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestKeyManager(t *testing.T) *KeyManager {
+	t.Helper()
+	dir := t.TempDir()
+	return &KeyManager{
+		keyRingPath:         filepath.Join(dir, "public_keys.json"),
+		principalPath:       filepath.Join(dir, "authorized_principals.json"),
+		legacyPrincipalPath: filepath.Join(dir, "authorized_principal.json"),
+		pairingPath:         filepath.Join(dir, "pairing.json"),
+	}
+}
+
+func newTestPublicKey(t *testing.T) PublicKeyInfo {
+	t.Helper()
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := normalizePublicKeyInfo(PublicKeyInfo{PublicKey: base64.StdEncoding.EncodeToString(der)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestCheckPairingCodeLockout(t *testing.T) {
+	km := newTestKeyManager(t)
+	code, _, err := km.OpenPairing(time.Minute, RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		peer    string
+		code    string
+		wantErr error
+	}{
+		{peer: "uid:1000", code: "WRONG-CODE", wantErr: ErrPairingCode},
+		{peer: "uid:1000", code: "WRONG-CODE", wantErr: ErrPairingCode},
+		{peer: "uid:1000", code: "WRONG-CODE", wantErr: ErrPairingCode},
+		{peer: "uid:1000", code: "WRONG-CODE", wantErr: ErrPairingCode},
+		{peer: "uid:1000", code: "WRONG-CODE", wantErr: ErrPairingCode},
+		{peer: "uid:1000", code: code, wantErr: ErrPairingLocked},
+		{peer: "uid:1001", code: "WRONG-CODE", wantErr: ErrPairingCode},
+		{peer: "uid:1001", code: code},
+	}
+
+	for i, step := range steps {
+		km.pairingMu.Lock()
+		session, err := km.checkPairingCodeLocked(step.code, step.peer)
+		km.pairingMu.Unlock()
+		if !errors.Is(err, step.wantErr) {
+			t.Fatalf("step %d (%s): error = %v, want %v", i, step.peer, err, step.wantErr)
+		}
+		if step.wantErr == nil && session.Role != RoleAdmin {
+			t.Fatalf("step %d: role = %q", i, session.Role)
+		}
+	}
+	if !km.PairingOpen() {
+		t.Fatal("pairing closed by another peer's failures")
+	}
+}
+
+func TestAddPairedIdentity(t *testing.T) {
+	existingKey := newTestPublicKey(t)
+	admin := AuthorizedPrincipal{Type: PrincipalTypeSID, Value: "S-1-5-21-1-2-3-1000", Role: RoleAdmin}
+
+	tests := []struct {
+		name       string
+		principals []AuthorizedPrincipal
+		key        PublicKeyInfo
+		principal  AuthorizedPrincipal
+		wantErr    error
+	}{
+		{name: "first admin", principal: admin},
+		{name: "first viewer", principal: AuthorizedPrincipal{Type: PrincipalTypeSID, Value: admin.Value, Role: RoleViewer}, wantErr: ErrLastAdminPrincipal},
+		{name: "existing principal", principals: []AuthorizedPrincipal{admin}, principal: AuthorizedPrincipal{Type: PrincipalTypeSID, Value: admin.Value, Role: RoleViewer}, wantErr: ErrPairingPrincipalExists},
+		{name: "existing key", principals: []AuthorizedPrincipal{admin}, key: existingKey, principal: AuthorizedPrincipal{Type: PrincipalTypeSID, Value: "S-1-5-21-1-2-3-1001", Role: RoleViewer}, wantErr: ErrPairingKeyExists},
+		{name: "new viewer", principals: []AuthorizedPrincipal{admin}, principal: AuthorizedPrincipal{Type: PrincipalTypeSID, Value: "S-1-5-21-1-2-3-1001", Role: RoleViewer}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			km := newTestKeyManager(t)
+			km.keys = []PublicKeyInfo{existingKey}
+			if err := km.refreshPublicKeysLocked(); err != nil {
+				t.Fatal(err)
+			}
+			km.authorizedPrincipals = tt.principals
+			key := tt.key
+			if key.KeyID == "" {
+				key = newTestPublicKey(t)
+			}
+
+			err := km.addPairedIdentity(key, tt.principal)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("addPairedIdentity() error = %v, want %v", err, tt.wantErr)
+			}
+
+			_, keyErr := os.Stat(km.keyRingPath)
+			_, principalErr := os.Stat(km.principalPath)
+			if tt.wantErr != nil {
+				if keyErr == nil || principalErr == nil || len(km.keys) != 1 || len(km.authorizedPrincipals) != len(tt.principals) {
+					t.Fatal("rejected pairing left partial state behind")
+				}
+				return
+			}
+			if keyErr != nil || principalErr != nil {
+				t.Fatalf("pairing not persisted: %v, %v", keyErr, principalErr)
+			}
+			if _, ok := km.PublicKey(key.KeyID); !ok {
+				t.Fatal("paired key not registered")
+			}
+		})
+	}
+}
@@ -0,0 +1,400 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	PrincipalTypeUID = "uid"
+	PrincipalTypeGID = "gid"
+	PrincipalTypeSID = "sid"
+
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+	RoleViewer   = "viewer"
+)
+
+var (
+	ErrPrincipalNotFound  = errors.New("授权主体不存在")
+	ErrLastAdminPrincipal = errors.New("至少需要保留一个 admin 授权主体")
+	errPrincipalsNotBound = errors.New("未绑定请求方身份")
+	ErrSocketPrincipals   = errors.New("unix socket 通过文件所有者与属组控制访问，最多只能授权一个 UID 与一个 GID（UID 0 不计入），多个用户请加入同一用户组后按 GID 授权")
+)
+
+type AuthorizedPrincipal struct {
+	Type   string   `json:"type"`
+	Value  string   `json:"value"`
+	Role   string   `json:"role"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+type authorizedPrincipalList struct {
+	Principals []AuthorizedPrincipal `json:"principals"`
+}
+
+func roleRank(role string) int {
+	switch role {
+	case RoleAdmin:
+		return 3
+	case RoleOperator:
+		return 2
+	case RoleViewer:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// 角色为空时视为 admin
+func normalizeRole(role string) (string, error) {
+	role = strings.ToLower(strings.TrimSpace(role))
+	if role == "" {
+		return RoleAdmin, nil
+	}
+	if roleRank(role) == 0 {
+		return "", fmt.Errorf("不支持的角色: %s", role)
+	}
+	return role, nil
+}
+
+func validateAuthorizedPrincipal(principal *AuthorizedPrincipal) error {
+	if principal == nil {
+		return fmt.Errorf("授权主体为空")
+	}
+
+	principal.Type = strings.ToLower(strings.TrimSpace(principal.Type))
+	principal.Value = strings.TrimSpace(principal.Value)
+
+	switch principal.Type {
+	case PrincipalTypeUID, PrincipalTypeGID:
+		name := strings.ToUpper(principal.Type)
+		if principal.Value == "" {
+			return fmt.Errorf("%s 不能为空", name)
+		}
+		id, err := strconv.ParseUint(principal.Value, 10, 32)
+		if err != nil {
+			return fmt.Errorf("%s 格式无效： %w", name, err)
+		}
+		principal.Value = strconv.FormatUint(id, 10)
+	case PrincipalTypeSID:
+		if principal.Value == "" {
+			return fmt.Errorf("SID 不能为空")
+		}
+		if !strings.HasPrefix(principal.Value, "S-") {
+			return fmt.Errorf("SID 格式无效")
+		}
+	default:
+		return fmt.Errorf("不支持的授权主体类型: %s", principal.Type)
+	}
+
+	role, err := normalizeRole(principal.Role)
+	if err != nil {
+		return err
+	}
+	principal.Role = role
+
+	scopes, err := normalizeScopes(principal.Scopes)
+	if err != nil {
+		return err
+	}
+	principal.Scopes = scopes
+
+	return nil
+}
+
+func samePrincipalSubject(left AuthorizedPrincipal, right AuthorizedPrincipal) bool {
+	return left.Type == right.Type && left.Value == right.Value
+}
+
+func hasAdminPrincipal(principals []AuthorizedPrincipal) bool {
+	return slices.ContainsFunc(principals, func(principal AuthorizedPrincipal) bool {
+		return slices.Contains(principalScopes(principal), ScopeAuthAdmin)
+	})
+}
+
+// unix socket 只能表达单一所有者与属组，超出时拒绝保存而不是放开 socket 权限；root 始终可以连接，不计入
+func checkSocketPrincipals(principals []AuthorizedPrincipal) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	var uids, gids int
+	for _, principal := range principals {
+		switch {
+		case principal.Type == PrincipalTypeUID && principal.Value != "0":
+			uids++
+		case principal.Type == PrincipalTypeGID:
+			gids++
+		}
+	}
+	if uids > 1 || gids > 1 {
+		return ErrSocketPrincipals
+	}
+	return nil
+}
+
+func (km *KeyManager) saveAuthorizedPrincipalsLocked(principals []AuthorizedPrincipal) error {
+	data, err := json.MarshalIndent(authorizedPrincipalList{Principals: principals}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化授权主体失败： %w", err)
+	}
+
+	if err := os.WriteFile(km.principalPath, data, 0o600); err != nil {
+		return fmt.Errorf("保存授权主体失败： %w", err)
+	}
+	if err := os.Remove(km.legacyPrincipalPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除旧授权主体文件失败： %w", err)
+	}
+
+	return nil
+}
+
+func (km *KeyManager) updateAuthorizedPrincipals(update func([]AuthorizedPrincipal) ([]AuthorizedPrincipal, bool, error)) (bool, error) {
+	km.mu.Lock()
+	if km.principalsLoadErr != nil {
+		err := km.principalsLoadErr
+		km.mu.Unlock()
+		return false, fmt.Errorf("现有授权主体文件无法读取，拒绝覆盖： %w", err)
+	}
+	next, changed, err := update(slices.Clone(km.authorizedPrincipals))
+	if err == nil && changed {
+		if !hasAdminPrincipal(next) {
+			err = ErrLastAdminPrincipal
+		} else if err = checkSocketPrincipals(next); err == nil {
+			err = km.saveAuthorizedPrincipalsLocked(next)
+		}
+	}
+	if err != nil || !changed {
+		km.mu.Unlock()
+		return false, err
+	}
+	km.authorizedPrincipals = next
+	handler := km.principalsChanged
+	km.mu.Unlock()
+
+	if handler != nil {
+		handler()
+	}
+	return true, nil
+}
+
+// 新增授权主体，已存在时更新其角色与权限范围
+func (km *KeyManager) AddAuthorizedPrincipal(principal AuthorizedPrincipal) (bool, error) {
+	if err := validateAuthorizedPrincipal(&principal); err != nil {
+		return false, err
+	}
+
+	return km.updateAuthorizedPrincipals(func(principals []AuthorizedPrincipal) ([]AuthorizedPrincipal, bool, error) {
+		index := slices.IndexFunc(principals, func(existing AuthorizedPrincipal) bool {
+			return samePrincipalSubject(existing, principal)
+		})
+		if index < 0 {
+			return append(principals, principal), true, nil
+		}
+		if principals[index].Role == principal.Role && slices.Equal(principals[index].Scopes, principal.Scopes) {
+			return principals, false, nil
+		}
+		principals[index] = principal
+		return principals, true, nil
+	})
+}
+
+func (km *KeyManager) RemoveAuthorizedPrincipal(principalType string, value string) (bool, error) {
+	target := AuthorizedPrincipal{Type: principalType, Value: value}
+	if err := validateAuthorizedPrincipal(&target); err != nil {
+		return false, err
+	}
+
+	return km.updateAuthorizedPrincipals(func(principals []AuthorizedPrincipal) ([]AuthorizedPrincipal, bool, error) {
+		index := slices.IndexFunc(principals, func(existing AuthorizedPrincipal) bool {
+			return samePrincipalSubject(existing, target)
+		})
+		if index < 0 {
+			return nil, false, ErrPrincipalNotFound
+		}
+		return slices.Delete(principals, index, index+1), true, nil
+	})
+}
+
+// 以 admin 角色授权指定 UID
+func (km *KeyManager) SetAuthorizedUID(uid uint32) (bool, error) {
+	return km.AddAuthorizedPrincipal(AuthorizedPrincipal{
+		Type:  PrincipalTypeUID,
+		Value: strconv.FormatUint(uint64(uid), 10),
+		Role:  RoleAdmin,
+	})
+}
+
+// 以 admin 角色授权指定 SID
+func (km *KeyManager) SetAuthorizedSID(sid string) (bool, error) {
+	return km.AddAuthorizedPrincipal(AuthorizedPrincipal{
+		Type:  PrincipalTypeSID,
+		Value: strings.TrimSpace(sid),
+		Role:  RoleAdmin,
+	})
+}
+
+func (km *KeyManager) loadAuthorizedPrincipals() error {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	data, err := os.ReadFile(km.principalPath)
+	switch {
+	case err == nil:
+		var list authorizedPrincipalList
+		if err := json.Unmarshal(data, &list); err != nil {
+			return fmt.Errorf("解析授权主体失败： %w", err)
+		}
+
+		principals := make([]AuthorizedPrincipal, 0, len(list.Principals))
+		for _, principal := range list.Principals {
+			if err := validateAuthorizedPrincipal(&principal); err != nil {
+				return err
+			}
+			if slices.ContainsFunc(principals, func(existing AuthorizedPrincipal) bool {
+				return samePrincipalSubject(existing, principal)
+			}) {
+				return fmt.Errorf("授权主体重复: %s:%s", principal.Type, principal.Value)
+			}
+			principals = append(principals, principal)
+		}
+		if len(principals) == 0 {
+			return fmt.Errorf("授权主体列表为空（%w）", errPrincipalsNotBound)
+		}
+
+		km.authorizedPrincipals = principals
+		return nil
+	case !os.IsNotExist(err):
+		return fmt.Errorf("读取授权主体文件失败： %w", err)
+	}
+
+	// 兼容只有单个授权主体的旧版本文件，视为 admin
+	data, err = os.ReadFile(km.legacyPrincipalPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("授权主体文件不存在（%w）", errPrincipalsNotBound)
+		}
+		return fmt.Errorf("读取授权主体文件失败： %w", err)
+	}
+
+	var principal AuthorizedPrincipal
+	if err := json.Unmarshal(data, &principal); err != nil {
+		return fmt.Errorf("解析授权主体失败： %w", err)
+	}
+	principal.Role = RoleAdmin
+	if err := validateAuthorizedPrincipal(&principal); err != nil {
+		return err
+	}
+
+	km.authorizedPrincipals = []AuthorizedPrincipal{principal}
+	return nil
+}
+
+func (km *KeyManager) HasAuthorizedPrincipal() bool {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+	return len(km.authorizedPrincipals) > 0
+}
+
+func (km *KeyManager) AuthorizedPrincipals() []AuthorizedPrincipal {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+	return slices.Clone(km.authorizedPrincipals)
+}
+
+func (km *KeyManager) AuthorizedPrincipal(principalType string, value string) (AuthorizedPrincipal, bool) {
+	target := AuthorizedPrincipal{Type: principalType, Value: value}
+	if err := validateAuthorizedPrincipal(&target); err != nil {
+		return AuthorizedPrincipal{}, false
+	}
+
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+	index := slices.IndexFunc(km.authorizedPrincipals, func(existing AuthorizedPrincipal) bool {
+		return samePrincipalSubject(existing, target)
+	})
+	if index < 0 {
+		return AuthorizedPrincipal{}, false
+	}
+	return km.authorizedPrincipals[index], true
+}
+
+// 授权主体变化后调用，用于同步 socket 权限
+func (km *KeyManager) SetPrincipalsChangedHandler(handler func()) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	km.principalsChanged = handler
+}
+
+// 匹配请求方的 UID/SID 与所属组，命中多个授权主体时合并其权限范围，角色取最高者
+func (km *KeyManager) AuthorizeRequestPrincipal(r *http.Request) (AuthorizedPrincipal, []string, error) {
+	principals := km.AuthorizedPrincipals()
+	if len(principals) == 0 {
+		return AuthorizedPrincipal{}, nil, fmt.Errorf("未绑定请求方身份")
+	}
+
+	requestType, requestValue, ok, err := getRequestPrincipal(r)
+	if err != nil {
+		return AuthorizedPrincipal{}, nil, err
+	}
+	if !ok {
+		return AuthorizedPrincipal{}, nil, fmt.Errorf("当前请求未携带可识别的本地身份信息")
+	}
+
+	var groups []string
+	if slices.ContainsFunc(principals, func(principal AuthorizedPrincipal) bool {
+		return principal.Type == PrincipalTypeGID
+	}) {
+		groups, err = getRequestGroups(r)
+		if err != nil {
+			return AuthorizedPrincipal{}, nil, err
+		}
+	}
+
+	var (
+		matched AuthorizedPrincipal
+		scopes  []string
+	)
+	for _, principal := range principals {
+		switch {
+		case principal.Type == requestType && principal.Value == requestValue:
+		case principal.Type == PrincipalTypeGID && slices.Contains(groups, principal.Value):
+		default:
+			continue
+		}
+		if roleRank(principal.Role) > roleRank(matched.Role) {
+			matched = principal
+		}
+		scopes = append(scopes, principalScopes(principal)...)
+	}
+	if matched.Type == "" {
+		return AuthorizedPrincipal{}, nil, fmt.Errorf("请求方身份不匹配")
+	}
+	if err := km.verifyRequestExecutable(r); err != nil {
+		return AuthorizedPrincipal{}, nil, err
+	}
+
+	slices.Sort(scopes)
+	return matched, slices.Compact(scopes), nil
+}
+
+// 以授权主体中的 SID 生成命名管道 SDDL，未绑定 SID 时返回空字符串
+func AuthorizedPipeSDDL() string {
+	var builder strings.Builder
+	for _, principal := range GetKeyManager().AuthorizedPrincipals() {
+		if principal.Type == PrincipalTypeSID {
+			builder.WriteString("(A;OICI;GWGR;;;" + principal.Value + ")")
+		}
+	}
+	if builder.Len() == 0 {
+		return ""
+	}
+	return "D:PAI" + builder.String() + "(A;OICI;GWGR;;;SY)"
+}
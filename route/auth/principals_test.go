@@ -0,0 +1,43 @@
+//go:build !windows
+
+package auth
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddAuthorizedPrincipalSocketLimits(t *testing.T) {
+	admin := AuthorizedPrincipal{Type: PrincipalTypeUID, Value: "1000", Role: RoleAdmin}
+
+	tests := []struct {
+		name    string
+		add     AuthorizedPrincipal
+		wantErr error
+	}{
+		{name: "single gid", add: AuthorizedPrincipal{Type: PrincipalTypeGID, Value: "100", Role: RoleViewer}},
+		{name: "root uid not counted", add: AuthorizedPrincipal{Type: PrincipalTypeUID, Value: "0", Role: RoleAdmin}},
+		{name: "second uid", add: AuthorizedPrincipal{Type: PrincipalTypeUID, Value: "1001", Role: RoleViewer}, wantErr: ErrSocketPrincipals},
+		{name: "update existing uid", add: AuthorizedPrincipal{Type: PrincipalTypeUID, Value: "1000", Role: RoleAdmin, Scopes: []string{ScopeAuthAdmin}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			km := &KeyManager{
+				principalPath:        filepath.Join(dir, "authorized_principals.json"),
+				legacyPrincipalPath:  filepath.Join(dir, "authorized_principal.json"),
+				authorizedPrincipals: []AuthorizedPrincipal{admin},
+			}
+
+			_, err := km.AddAuthorizedPrincipal(tt.add)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AddAuthorizedPrincipal() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && len(km.AuthorizedPrincipals()) != 1 {
+				t.Fatalf("principals changed after rejected add: %v", km.AuthorizedPrincipals())
+			}
+		})
+	}
+}
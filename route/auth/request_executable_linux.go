@@ -0,0 +1,100 @@
+//go:build linux
+
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/UruhaLushia/sparkle-service/route/pipectx"
+
+	"golang.org/x/sys/unix"
+)
+
+type executableDigestKey struct {
+	dev   uint64
+	ino   uint64
+	size  int64
+	mtime unix.Timespec
+	ctime unix.Timespec
+}
+
+var (
+	executableDigestMu    sync.Mutex
+	executableDigestCache = make(map[executableDigestKey]string)
+)
+
+const maxExecutableDigestCache = 64
+
+// 通过连接建立时取得的 pidfd 确认读取 /proc/<pid>/exe 期间 PID 没有被其他进程复用
+func getRequestExecutable(r *http.Request) (PeerExecutable, error) {
+	info, ok := pipectx.RequestUnixPeerInfo(r)
+	if !ok {
+		return PeerExecutable{}, fmt.Errorf("无法识别请求方进程")
+	}
+	if info.PIDFD < 0 {
+		return PeerExecutable{}, fmt.Errorf("无法取得请求方进程的 pidfd")
+	}
+
+	exeLink := "/proc/" + strconv.Itoa(info.PID) + "/exe"
+	file, err := os.Open(exeLink)
+	if err != nil {
+		return PeerExecutable{}, fmt.Errorf("打开请求方可执行文件失败： %w", err)
+	}
+	defer file.Close()
+	path, err := os.Readlink(exeLink)
+	if err != nil {
+		return PeerExecutable{}, fmt.Errorf("读取请求方可执行文件路径失败： %w", err)
+	}
+	if err := unix.PidfdSendSignal(info.PIDFD, 0, nil, 0); err != nil {
+		return PeerExecutable{}, fmt.Errorf("请求方进程已退出")
+	}
+
+	digest, err := executableDigest(file)
+	if err != nil {
+		return PeerExecutable{}, err
+	}
+	return PeerExecutable{PID: info.PID, Path: path, SHA256: digest}, nil
+}
+
+// 可执行文件通常较大，按 inode 与修改时间缓存摘要
+func executableDigest(file *os.File) (string, error) {
+	var stat unix.Stat_t
+	if err := unix.Fstat(int(file.Fd()), &stat); err != nil {
+		return "", fmt.Errorf("读取请求方可执行文件信息失败： %w", err)
+	}
+	key := executableDigestKey{
+		dev:   uint64(stat.Dev),
+		ino:   uint64(stat.Ino),
+		size:  stat.Size,
+		mtime: stat.Mtim,
+		ctime: stat.Ctim,
+	}
+
+	executableDigestMu.Lock()
+	digest, ok := executableDigestCache[key]
+	executableDigestMu.Unlock()
+	if ok {
+		return digest, nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("计算请求方可执行文件摘要失败： %w", err)
+	}
+	digest = hex.EncodeToString(hash.Sum(nil))
+
+	executableDigestMu.Lock()
+	if len(executableDigestCache) >= maxExecutableDigestCache {
+		clear(executableDigestCache)
+	}
+	executableDigestCache[key] = digest
+	executableDigestMu.Unlock()
+	return digest, nil
+}
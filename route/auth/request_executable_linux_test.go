@@ -0,0 +1,115 @@
+//go:build linux
+
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecutableDigestCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client")
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	digestOf := func(content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		// 保持大小与修改时间不变，只有 ctime 能反映内容被改写
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	digest := func() string {
+		t.Helper()
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		got, err := executableDigest(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	write("version-1")
+	if got := digest(); got != digestOf("version-1") {
+		t.Fatalf("digest = %s, want %s", got, digestOf("version-1"))
+	}
+	if got := digest(); got != digestOf("version-1") {
+		t.Fatalf("cached digest = %s, want %s", got, digestOf("version-1"))
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	write("version-2")
+	if got := digest(); got != digestOf("version-2") {
+		t.Fatalf("digest after rewrite = %s, want %s", got, digestOf("version-2"))
+	}
+}
+
+func TestVerifyRequestExecutable(t *testing.T) {
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	self, err = filepath.EvalSymlinks(self)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(self)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "no allowlist"},
+		{name: "path", content: `{"executables":[{"path":"` + self + `"}]}`},
+		{name: "path and digest", content: `{"executables":[{"path":"` + self + `","sha256":"` + digest + `"}]}`},
+		{name: "other path", content: `{"executables":[{"path":"/usr/bin/sparkle-client"}]}`, wantErr: true},
+		{name: "other digest", content: `{"executables":[{"sha256":"` + strings.Repeat("0", 64) + `"}]}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			km := newTestKeyManager(t)
+			km.executablesPath = filepath.Join(t.TempDir(), "client_executables.json")
+			if tt.content != "" {
+				if err := os.WriteFile(km.executablesPath, []byte(tt.content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var err error
+			serveUnixRequest(t, func(w http.ResponseWriter, r *http.Request) {
+				err = km.verifyRequestExecutable(r)
+			})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), digest) {
+					t.Fatalf("verifyRequestExecutable() error = %v, want rejection naming sha256 %s", err, digest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifyRequestExecutable() error = %v", err)
+			}
+		})
+	}
+}
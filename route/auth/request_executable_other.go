@@ -0,0 +1,13 @@
+//go:build !linux
+
+package auth
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// 仅 Linux 支持校验请求方可执行文件，其他平台配置了允许列表时拒绝请求
+func getRequestExecutable(_ *http.Request) (PeerExecutable, error) {
+	return PeerExecutable{}, fmt.Errorf("当前平台不支持校验请求方可执行文件")
+}
@@ -17,3 +17,21 @@ func getRequestPrincipal(r *http.Request) (string, string, bool, error) {
 
 	return "uid", strconv.FormatUint(uint64(info.UID), 10), true, nil
 }
+
+func getRequestGroups(r *http.Request) ([]string, error) {
+	info, ok := pipectx.RequestDarwinPeerInfo(r)
+	if !ok {
+		return nil, nil
+	}
+
+	groups := make([]string, 0, len(info.Groups))
+	for _, gid := range info.Groups {
+		groups = append(groups, strconv.FormatUint(uint64(gid), 10))
+	}
+	return groups, nil
+}
+
+func getRequestPID(r *http.Request) (int, bool) {
+	info, ok := pipectx.RequestDarwinPeerInfo(r)
+	return info.PID, ok && info.PID > 0
+}
@@ -3,10 +3,16 @@
 package auth
 
 import (
+	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/UruhaLushia/sparkle-service/route/pipectx"
+
+	"golang.org/x/sys/unix"
 )
 
 func getRequestPrincipal(r *http.Request) (string, string, bool, error) {
@@ -17,3 +23,43 @@ func getRequestPrincipal(r *http.Request) (string, string, bool, error) {
 
 	return "uid", strconv.FormatUint(uint64(info.UID), 10), true, nil
 }
+
+// 返回请求方的主组与附加组。附加组优先使用连接建立时通过 SO_PEERGROUPS 取得的值，
+// 旧内核退回读取 /proc/<pid>/status，并通过 pidfd 确认读取期间 PID 没有被复用
+func getRequestGroups(r *http.Request) ([]string, error) {
+	info, ok := pipectx.RequestUnixPeerInfo(r)
+	if !ok {
+		return nil, nil
+	}
+
+	groups := []string{strconv.FormatUint(uint64(info.GID), 10)}
+	if info.Groups != nil {
+		for _, gid := range info.Groups {
+			groups = append(groups, strconv.FormatUint(uint64(gid), 10))
+		}
+		return groups, nil
+	}
+
+	if info.PIDFD < 0 {
+		return nil, fmt.Errorf("无法取得请求方进程的 pidfd")
+	}
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(info.PID), "status"))
+	if err != nil {
+		return nil, fmt.Errorf("读取请求方所属组失败： %w", err)
+	}
+	if err := unix.PidfdSendSignal(info.PIDFD, 0, nil, 0); err != nil {
+		return nil, fmt.Errorf("请求方进程已退出")
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, found := strings.CutPrefix(line, "Groups:"); found {
+			groups = append(groups, strings.Fields(value)...)
+			break
+		}
+	}
+	return groups, nil
+}
+
+func getRequestPID(r *http.Request) (int, bool) {
+	info, ok := pipectx.RequestUnixPeerInfo(r)
+	return info.PID, ok
+}
@@ -7,3 +7,11 @@ import "net/http"
 func getRequestPrincipal(_ *http.Request) (string, string, bool, error) {
 	return "", "", false, nil
 }
+
+func getRequestGroups(_ *http.Request) ([]string, error) {
+	return nil, nil
+}
+
+func getRequestPID(_ *http.Request) (int, bool) {
+	return 0, false
+}
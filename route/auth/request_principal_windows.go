@@ -57,3 +57,20 @@ func getPipeClientSID(pipe windows.Handle) (string, error) {
 
 	return tokenUser.User.Sid.String(), nil
 }
+
+func getRequestGroups(_ *http.Request) ([]string, error) {
+	return nil, nil
+}
+
+func getRequestPID(r *http.Request) (int, bool) {
+	handle, ok := pipectx.RequestPipeHandle(r)
+	if !ok || handle == 0 {
+		return 0, false
+	}
+
+	var pid uint32
+	if err := windows.GetNamedPipeClientProcessId(handle, &pid); err != nil || pid == 0 {
+		return 0, false
+	}
+	return int(pid), true
+}
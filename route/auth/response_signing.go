@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/UruhaLushia/sparkle-service/log"
+)
+
+const (
+	ServiceKeyIDHeader         = "X-Service-Key-Id"
+	ServiceTimestampHeader     = "X-Service-Timestamp"
+	ServiceNonceHeader         = "X-Service-Nonce"
+	ServiceContentSHA256Header = "X-Service-Content-SHA256"
+	ServiceSignatureHeader     = "X-Service-Signature"
+
+	// 超过该大小或处理器主动 Flush 时改为流式输出，摘要与签名放在 trailer 中
+	maxBufferedResponseSize = 1 << 20
+)
+
+// 事件流与控制器代理的响应不缓存，直接流式输出
+func streamingResponsePath(path string) bool {
+	return path == "/core/events" || path == "/core/controller" || strings.HasPrefix(path, "/core/controller/")
+}
+
+type responseSigningContextKey struct{}
+
+// 会话令牌请求通过认证后记录会话 ID，响应签名随之绑定到该会话
+func bindResponseSession(r *http.Request, sessionID string) {
+	if sw, ok := r.Context().Value(responseSigningContextKey{}).(*signingResponseWriter); ok {
+		sw.sessionID = sessionID
+	}
+}
+
+var serviceKeyWarnOnce sync.Once
+
+// 用服务私钥对响应签名，客户端据此确认对端是持有服务私钥的真实服务
+func SignResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		km := GetKeyManager()
+		key, err := km.loadServiceKey()
+		if err != nil {
+			serviceKeyWarnOnce.Do(func() {
+				log.Printf("警告：加载服务私钥失败，响应将不带签名：%v", err)
+			})
+			next.ServeHTTP(w, r)
+			return
+		}
+		identity, err := serviceIdentityOf(key)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sw := &signingResponseWriter{
+			ResponseWriter: w,
+			request:        r,
+			key:            key,
+			keyID:          identity.KeyID,
+			hash:           sha256.New(),
+			alwaysStream:   streamingResponsePath(r.URL.Path),
+		}
+		// 处理器 panic 时不补写响应，由 net/http 直接断开连接
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), responseSigningContextKey{}, sw)))
+		sw.finish()
+	})
+}
+
+// 响应签名覆盖请求的方法、路径、X-Nonce 与会话 ID，防止响应被挪用到其他请求或其他会话
+func ResponseSignatureMessage(timestamp string, nonce string, sessionID string, method string, path string, status int, bodyHash string) string {
+	return strings.Join([]string{
+		"SPARKLE-RESPONSE-V1",
+		timestamp,
+		nonce,
+		sessionID,
+		strings.ToUpper(method),
+		path,
+		strconv.Itoa(status),
+		bodyHash,
+	}, "\n")
+}
+
+type signingResponseWriter struct {
+	http.ResponseWriter
+	request     *http.Request
+	key         ed25519.PrivateKey
+	keyID       string
+	sessionID   string
+	timestamp   string
+	hash        hash.Hash
+	buf         bytes.Buffer
+	status      int
+	wroteHeader bool
+	streaming   bool
+	hijacked    bool
+	// 为 true 时首次写入即开始流式输出
+	alwaysStream bool
+}
+
+func (w *signingResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader || w.hijacked {
+		return
+	}
+	if status >= 100 && status < 200 {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+	w.wroteHeader = true
+}
+
+func (w *signingResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.hash.Write(p)
+	if !w.streaming && w.alwaysStream {
+		if err := w.startStreaming(); err != nil {
+			return 0, err
+		}
+	}
+	if w.streaming {
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf.Write(p)
+	if w.buf.Len() > maxBufferedResponseSize {
+		if err := w.startStreaming(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *signingResponseWriter) Flush() {
+	if w.hijacked {
+		return
+	}
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.streaming {
+		if err := w.startStreaming(); err != nil {
+			return
+		}
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *signingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *signingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *signingResponseWriter) writeIdentityHeaders() {
+	w.timestamp = strconv.FormatInt(time.Now().UnixMilli(), 10)
+	header := w.ResponseWriter.Header()
+	header.Set(ServiceKeyIDHeader, w.keyID)
+	header.Set(ServiceTimestampHeader, w.timestamp)
+	header.Set(ServiceNonceHeader, w.request.Header.Get("X-Nonce"))
+}
+
+func (w *signingResponseWriter) startStreaming() error {
+	w.streaming = true
+	w.writeIdentityHeaders()
+	header := w.ResponseWriter.Header()
+	header.Del("Content-Length")
+	header.Add("Trailer", ServiceContentSHA256Header)
+	header.Add("Trailer", ServiceSignatureHeader)
+	w.ResponseWriter.WriteHeader(w.status)
+
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+func (w *signingResponseWriter) finish() {
+	if w.hijacked {
+		return
+	}
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.streaming {
+		w.writeIdentityHeaders()
+	}
+
+	bodyHash := hex.EncodeToString(w.hash.Sum(nil))
+	message := ResponseSignatureMessage(w.timestamp, w.request.Header.Get("X-Nonce"), w.sessionID, w.request.Method, w.request.URL.EscapedPath(), w.status, bodyHash)
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(w.key, []byte(message)))
+
+	// 流式输出时这两个头已声明为 trailer，在响应体之后发送
+	header := w.ResponseWriter.Header()
+	header.Set(ServiceContentSHA256Header, bodyHash)
+	header.Set(ServiceSignatureHeader, signature)
+	if w.streaming {
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+}
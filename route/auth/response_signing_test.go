@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestSignResponses(t *testing.T) {
+	km := GetKeyManager()
+	previous := km.serviceKeyPath
+	t.Cleanup(func() {
+		km.serviceKeyPath = previous
+		km.serviceKey = nil
+	})
+	km.serviceKeyPath = filepath.Join(t.TempDir(), "service_key.pem")
+	serviceKey, err := km.loadServiceKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := serviceKey.Public().(ed25519.PublicKey)
+
+	tests := []struct {
+		name          string
+		path          string
+		body          []byte
+		sessionID     string
+		wantStreaming bool
+	}{
+		{name: "buffered", path: "/core/profile", body: []byte(`{"status":"success"}`)},
+		{name: "session bound", path: "/core/profile", body: []byte(`{}`), sessionID: "0123456789abcdef"},
+		{name: "large body", path: "/core/profile", body: bytes.Repeat([]byte("x"), maxBufferedResponseSize+1), wantStreaming: true},
+		{name: "event stream", path: "/core/events", body: []byte("data: {}\n\n"), wantStreaming: true},
+		{name: "controller proxy", path: "/core/controller/proxies", body: []byte(`{}`), sessionID: "fedcba9876543210", wantStreaming: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := SignResponses(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.sessionID != "" {
+					bindResponseSession(r, tt.sessionID)
+				}
+				_, _ = w.Write(tt.body)
+			}))
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			r.Header.Set("X-Nonce", "nonce-"+tt.name)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, r)
+
+			result := recorder.Result()
+			body, err := io.ReadAll(result.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(body, tt.body) {
+				t.Fatalf("body length = %d, want %d", len(body), len(tt.body))
+			}
+			if streaming := result.Header.Get("Trailer") != ""; streaming != tt.wantStreaming {
+				t.Fatalf("streaming = %v, want %v", streaming, tt.wantStreaming)
+			}
+
+			values := result.Header
+			if tt.wantStreaming {
+				values = result.Trailer
+			}
+			sum := sha256.Sum256(tt.body)
+			bodyHash := hex.EncodeToString(sum[:])
+			if got := values.Get(ServiceContentSHA256Header); got != bodyHash {
+				t.Fatalf("content hash = %q, want %q", got, bodyHash)
+			}
+			signature, err := base64.StdEncoding.DecodeString(values.Get(ServiceSignatureHeader))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			timestamp := result.Header.Get(ServiceTimestampHeader)
+			nonce := result.Header.Get(ServiceNonceHeader)
+			message := ResponseSignatureMessage(timestamp, nonce, tt.sessionID, http.MethodGet, tt.path, http.StatusOK, bodyHash)
+			if !ed25519.Verify(publicKey, []byte(message), signature) {
+				t.Fatal("response signature does not verify")
+			}
+			otherSession := ResponseSignatureMessage(timestamp, nonce, "other-session", http.MethodGet, tt.path, http.StatusOK, bodyHash)
+			if ed25519.Verify(publicKey, []byte(otherSession), signature) {
+				t.Fatal("response signature verifies for another session")
+			}
+			if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+				t.Fatalf("invalid timestamp %q", timestamp)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	rotationChallengeTTL   = time.Minute
+	DefaultRotationOverlap = 5 * time.Minute
+	MaxRotationOverlap     = 7 * 24 * time.Hour
+)
+
+var (
+	ErrRotationChallenge = errors.New("轮换挑战无效或已过期")
+	ErrKeyExists         = errors.New("公钥已注册")
+)
+
+type RotationChallenge struct {
+	Challenge string    `json:"challenge"`
+	KeyID     string    `json:"key_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type rotationChallengeStore struct {
+	mu         sync.Mutex
+	challenges map[string]RotationChallenge
+}
+
+var rotationChallenges = &rotationChallengeStore{challenges: make(map[string]RotationChallenge)}
+
+// 为指定公钥签发一次性轮换挑战
+func IssueRotationChallenge(keyID string) (RotationChallenge, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return RotationChallenge{}, fmt.Errorf("生成轮换挑战失败： %w", err)
+	}
+
+	now := time.Now().UTC()
+	challenge := RotationChallenge{
+		Challenge: base64.RawURLEncoding.EncodeToString(buf),
+		KeyID:     keyID,
+		ExpiresAt: now.Add(rotationChallengeTTL),
+	}
+
+	s := rotationChallenges
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for value, existing := range s.challenges {
+		if !now.Before(existing.ExpiresAt) {
+			delete(s.challenges, value)
+		}
+	}
+	s.challenges[challenge.Challenge] = challenge
+	return challenge, nil
+}
+
+func consumeRotationChallenge(value string, keyID string) error {
+	s := rotationChallenges
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	challenge, ok := s.challenges[value]
+	if !ok {
+		return ErrRotationChallenge
+	}
+	delete(s.challenges, value)
+	if challenge.KeyID != keyID || !time.Now().Before(challenge.ExpiresAt) {
+		return ErrRotationChallenge
+	}
+	return nil
+}
+
+// 新私钥需要对该字符串签名，以证明调用方持有新私钥
+func RotationProofMessage(challenge string, oldKeyID string, newKeyID string) string {
+	return strings.Join([]string{
+		"SPARKLE-ROTATE-V1",
+		challenge,
+		oldKeyID,
+		newKeyID,
+	}, "\n")
+}
+
+// 用新公钥替换 oldKeyID：新公钥继承旧公钥的标签、权限范围与有效期，旧公钥在 overlap 后过期
+func (km *KeyManager) RotatePublicKey(oldKeyID string, challenge string, newPubKeyBase64 string, proof string, overlap time.Duration) (PublicKeyInfo, error) {
+	if overlap < 0 || overlap > MaxRotationOverlap {
+		return PublicKeyInfo{}, fmt.Errorf("重叠期必须在 0 到 %s 之间", MaxRotationOverlap)
+	}
+
+	candidate, err := normalizePublicKeyInfo(PublicKeyInfo{PublicKey: newPubKeyBase64})
+	if err != nil {
+		return PublicKeyInfo{}, err
+	}
+	_, newPublicKey, err := parsePublicKey(candidate.PublicKey)
+	if err != nil {
+		return PublicKeyInfo{}, err
+	}
+
+	if err := consumeRotationChallenge(challenge, oldKeyID); err != nil {
+		return PublicKeyInfo{}, err
+	}
+	sig, err := base64.StdEncoding.DecodeString(proof)
+	if err != nil {
+		return PublicKeyInfo{}, fmt.Errorf("轮换证明解码失败： %w", err)
+	}
+	if !ed25519.Verify(newPublicKey, []byte(RotationProofMessage(challenge, oldKeyID, candidate.KeyID)), sig) {
+		return PublicKeyInfo{}, fmt.Errorf("轮换证明验证失败")
+	}
+
+	var rotated PublicKeyInfo
+	_, err = km.updatePublicKeys(func(keys []PublicKeyInfo, now time.Time) ([]PublicKeyInfo, bool, error) {
+		index := slices.IndexFunc(keys, func(key PublicKeyInfo) bool {
+			return key.KeyID == oldKeyID
+		})
+		if index < 0 {
+			return nil, false, ErrKeyNotFound
+		}
+		if !keys[index].Active(now) {
+			return nil, false, fmt.Errorf("当前公钥已失效")
+		}
+		if slices.ContainsFunc(keys, func(key PublicKeyInfo) bool {
+			return key.KeyID == candidate.KeyID
+		}) {
+			return nil, false, ErrKeyExists
+		}
+
+		old := keys[index]
+		rotated = candidate
+		rotated.Label = old.Label
+		rotated.Scopes = slices.Clone(old.Scopes)
+		rotated.ExpiresAt = old.ExpiresAt
+		rotated.CreatedAt = now
+
+		expiresAt := now.Add(overlap)
+		if old.ExpiresAt == nil || expiresAt.Before(*old.ExpiresAt) {
+			keys[index].ExpiresAt = &expiresAt
+		}
+		return append(keys, rotated), true, nil
+	})
+	if err != nil {
+		return PublicKeyInfo{}, err
+	}
+	RevokeKeySessions(oldKeyID)
+	return rotated, nil
+}
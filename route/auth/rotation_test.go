@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatePublicKey(t *testing.T) {
+	publicKey, newPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPublicKey := base64.StdEncoding.EncodeToString(der)
+	newKey, err := normalizePublicKeyInfo(PublicKeyInfo{PublicKey: newPublicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type rotation struct {
+		oldKeyID       string
+		challengeKeyID string
+		challenge      string
+		proofKey       ed25519.PrivateKey
+		proofKeyID     string
+		overlap        time.Duration
+	}
+	tests := []struct {
+		name    string
+		modify  func(*rotation)
+		wantErr string
+	}{
+		{name: "valid proof"},
+		{name: "proof by old key", modify: func(rot *rotation) { rot.proofKey = nil }, wantErr: "轮换证明验证失败"},
+		{name: "proof for other key", modify: func(rot *rotation) { rot.proofKeyID = "other" }, wantErr: "轮换证明验证失败"},
+		{name: "challenge for other key", modify: func(rot *rotation) { rot.challengeKeyID = newKey.KeyID }, wantErr: ErrRotationChallenge.Error()},
+		{name: "unknown challenge", modify: func(rot *rotation) { rot.challenge = "unknown" }, wantErr: ErrRotationChallenge.Error()},
+		{name: "overlap too long", modify: func(rot *rotation) { rot.overlap = MaxRotationOverlap + time.Second }, wantErr: "重叠期"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			km, oldKeyID, oldPrivateKey := newTestSigningKeyManager(t)
+			km.keys[0].Label = "laptop"
+			km.keys[0].Scopes = []string{ScopeCoreRead}
+
+			rot := rotation{oldKeyID: oldKeyID, challengeKeyID: oldKeyID, proofKey: newPrivateKey, proofKeyID: newKey.KeyID, overlap: time.Minute}
+			if tt.modify != nil {
+				tt.modify(&rot)
+			}
+			if rot.proofKey == nil {
+				rot.proofKey = oldPrivateKey
+			}
+			if rot.challenge == "" {
+				challenge, err := IssueRotationChallenge(rot.challengeKeyID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				rot.challenge = challenge.Challenge
+			}
+			proof := ed25519.Sign(rot.proofKey, []byte(RotationProofMessage(rot.challenge, rot.oldKeyID, rot.proofKeyID)))
+
+			rotated, err := km.RotatePublicKey(rot.oldKeyID, rot.challenge, newPublicKey, base64.StdEncoding.EncodeToString(proof), rot.overlap)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RotatePublicKey() error = %v, want %q", err, tt.wantErr)
+				}
+				if len(km.keys) != 1 || km.keys[0].ExpiresAt != nil {
+					t.Fatal("rejected rotation changed the key ring")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RotatePublicKey() error = %v", err)
+			}
+
+			if rotated.KeyID != newKey.KeyID || rotated.Label != "laptop" || len(rotated.Scopes) != 1 || rotated.Scopes[0] != ScopeCoreRead {
+				t.Fatalf("rotated key = %+v", rotated)
+			}
+			old, _ := km.PublicKey(oldKeyID)
+			if old.ExpiresAt == nil || old.ExpiresAt.After(time.Now().Add(rot.overlap)) {
+				t.Fatalf("old key expires at %v, want within %s", old.ExpiresAt, rot.overlap)
+			}
+			if _, err := km.RotatePublicKey(oldKeyID, rot.challenge, newPublicKey, base64.StdEncoding.EncodeToString(proof), rot.overlap); !errors.Is(err, ErrRotationChallenge) {
+				t.Fatalf("reused challenge error = %v, want %v", err, ErrRotationChallenge)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/UruhaLushia/sparkle-service/route/httphelper"
+)
+
+const (
+	ScopeCoreRead        = "core:read"
+	ScopeCoreControl     = "core:control"
+	ScopeCoreProfile     = "core:profile"
+	ScopeSysproxyWrite   = "sysproxy:write"
+	ScopeDNSWrite        = "dns:write"
+	ScopeServiceControl  = "service:control"
+	ScopeControllerProxy = "controller:proxy"
+	ScopeAuthAdmin       = "auth:admin"
+	ScopeAuditRead       = "audit:read"
+)
+
+var allScopes = []string{
+	ScopeCoreRead,
+	ScopeCoreControl,
+	ScopeCoreProfile,
+	ScopeSysproxyWrite,
+	ScopeDNSWrite,
+	ScopeServiceControl,
+	ScopeControllerProxy,
+	ScopeAuthAdmin,
+	ScopeAuditRead,
+}
+
+func roleScopes(role string) []string {
+	switch role {
+	case RoleAdmin:
+		return allScopes
+	case RoleOperator:
+		return slices.DeleteFunc(slices.Clone(allScopes), func(scope string) bool {
+			return scope == ScopeAuthAdmin
+		})
+	case RoleViewer:
+		return []string{ScopeCoreRead}
+	default:
+		return nil
+	}
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, nil
+	}
+
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !slices.Contains(allScopes, scope) {
+			return nil, fmt.Errorf("不支持的权限范围: %s", scope)
+		}
+		normalized = append(normalized, scope)
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized), nil
+}
+
+// 未设置 scopes 时不作限制，否则取两者交集
+func intersectScopes(granted []string, limit []string) []string {
+	if len(limit) == 0 {
+		return slices.Clone(granted)
+	}
+	return slices.DeleteFunc(slices.Clone(granted), func(scope string) bool {
+		return !slices.Contains(limit, scope)
+	})
+}
+
+// 授权主体的有效权限范围：角色对应的权限范围，再由主体自身的 scopes 收窄
+func principalScopes(principal AuthorizedPrincipal) []string {
+	return intersectScopes(roleScopes(principal.Role), principal.Scopes)
+}
+
+// 校验请求是否具备全部权限范围，缺少时返回带权限范围名称的 403 错误
+func CheckScope(r *http.Request, scopes ...string) error {
+	granted := RequestIdentityFrom(r).Scopes
+	for _, scope := range scopes {
+		if !slices.Contains(granted, scope) {
+			return httphelper.Forbidden(fmt.Sprintf("缺少权限范围: %s", scope))
+		}
+	}
+	return nil
+}
+
+func RequireScope(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := CheckScope(r, scopes...); err != nil {
+				httphelper.SendError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/UruhaLushia/sparkle-service/route/httphelper"
+)
+
+func TestPrincipalScopes(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal AuthorizedPrincipal
+		keyScopes []string
+		want      []string
+	}{
+		{name: "admin", principal: AuthorizedPrincipal{Role: RoleAdmin}, want: allScopes},
+		{name: "operator has no auth admin", principal: AuthorizedPrincipal{Role: RoleOperator}, want: slices.DeleteFunc(slices.Clone(allScopes), func(scope string) bool { return scope == ScopeAuthAdmin })},
+		{name: "viewer", principal: AuthorizedPrincipal{Role: RoleViewer}, want: []string{ScopeCoreRead}},
+		{name: "unknown role", principal: AuthorizedPrincipal{Role: "guest"}, want: nil},
+		{name: "principal narrows role", principal: AuthorizedPrincipal{Role: RoleOperator, Scopes: []string{ScopeCoreRead, ScopeCoreControl}}, want: []string{ScopeCoreRead, ScopeCoreControl}},
+		{name: "principal cannot widen role", principal: AuthorizedPrincipal{Role: RoleViewer, Scopes: []string{ScopeCoreRead, ScopeAuthAdmin}}, want: []string{ScopeCoreRead}},
+		{name: "key narrows principal", principal: AuthorizedPrincipal{Role: RoleAdmin}, keyScopes: []string{ScopeAuditRead}, want: []string{ScopeAuditRead}},
+		{name: "disjoint key scopes", principal: AuthorizedPrincipal{Role: RoleViewer}, keyScopes: []string{ScopeAuditRead}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := intersectScopes(principalScopes(tt.principal), tt.keyScopes)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("scopes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckScope(t *testing.T) {
+	tests := []struct {
+		name    string
+		granted []string
+		require []string
+		wantErr bool
+	}{
+		{name: "granted", granted: []string{ScopeCoreRead, ScopeCoreControl}, require: []string{ScopeCoreControl}},
+		{name: "all required", granted: []string{ScopeCoreRead, ScopeCoreControl}, require: []string{ScopeCoreControl, ScopeCoreProfile}, wantErr: true},
+		{name: "no identity", require: []string{ScopeCoreRead}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/core", nil)
+			if tt.granted != nil {
+				r = withRequestIdentity(r, "key", "", RoleOperator, tt.granted)
+			}
+
+			err := CheckScope(r, tt.require...)
+			var httpErr *httphelper.HTTPError
+			switch {
+			case !tt.wantErr && err != nil:
+				t.Fatalf("CheckScope() error = %v", err)
+			case tt.wantErr && (!errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusForbidden):
+				t.Fatalf("CheckScope() error = %v, want 403", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// 服务自身的身份公钥，客户端在 service init 时取得并用于校验响应签名
+type ServiceIdentity struct {
+	KeyID     string `json:"key_id"`
+	PublicKey string `json:"public_key"`
+}
+
+// 加载服务私钥，不存在时生成并保存，只有服务所在用户可读
+func (km *KeyManager) ServiceIdentity() (ServiceIdentity, error) {
+	key, err := km.loadServiceKey()
+	if err != nil {
+		return ServiceIdentity{}, err
+	}
+	return serviceIdentityOf(key)
+}
+
+func serviceIdentityOf(key ed25519.PrivateKey) (ServiceIdentity, error) {
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return ServiceIdentity{}, fmt.Errorf("编码服务公钥失败： %w", err)
+	}
+	publicKey := base64.StdEncoding.EncodeToString(der)
+	keyID, err := computeKeyID(publicKey)
+	if err != nil {
+		return ServiceIdentity{}, err
+	}
+	return ServiceIdentity{KeyID: keyID, PublicKey: publicKey}, nil
+}
+
+func (km *KeyManager) loadServiceKey() (ed25519.PrivateKey, error) {
+	km.serviceKeyMu.Lock()
+	defer km.serviceKeyMu.Unlock()
+	if km.serviceKey != nil {
+		return km.serviceKey, nil
+	}
+
+	key, err := readServiceKey(km.serviceKeyPath)
+	if os.IsNotExist(err) {
+		key, err = createServiceKey(km.serviceKeyPath)
+		if os.IsExist(err) {
+			// 其他进程已先一步生成
+			key, err = readServiceKey(km.serviceKeyPath)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	km.serviceKey = key
+	return key, nil
+}
+
+func readServiceKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("读取服务私钥失败： %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("服务私钥文件格式无效")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析服务私钥失败： %w", err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("服务私钥不是 Ed25519 类型")
+	}
+	return key, nil
+}
+
+func createServiceKey(path string) (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成服务私钥失败： %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("编码服务私钥失败： %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("保存服务私钥失败： %w", err)
+	}
+	defer file.Close()
+	if err := restrictServiceKeyFile(path); err != nil {
+		_ = os.Remove(path)
+		return nil, err
+	}
+	if err := pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		_ = os.Remove(path)
+		return nil, fmt.Errorf("保存服务私钥失败： %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package auth
+
+// 文件以 0600 创建，无需额外处理
+func restrictServiceKeyFile(_ string) error {
+	return nil
+}
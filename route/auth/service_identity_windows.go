@@ -0,0 +1,27 @@
+//go:build windows
+
+package auth
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// ProgramData 默认对普通用户可读，需要单独限制服务私钥文件的访问控制列表
+const serviceKeySDDL = "D:PAI(A;;FA;;;SY)(A;;FA;;;BA)"
+
+func restrictServiceKeyFile(path string) error {
+	sd, err := windows.SecurityDescriptorFromString(serviceKeySDDL)
+	if err != nil {
+		return fmt.Errorf("解析服务私钥访问控制列表失败： %w", err)
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return fmt.Errorf("读取服务私钥访问控制列表失败： %w", err)
+	}
+	if err := windows.SetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION|windows.PROTECTED_DACL_SECURITY_INFORMATION, nil, nil, dacl, nil); err != nil {
+		return fmt.Errorf("设置服务私钥访问控制列表失败： %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/UruhaLushia/sparkle-service/route/httphelper"
+)
+
+const (
+	SessionTokenHeader = "X-Session-Token"
+	DefaultSessionTTL  = 5 * time.Minute
+	MaxSessionTTL      = time.Hour
+	maxSessions        = 256
+	sessionTokenPrefix = "sps_"
+)
+
+var (
+	ErrSessionNotFound = errors.New("会话不存在")
+	ErrSessionInvalid  = errors.New("会话令牌无效或已过期")
+)
+
+type Session struct {
+	ID        string    `json:"id"`
+	KeyID     string    `json:"key_id"`
+	Principal string    `json:"principal"`
+	PID       int       `json:"pid"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type sessionStore struct {
+	mu sync.Mutex
+	// 以令牌摘要为键，内存中不保存令牌原文
+	sessions map[string]Session
+}
+
+var sessions = &sessionStore{sessions: make(map[string]Session)}
+
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *sessionStore) evictExpiredLocked(now time.Time) {
+	for hash, session := range s.sessions {
+		if !now.Before(session.ExpiresAt) {
+			delete(s.sessions, hash)
+		}
+	}
+}
+
+// 为已通过签名认证的请求签发会话令牌，令牌绑定请求方的本地身份与进程
+func IssueSession(r *http.Request, ttl time.Duration, scopes []string) (string, Session, error) {
+	identity := RequestIdentityFrom(r)
+	if identity.KeyID == "" || identity.SessionID != "" {
+		return "", Session{}, httphelper.Forbidden("只能使用签名请求签发会话")
+	}
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	if ttl > MaxSessionTTL {
+		return "", Session{}, fmt.Errorf("会话有效期不能超过 %s", MaxSessionTTL)
+	}
+
+	requested, err := normalizeScopes(scopes)
+	if err != nil {
+		return "", Session{}, err
+	}
+	granted := identity.Scopes
+	if len(requested) > 0 {
+		for _, scope := range requested {
+			if !slices.Contains(granted, scope) {
+				return "", Session{}, httphelper.Forbidden(fmt.Sprintf("缺少权限范围: %s", scope))
+			}
+		}
+		granted = requested
+	}
+	if len(granted) == 0 {
+		return "", Session{}, httphelper.Forbidden("没有可授予会话的权限范围")
+	}
+
+	pid, ok := getRequestPID(r)
+	if !ok || identity.Principal == "" {
+		return "", Session{}, fmt.Errorf("无法识别请求方进程，不能签发会话")
+	}
+
+	buf := make([]byte, 40)
+	if _, err := rand.Read(buf); err != nil {
+		return "", Session{}, fmt.Errorf("生成会话令牌失败： %w", err)
+	}
+	token := sessionTokenPrefix + base64.RawURLEncoding.EncodeToString(buf[:32])
+
+	now := time.Now().UTC()
+	session := Session{
+		ID:        hex.EncodeToString(buf[32:]),
+		KeyID:     identity.KeyID,
+		Principal: identity.Principal,
+		PID:       pid,
+		Scopes:    slices.Clone(granted),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	s := sessions
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evictExpiredLocked(now)
+	if len(s.sessions) >= maxSessions {
+		return "", Session{}, httphelper.NewError(http.StatusTooManyRequests, "有效会话过多，请稍后再试")
+	}
+	s.sessions[hashSessionToken(token)] = session
+	return token, session, nil
+}
+
+// 校验会话令牌：未过期、签发所用的公钥仍然有效，且请求来自同一本地身份与进程
+func authenticateSession(r *http.Request, km *KeyManager, token string) (Session, error) {
+	now := time.Now().UTC()
+
+	s := sessions
+	s.mu.Lock()
+	session, ok := s.sessions[hashSessionToken(token)]
+	s.mu.Unlock()
+	if !ok || !now.Before(session.ExpiresAt) {
+		return Session{}, httphelper.Unauthorized(ErrSessionInvalid.Error())
+	}
+
+	if key, ok := km.PublicKey(session.KeyID); !ok || !key.Active(now) {
+		RevokeKeySessions(session.KeyID)
+		return Session{}, httphelper.Unauthorized(ErrSessionInvalid.Error())
+	}
+
+	principalType, principalValue, ok, err := getRequestPrincipal(r)
+	if err != nil || !ok || principalType+":"+principalValue != session.Principal {
+		return Session{}, httphelper.Unauthorized("会话令牌与请求方身份不匹配")
+	}
+	if pid, ok := getRequestPID(r); !ok || pid != session.PID {
+		return Session{}, httphelper.Unauthorized("会话令牌与请求方进程不匹配")
+	}
+	return session, nil
+}
+
+func Sessions() []Session {
+	now := time.Now().UTC()
+
+	s := sessions
+	s.mu.Lock()
+	s.evictExpiredLocked(now)
+	list := make([]Session, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		list = append(list, session)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+func LookupSession(id string) (Session, bool) {
+	s := sessions
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, session := range s.sessions {
+		if session.ID == id {
+			return session, true
+		}
+	}
+	return Session{}, false
+}
+
+func RevokeSession(id string) error {
+	s := sessions
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, session := range s.sessions {
+		if session.ID == id {
+			delete(s.sessions, hash)
+			return nil
+		}
+	}
+	return ErrSessionNotFound
+}
+
+// 公钥轮换或撤销后，由其签发的会话立即失效
+func RevokeKeySessions(keyID string) {
+	s := sessions
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, session := range s.sessions {
+		if session.KeyID == keyID {
+			delete(s.sessions, hash)
+		}
+	}
+}
+
+// 拒绝会话令牌认证的请求，用于签发会话、轮换公钥等需要私钥签名的操作
+func RequireSignedRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if RequestIdentityFrom(r).SessionID != "" {
+			httphelper.SendError(w, httphelper.Forbidden("该接口不接受会话令牌，请使用签名请求"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
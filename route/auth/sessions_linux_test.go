@@ -0,0 +1,152 @@
+//go:build linux
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UruhaLushia/sparkle-service/route/httphelper"
+	"github.com/UruhaLushia/sparkle-service/route/pipectx"
+)
+
+// 经真实的 Unix socket 发起请求，使处理器取得本进程的对端信息
+func serveUnixRequest(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	addr := filepath.Join(t.TempDir(), "test.sock")
+	listener, err := net.Listen("unix", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: handler}
+	pipectx.ConfigureServer(server)
+	go server.Serve(listener)
+	defer server.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", addr)
+		},
+	}}
+	resp, err := client.Get("http://unix/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestAuthenticateSession(t *testing.T) {
+	km, keyID, _ := newTestSigningKeyManager(t)
+	principal := "uid:" + strconv.Itoa(os.Geteuid())
+	now := time.Now().UTC()
+
+	tests := []struct {
+		name        string
+		session     Session
+		wantErr     string
+		wantRevoked bool
+	}{
+		{name: "same process", session: Session{KeyID: keyID, Principal: principal, PID: os.Getpid()}},
+		{name: "other process", session: Session{KeyID: keyID, Principal: principal, PID: os.Getpid() + 1}, wantErr: "进程不匹配"},
+		{name: "other principal", session: Session{KeyID: keyID, Principal: "uid:4294967294", PID: os.Getpid()}, wantErr: "身份不匹配"},
+		{name: "expired", session: Session{KeyID: keyID, Principal: principal, PID: os.Getpid(), ExpiresAt: now.Add(-time.Second)}, wantErr: ErrSessionInvalid.Error()},
+		{name: "unknown key", session: Session{KeyID: strings.Repeat("0", 64), Principal: principal, PID: os.Getpid()}, wantErr: ErrSessionInvalid.Error(), wantRevoked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := tt.session
+			session.ID = strings.ReplaceAll(tt.name, " ", "-")
+			if session.ExpiresAt.IsZero() {
+				session.ExpiresAt = now.Add(time.Minute)
+			}
+			token := sessionTokenPrefix + session.ID
+			sessions.mu.Lock()
+			sessions.sessions[hashSessionToken(token)] = session
+			sessions.mu.Unlock()
+			t.Cleanup(func() { _ = RevokeSession(session.ID) })
+
+			var err error
+			serveUnixRequest(t, func(w http.ResponseWriter, r *http.Request) {
+				_, err = authenticateSession(r, km, token)
+			})
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("authenticateSession() error = %v", err)
+				}
+				return
+			}
+			var httpErr *httphelper.HTTPError
+			if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusUnauthorized || !strings.Contains(httpErr.Message, tt.wantErr) {
+				t.Fatalf("authenticateSession() error = %v, want 401 %q", err, tt.wantErr)
+			}
+			if _, ok := LookupSession(session.ID); ok == tt.wantRevoked {
+				t.Fatalf("session still registered = %v, want %v", ok, !tt.wantRevoked)
+			}
+		})
+	}
+}
+
+func TestIssueSession(t *testing.T) {
+	granted := []string{ScopeCoreControl, ScopeCoreRead}
+
+	tests := []struct {
+		name       string
+		sessionID  string
+		scopes     []string
+		ttl        time.Duration
+		wantScopes []string
+		wantStatus int
+	}{
+		{name: "inherits scopes", wantScopes: granted},
+		{name: "narrows scopes", scopes: []string{ScopeCoreRead}, wantScopes: []string{ScopeCoreRead}},
+		{name: "cannot widen scopes", scopes: []string{ScopeAuthAdmin}, wantStatus: http.StatusForbidden},
+		{name: "session cannot issue session", sessionID: "parent", wantStatus: http.StatusForbidden},
+		{name: "ttl too long", ttl: MaxSessionTTL + time.Second, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var session Session
+			var err error
+			serveUnixRequest(t, func(w http.ResponseWriter, r *http.Request) {
+				r = withRequestIdentity(r, strings.Repeat("a", 64), tt.sessionID, RoleOperator, granted)
+				_, session, err = IssueSession(r, tt.ttl, tt.scopes)
+			})
+
+			if tt.wantStatus != 0 {
+				status := http.StatusInternalServerError
+				var httpErr *httphelper.HTTPError
+				if errors.As(err, &httpErr) {
+					status = httpErr.StatusCode
+				}
+				if err == nil || status != tt.wantStatus {
+					t.Fatalf("IssueSession() error = %v, want status %d", err, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = RevokeSession(session.ID) })
+			if session.PID != os.Getpid() || session.Principal != "uid:"+strconv.Itoa(os.Geteuid()) {
+				t.Fatalf("session bound to %s pid %d", session.Principal, session.PID)
+			}
+			if strings.Join(session.Scopes, ",") != strings.Join(tt.wantScopes, ",") {
+				t.Fatalf("scopes = %v, want %v", session.Scopes, tt.wantScopes)
+			}
+			if got := session.ExpiresAt.Sub(session.CreatedAt); got != DefaultSessionTTL {
+				t.Fatalf("ttl = %s, want %s", got, DefaultSessionTTL)
+			}
+		})
+	}
+}
@@ -4,6 +4,17 @@ import routeauth "github.com/UruhaLushia/sparkle-service/route/auth"
 
 type AuthorizedPrincipal = routeauth.AuthorizedPrincipal
 type KeyManager = routeauth.KeyManager
+type KeyOption = routeauth.KeyOption
+type PublicKeyInfo = routeauth.PublicKeyInfo
+type ServiceIdentity = routeauth.ServiceIdentity
+
+const DefaultPairingTTL = routeauth.DefaultPairingTTL
+
+var (
+	WithKeyLabel  = routeauth.WithKeyLabel
+	WithKeyScopes = routeauth.WithKeyScopes
+	WithKeyExpiry = routeauth.WithKeyExpiry
+)
 
 func GetKeyManager() *KeyManager {
 	return routeauth.GetKeyManager()
@@ -0,0 +1,83 @@
+package authapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/UruhaLushia/sparkle-service/route/auth"
+	"github.com/UruhaLushia/sparkle-service/route/httphelper"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func Router() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(httphelper.RequestLogger)
+
+	r.Group(func(r chi.Router) {
+		r.Use(auth.RequireSignedRequest)
+		// 轮换只作用于签名请求的公钥本身，新公钥继承其权限范围，因此不需要额外的权限范围
+		r.Post("/rotate/challenge", authRotateChallenge)
+		r.Post("/rotate", authRotate)
+		// 会话的权限范围不超过签发请求本身的权限范围
+		r.Post("/session", authIssueSession)
+	})
+	r.Delete("/sessions/{id}", authRevokeSession)
+
+	r.Group(func(r chi.Router) {
+		r.Use(auth.RequireScope(auth.ScopeAuthAdmin))
+		r.Get("/principals", authPrincipals)
+		r.Post("/principals", authAddPrincipal)
+		r.Delete("/principals/{type}/{value}", authRemovePrincipal)
+		r.Get("/keys", authKeys)
+		r.Post("/keys", authAddKey)
+		r.Delete("/keys/{id}", authRevokeKey)
+		r.Get("/sessions", authSessions)
+	})
+
+	return r
+}
+
+func authPrincipals(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, auth.GetKeyManager().AuthorizedPrincipals())
+}
+
+func authAddPrincipal(w http.ResponseWriter, r *http.Request) {
+	var principal auth.AuthorizedPrincipal
+	if err := httphelper.DecodeRequest(r, &principal); err != nil {
+		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+		return
+	}
+
+	changed, err := auth.GetKeyManager().AddAuthorizedPrincipal(principal)
+	if err != nil {
+		sendPrincipalError(w, err)
+		return
+	}
+	if !changed {
+		httphelper.SendJSON(w, "success", "授权主体未变化")
+		return
+	}
+	httphelper.SendJSON(w, "success", "授权主体已保存")
+}
+
+func authRemovePrincipal(w http.ResponseWriter, r *http.Request) {
+	if _, err := auth.GetKeyManager().RemoveAuthorizedPrincipal(chi.URLParam(r, "type"), chi.URLParam(r, "value")); err != nil {
+		sendPrincipalError(w, err)
+		return
+	}
+	httphelper.SendJSON(w, "success", "授权主体已删除")
+}
+
+func sendPrincipalError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrPrincipalNotFound):
+		httphelper.SendError(w, httphelper.NewError(http.StatusNotFound, err.Error()))
+	case errors.Is(err, auth.ErrLastAdminPrincipal):
+		httphelper.SendError(w, httphelper.Conflict(err.Error()))
+	default:
+		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+	}
+}
@@ -0,0 +1,77 @@
+package authapi
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/UruhaLushia/sparkle-service/route/auth"
+	"github.com/UruhaLushia/sparkle-service/route/httphelper"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type addKeyRequest struct {
+	PublicKey string     `json:"public_key"`
+	Label     *string    `json:"label,omitempty"`
+	Scopes    *[]string  `json:"scopes,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func authKeys(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, auth.GetKeyManager().PublicKeys())
+}
+
+func authAddKey(w http.ResponseWriter, r *http.Request) {
+	var req addKeyRequest
+	if err := httphelper.DecodeRequest(r, &req); err != nil {
+		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+		return
+	}
+
+	keyID, err := auth.PublicKeyID(req.PublicKey)
+	if err != nil {
+		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+		return
+	}
+
+	// 只修改请求中给出的字段，重复提交不会清除已有的标签、权限范围与有效期
+	var options []auth.KeyOption
+	if req.Label != nil {
+		options = append(options, auth.WithKeyLabel(*req.Label))
+	}
+	if req.Scopes != nil {
+		options = append(options, auth.WithKeyScopes(*req.Scopes...))
+	}
+	if req.ExpiresAt != nil {
+		options = append(options, auth.WithKeyExpiry(*req.ExpiresAt))
+	}
+
+	km := auth.GetKeyManager()
+	if _, err := km.SetPublicKey(req.PublicKey, options...); err != nil {
+		sendKeyError(w, err)
+		return
+	}
+	key, _ := km.PublicKey(keyID)
+	render.JSON(w, r, key)
+}
+
+func authRevokeKey(w http.ResponseWriter, r *http.Request) {
+	if _, err := auth.GetKeyManager().RevokePublicKey(chi.URLParam(r, "id")); err != nil {
+		sendKeyError(w, err)
+		return
+	}
+	httphelper.SendJSON(w, "success", "公钥已撤销")
+}
+
+func sendKeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrKeyNotFound):
+		httphelper.SendError(w, httphelper.NewError(http.StatusNotFound, err.Error()))
+	case errors.Is(err, auth.ErrKeyRevoked), errors.Is(err, auth.ErrLastActiveKey), errors.Is(err, auth.ErrKeyExpiryLocked):
+		httphelper.SendError(w, httphelper.Conflict(err.Error()))
+	default:
+		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+	}
+}
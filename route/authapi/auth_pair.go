@@ -0,0 +1,46 @@
+package authapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/UruhaLushia/sparkle-service/log"
+	"github.com/UruhaLushia/sparkle-service/route/auth"
+	"github.com/UruhaLushia/sparkle-service/route/httphelper"
+
+	"github.com/go-chi/render"
+)
+
+type pairRequest struct {
+	Code      string `json:"code"`
+	PublicKey string `json:"public_key"`
+}
+
+func Pair(w http.ResponseWriter, r *http.Request) {
+	var req pairRequest
+	if err := httphelper.DecodeRequest(r, &req); err != nil {
+		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+		return
+	}
+
+	result, err := auth.GetKeyManager().Pair(r, req.Code, req.PublicKey)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrPairingClosed),
+			errors.Is(err, auth.ErrPairingPrincipalExists),
+			errors.Is(err, auth.ErrPairingKeyExists),
+			errors.Is(err, auth.ErrLastAdminPrincipal),
+			errors.Is(err, auth.ErrSocketPrincipals):
+			httphelper.SendError(w, httphelper.Conflict(err.Error()))
+		case errors.Is(err, auth.ErrPairingLocked):
+			httphelper.SendError(w, httphelper.NewError(http.StatusTooManyRequests, err.Error()))
+		case errors.Is(err, auth.ErrPairingCode):
+			httphelper.SendError(w, httphelper.Forbidden(err.Error()))
+		default:
+			sendKeyError(w, err)
+		}
+		return
+	}
+	log.Printf("配对成功：%s:%s（%s），公钥 %s", result.Principal.Type, result.Principal.Value, result.Principal.Role, result.KeyID)
+	render.JSON(w, r, result)
+}
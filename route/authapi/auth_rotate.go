@@ -0,0 +1,55 @@
+package authapi
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/UruhaLushia/sparkle-service/route/auth"
+	"github.com/UruhaLushia/sparkle-service/route/httphelper"
+
+	"github.com/go-chi/render"
+)
+
+type rotateRequest struct {
+	Challenge      string `json:"challenge"`
+	PublicKey      string `json:"public_key"`
+	Proof          string `json:"proof"`
+	OverlapSeconds *int   `json:"overlap_seconds,omitempty"`
+}
+
+func authRotateChallenge(w http.ResponseWriter, r *http.Request) {
+	challenge, err := auth.IssueRotationChallenge(auth.RequestIdentityFrom(r).KeyID)
+	if err != nil {
+		httphelper.SendError(w, err)
+		return
+	}
+	render.JSON(w, r, challenge)
+}
+
+func authRotate(w http.ResponseWriter, r *http.Request) {
+	var req rotateRequest
+	if err := httphelper.DecodeRequest(r, &req); err != nil {
+		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+		return
+	}
+
+	overlap := auth.DefaultRotationOverlap
+	if req.OverlapSeconds != nil {
+		overlap = time.Duration(*req.OverlapSeconds) * time.Second
+	}
+
+	key, err := auth.GetKeyManager().RotatePublicKey(auth.RequestIdentityFrom(r).KeyID, req.Challenge, req.PublicKey, req.Proof, overlap)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrRotationChallenge):
+			httphelper.SendError(w, httphelper.Unauthorized(err.Error()))
+		case errors.Is(err, auth.ErrKeyExists):
+			httphelper.SendError(w, httphelper.Conflict(err.Error()))
+		default:
+			sendKeyError(w, err)
+		}
+		return
+	}
+	render.JSON(w, r, key)
+}
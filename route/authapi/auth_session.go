@@ -0,0 +1,68 @@
+package authapi
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/UruhaLushia/sparkle-service/route/auth"
+	"github.com/UruhaLushia/sparkle-service/route/httphelper"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type sessionRequest struct {
+	TTLSeconds int      `json:"ttl_seconds,omitempty"`
+	Scopes     []string `json:"scopes,omitempty"`
+}
+
+type sessionResponse struct {
+	Token   string       `json:"token"`
+	Session auth.Session `json:"session"`
+}
+
+func authIssueSession(w http.ResponseWriter, r *http.Request) {
+	var req sessionRequest
+	if _, err := httphelper.DecodeOptionalRequest(r, &req); err != nil {
+		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+		return
+	}
+
+	token, session, err := auth.IssueSession(r, time.Duration(req.TTLSeconds)*time.Second, req.Scopes)
+	if err != nil {
+		var httpErr *httphelper.HTTPError
+		if !errors.As(err, &httpErr) {
+			err = httphelper.BadRequest(err.Error())
+		}
+		httphelper.SendError(w, err)
+		return
+	}
+	render.JSON(w, r, sessionResponse{Token: token, Session: session})
+}
+
+func authSessions(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, auth.Sessions())
+}
+
+// 会话可以由签发它的公钥或具备 auth:admin 的调用方撤销
+func authRevokeSession(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	session, ok := auth.LookupSession(id)
+	if !ok {
+		httphelper.SendError(w, httphelper.NewError(http.StatusNotFound, auth.ErrSessionNotFound.Error()))
+		return
+	}
+	identity := auth.RequestIdentityFrom(r)
+	if session.KeyID != identity.KeyID && !slices.Contains(identity.Scopes, auth.ScopeAuthAdmin) {
+		httphelper.SendError(w, httphelper.Forbidden("缺少权限范围: "+auth.ScopeAuthAdmin))
+		return
+	}
+
+	if err := auth.RevokeSession(id); err != nil {
+		httphelper.SendError(w, httphelper.NewError(http.StatusNotFound, err.Error()))
+		return
+	}
+	httphelper.SendJSON(w, "success", "会话已撤销")
+}
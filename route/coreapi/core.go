@@ -1,7 +1,9 @@
 package coreapi
 
 import (
+	"errors"
 	corepkg "github.com/UruhaLushia/sparkle-service/core"
+	"github.com/UruhaLushia/sparkle-service/log"
 	"github.com/UruhaLushia/sparkle-service/route/auth"
 	"github.com/UruhaLushia/sparkle-service/route/httphelper"
 	"net/http"
@@ -16,42 +18,89 @@ var (
 	isInit atomic.Bool
 )
 
-func Router() http.Handler {
+func initManager() {
 	if !isInit.Load() {
 		cm = corepkg.NewCoreManager(corepkg.WithTrafficMonitorPipeSDDL(trafficMonitorPipeSDDL()))
 		isInit.Store(true)
 	}
+}
+
+func Router() http.Handler {
+	initManager()
 
 	r := chi.NewRouter()
 
 	r.Use(httphelper.RequestLogger)
 
-	r.Get("/", coreStatus)
-	r.Get("/events", coreEvents)
-	r.HandleFunc("/controller", coreControllerProxy)
-	r.HandleFunc("/controller/*", coreControllerProxy)
-	r.Get("/profile", coreProfile)
-	r.Post("/profile", coreSaveProfile)
-	r.Patch("/profile", corePatchProfile)
-	r.Post("/start", coreStart)
-	r.Post("/stop", coreStop)
-	r.Post("/restart", coreRestart)
+	r.Group(func(r chi.Router) {
+		r.Use(auth.RequireScope(auth.ScopeCoreRead))
+		r.Get("/", coreStatus)
+		r.Get("/events", coreEvents)
+		r.Get("/profile", coreProfile)
+		r.Get("/profile/history", coreProfileHistory)
+		r.Get("/profile/history/{id}/diff", coreProfileHistoryDiff)
+		r.Get("/profiles", coreProfiles)
+		r.Get("/profiles/{name}", coreNamedProfile)
+		r.Get("/launch-plan", coreLaunchPlan)
+		r.Get("/binary/trust", coreBinaryTrust)
+		r.Get("/secrets", coreSecrets)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(auth.RequireScope(auth.ScopeControllerProxy))
+		r.HandleFunc("/controller", coreControllerProxy)
+		r.HandleFunc("/controller/*", coreControllerProxy)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(auth.RequireScope(auth.ScopeCoreProfile))
+		r.Post("/profile", coreSaveProfile)
+		r.Patch("/profile", corePatchProfile)
+		r.Post("/profile/rollback/{id}", coreProfileRollback)
+		r.Put("/profiles/{name}", coreSaveNamedProfile)
+		r.Delete("/profiles/{name}", coreDeleteNamedProfile)
+		r.Post("/profiles/{name}/activate", coreActivateProfile)
+		r.Put("/secrets/{name}", coreSetSecret)
+		r.Delete("/secrets/{name}", coreDeleteSecret)
+		r.With(auth.RequireScope(auth.ScopeCoreControl)).Patch("/scheduling", coreScheduling)
+	})
+
+	// 携带启动配置的启动与重启还需要 core:profile，在 selectLaunchProfile 中校验
+	r.Group(func(r chi.Router) {
+		r.Use(auth.RequireScope(auth.ScopeCoreControl))
+		r.Post("/start", coreStart)
+		r.Post("/stop", coreStop)
+		r.Post("/restart", coreRestart)
+	})
 
 	return r
 }
 
 func trafficMonitorPipeSDDL() string {
-	if sid, ok := auth.GetKeyManager().GetAuthorizedSID(); ok {
-		return "D:PAI(A;OICI;GWGR;;;" + sid + ")(A;OICI;GWGR;;;SY)"
+	return auth.AuthorizedPipeSDDL()
+}
+
+func Restore() {
+	initManager()
+	reattached, err := cm.ReattachDetachedCore()
+	if err != nil {
+		log.Printf("重新接管核心进程失败：%v", err)
+	}
+	if reattached {
+		return
 	}
-	return ""
+	go func() {
+		if err := cm.RestoreDesiredState(); err != nil {
+			log.Printf("恢复核心运行状态失败：%v", err)
+		}
+	}()
 }
 
 func Stop() error {
 	if !isInit.Load() || cm == nil {
 		return nil
 	}
-	return cm.StopCore()
+	return cm.ShutdownCore()
 }
 
 func coreStatus(w http.ResponseWriter, r *http.Request) {
@@ -69,6 +118,7 @@ func coreProfile(w http.ResponseWriter, r *http.Request) {
 		httphelper.SendError(w, err)
 		return
 	}
+	w.Header().Set("ETag", corepkg.LaunchProfileETag(profile))
 	render.JSON(w, r, profile)
 }
 
@@ -79,8 +129,8 @@ func coreSaveProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := corepkg.SaveLaunchProfile(profile); err != nil {
-		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+	if err := corepkg.SaveLaunchProfile(profile, profileSaveOptions(r)...); err != nil {
+		sendProfileError(w, r, err)
 		return
 	}
 	normalized, err := corepkg.LoadLaunchProfile()
@@ -90,48 +140,57 @@ func coreSaveProfile(w http.ResponseWriter, r *http.Request) {
 	}
 	cm.ApplyLaunchProfile(normalized, coreLaunchOptions(r)...)
 
+	w.Header().Set("ETag", corepkg.LaunchProfileETag(normalized))
 	httphelper.SendJSON(w, "success", "核心启动配置已更新")
 }
 
 func corePatchProfile(w http.ResponseWriter, r *http.Request) {
-	var patch corepkg.LaunchProfilePatch
-	if err := httphelper.DecodeRequest(r, &patch); err != nil {
-		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+	patch, err := httphelper.ReadRequestBody(r)
+	if err != nil {
+		httphelper.SendError(w, err)
 		return
 	}
 
-	profile, err := corepkg.PatchLaunchProfile(patch)
+	profile, err := corepkg.PatchLaunchProfile(patch, profileSaveOptions(r)...)
 	if err != nil {
-		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+		sendProfileError(w, r, err)
 		return
 	}
 	cm.ApplyLaunchProfile(profile, coreLaunchOptions(r)...)
 
+	w.Header().Set("ETag", corepkg.LaunchProfileETag(profile))
 	httphelper.SendJSON(w, "success", "核心启动配置已更新")
 }
 
+func coreLaunchPlan(w http.ResponseWriter, r *http.Request) {
+	plan, err := cm.LaunchPlan(nil, coreLaunchOptions(r)...)
+	if err != nil {
+		sendCoreError(w, r, err)
+		return
+	}
+	render.JSON(w, r, plan)
+}
+
 func coreStart(w http.ResponseWriter, r *http.Request) {
 	profile, hasProfile, err := decodeOptionalLaunchProfile(r)
 	if err != nil {
 		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
 		return
 	}
-	if hasProfile {
-		if err := corepkg.SaveLaunchProfile(*profile); err != nil {
-			httphelper.SendError(w, httphelper.BadRequest(err.Error()))
-			return
-		}
+	if !selectLaunchProfile(w, r, profile, hasProfile) {
+		return
 	}
-
 	if err := cm.StartCoreWithProfile(profile, coreLaunchOptions(r)...); err != nil {
-		httphelper.SendError(w, err)
+		sendCoreError(w, r, err)
 		return
 	}
+	rememberCoreState(r, true)
 
 	sendCoreReady(w, r, "核心启动成功")
 }
 
 func coreStop(w http.ResponseWriter, r *http.Request) {
+	rememberCoreState(r, false)
 	if err := cm.StopCore(); err != nil {
 		httphelper.SendError(w, err)
 		return
@@ -145,20 +204,73 @@ func coreRestart(w http.ResponseWriter, r *http.Request) {
 		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
 		return
 	}
-	if hasProfile {
-		if err := corepkg.SaveLaunchProfile(*profile); err != nil {
-			httphelper.SendError(w, httphelper.BadRequest(err.Error()))
-			return
-		}
+	if r.URL.Query().Has("canary") {
+		coreCanaryRestart(w, r, profile, hasProfile)
+		return
+	}
+	if !selectLaunchProfile(w, r, profile, hasProfile) {
+		return
 	}
-
 	if err := cm.RestartCoreWithProfile(profile, coreLaunchOptions(r)...); err != nil {
-		httphelper.SendError(w, err)
+		sendCoreError(w, r, err)
 		return
 	}
+	rememberCoreState(r, true)
 	sendCoreReady(w, r, "核心重启成功")
 }
 
+func coreBinaryTrust(w http.ResponseWriter, r *http.Request) {
+	decision, err := corepkg.CheckCoreBinaryTrust()
+	if err != nil {
+		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+		return
+	}
+	render.JSON(w, r, decision)
+}
+
+// 启动或重启成功后才记录期望运行，避免失败的启动请求在服务重启后被反复重试
+func rememberCoreState(r *http.Request, running bool) {
+	if err := corepkg.SaveDesiredCoreState(running, coreLaunchOptions(r)...); err != nil {
+		log.Printf("保存核心运行状态失败：%v", err)
+	}
+}
+
+func sendCoreError(w http.ResponseWriter, r *http.Request, err error) {
+	if sendLaunchPolicyError(w, r, err) {
+		return
+	}
+	if errors.Is(err, corepkg.ErrUntrustedCoreBinary) {
+		httphelper.SendError(w, httphelper.Forbidden(err.Error()))
+		return
+	}
+	httphelper.SendError(w, err)
+}
+
+func sendProfileError(w http.ResponseWriter, r *http.Request, err error) {
+	if sendLaunchPolicyError(w, r, err) {
+		return
+	}
+	if errors.Is(err, corepkg.ErrLaunchProfilePreconditionFailed) {
+		httphelper.SendError(w, httphelper.NewError(http.StatusPreconditionFailed, err.Error()))
+		return
+	}
+	httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+}
+
+func sendLaunchPolicyError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var policyErr *corepkg.LaunchPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+	render.Status(r, http.StatusForbidden)
+	render.JSON(w, r, map[string]any{
+		"status":     "error",
+		"message":    policyErr.Error(),
+		"violations": policyErr.Violations,
+	})
+	return true
+}
+
 func decodeOptionalLaunchProfile(r *http.Request) (*corepkg.LaunchProfile, bool, error) {
 	var profile corepkg.LaunchProfile
 	ok, err := httphelper.DecodeOptionalRequest(r, &profile)
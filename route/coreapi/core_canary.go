@@ -0,0 +1,71 @@
+package coreapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	corepkg "github.com/UruhaLushia/sparkle-service/core"
+	"github.com/UruhaLushia/sparkle-service/route/auth"
+	"github.com/UruhaLushia/sparkle-service/route/httphelper"
+
+	"github.com/go-chi/render"
+)
+
+func parseCanaryWindow(r *http.Request) (time.Duration, error) {
+	value := r.URL.Query().Get("canary")
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 || time.Duration(seconds)*time.Second > corepkg.MaxCanaryWindow {
+		return 0, fmt.Errorf("canary 必须是 1 到 %d 之间的秒数", int(corepkg.MaxCanaryWindow/time.Second))
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+func coreCanaryRestart(w http.ResponseWriter, r *http.Request, profile *corepkg.LaunchProfile, hasProfile bool) {
+	window, err := parseCanaryWindow(r)
+	if err != nil {
+		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+		return
+	}
+
+	identity := auth.RequestIdentityFrom(r)
+	previous, err := corepkg.SnapshotLaunchProfile(corepkg.WithProfileAuthor(identity.Principal, identity.KeyID))
+	if err != nil {
+		if errors.Is(err, corepkg.ErrLaunchProfileNotFound) {
+			httphelper.SendError(w, httphelper.Conflict("灰度重启需要已保存的启动配置作为回滚目标："+err.Error()))
+			return
+		}
+		httphelper.SendError(w, err)
+		return
+	}
+	canary, err := cm.BeginCanary(previous, window)
+	if err != nil {
+		if errors.Is(err, corepkg.ErrCanaryInProgress) {
+			httphelper.SendError(w, httphelper.Conflict(err.Error()))
+			return
+		}
+		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+		return
+	}
+	if !selectLaunchProfile(w, r, profile, hasProfile) {
+		canary.Abort()
+		return
+	}
+	if err := canary.Restart(profile, coreLaunchOptions(r)...); err != nil {
+		sendCoreError(w, r, err)
+		return
+	}
+	rememberCoreState(r, true)
+
+	response := map[string]any{
+		"status":  "success",
+		"message": "核心已灰度重启，观察期内失败将自动回滚",
+		"canary":  map[string]any{"seconds": int(window / time.Second)},
+	}
+	if status, err := cm.GetProcessInfo(); err == nil {
+		response["core"] = status
+	}
+	render.JSON(w, r, response)
+}
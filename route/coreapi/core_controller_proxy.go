@@ -8,6 +8,8 @@ import (
 	"net/http/httputil"
 	"strings"
 
+	"github.com/UruhaLushia/sparkle-service/core/controller"
+	"github.com/UruhaLushia/sparkle-service/route/auth"
 	"github.com/UruhaLushia/sparkle-service/route/httphelper"
 )
 
@@ -26,10 +28,12 @@ func coreControllerProxy(w http.ResponseWriter, r *http.Request) {
 			req.URL.Path = targetPath
 			req.URL.RawPath = ""
 			req.Host = "mihomo.local"
+			// 会话令牌只用于本服务认证，不转发给核心
+			req.Header.Del(auth.SessionTokenHeader)
 		},
 		Transport: &http.Transport{
 			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
-				return dialCoreController(ctx, network, address)
+				return controller.Dial(ctx, network, address)
 			},
 		},
 		ErrorHandler: func(w http.ResponseWriter, _ *http.Request, err error) {
@@ -13,5 +13,5 @@ func coreLaunchOptions(r *http.Request) []corepkg.LaunchOption {
 	if !ok || !info.HasGID {
 		return nil
 	}
-	return []corepkg.LaunchOption{corepkg.WithLogFileGroup(info.GID)}
+	return []corepkg.LaunchOption{corepkg.WithLogFileOwner(info.UID, info.GID)}
 }
@@ -0,0 +1,17 @@
+//go:build windows
+
+package coreapi
+
+import (
+	corepkg "github.com/UruhaLushia/sparkle-service/core"
+	"github.com/UruhaLushia/sparkle-service/route/auth"
+	"net/http"
+)
+
+func coreLaunchOptions(r *http.Request) []corepkg.LaunchOption {
+	peer := auth.RequestPeerFrom(r)
+	if peer.Type != "sid" || peer.Value == "" {
+		return nil
+	}
+	return []corepkg.LaunchOption{corepkg.WithLogFileOwnerSID(peer.Value)}
+}
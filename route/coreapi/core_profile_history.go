@@ -0,0 +1,114 @@
+package coreapi
+
+import (
+	"errors"
+	"net/http"
+
+	corepkg "github.com/UruhaLushia/sparkle-service/core"
+	"github.com/UruhaLushia/sparkle-service/route/auth"
+	"github.com/UruhaLushia/sparkle-service/route/httphelper"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type coreRollbackRequest struct {
+	Restart  bool `json:"restart"`
+	Activate bool `json:"activate"`
+}
+
+func profileSaveOptions(r *http.Request) []corepkg.ProfileSaveOption {
+	identity := auth.RequestIdentityFrom(r)
+	return []corepkg.ProfileSaveOption{
+		corepkg.WithProfileAuthor(identity.Principal, identity.KeyID),
+		corepkg.WithProfileIfMatch(r.Header.Get("If-Match")),
+	}
+}
+
+func coreProfileHistory(w http.ResponseWriter, r *http.Request) {
+	history, err := corepkg.LaunchProfileHistory()
+	if err != nil {
+		httphelper.SendError(w, err)
+		return
+	}
+	if history == nil {
+		history = []corepkg.LaunchProfileRevision{}
+	}
+	render.JSON(w, r, history)
+}
+
+func coreProfileHistoryDiff(w http.ResponseWriter, r *http.Request) {
+	diff, err := corepkg.DiffLaunchProfileRevision(chi.URLParam(r, "id"))
+	if err != nil {
+		sendProfileHistoryError(w, err)
+		return
+	}
+	render.JSON(w, r, diff)
+}
+
+func coreProfileRollback(w http.ResponseWriter, r *http.Request) {
+	var req coreRollbackRequest
+	if err := httphelper.DecodeRequest(r, &req); err != nil {
+		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+		return
+	}
+	if req.Restart {
+		if err := auth.CheckScope(r, auth.ScopeCoreControl); err != nil {
+			httphelper.SendError(w, err)
+			return
+		}
+	}
+
+	id := chi.URLParam(r, "id")
+	if req.Restart && !req.Activate {
+		// 未显式要求切换时，重启只能作用于当前激活的配置
+		revision, err := corepkg.LaunchProfileRevisionByID(id)
+		if err != nil {
+			sendProfileHistoryError(w, err)
+			return
+		}
+		if revision.ProfileName() != corepkg.ActiveLaunchProfileName() {
+			httphelper.SendError(w, httphelper.Conflict("该版本不属于当前激活的启动配置，如需切换并重启请同时指定 activate"))
+			return
+		}
+	}
+
+	name, profile, err := corepkg.RollbackLaunchProfile(id, profileSaveOptions(r)...)
+	if err != nil {
+		if errors.Is(err, corepkg.ErrLaunchProfileRevisionNotFound) {
+			sendProfileHistoryError(w, err)
+			return
+		}
+		sendProfileError(w, r, err)
+		return
+	}
+	if req.Activate {
+		if err := corepkg.SetActiveLaunchProfile(name); err != nil {
+			sendNamedProfileError(w, err)
+			return
+		}
+	}
+
+	if !req.Restart {
+		if name == corepkg.ActiveLaunchProfileName() {
+			cm.ApplyLaunchProfile(profile, coreLaunchOptions(r)...)
+		}
+		httphelper.SendJSON(w, "success", "核心启动配置已回滚")
+		return
+	}
+
+	if err := cm.RestartCoreWithProfile(nil, coreLaunchOptions(r)...); err != nil {
+		sendCoreError(w, r, err)
+		return
+	}
+	rememberCoreState(r, true)
+	sendCoreReady(w, r, "核心启动配置已回滚并重启核心")
+}
+
+func sendProfileHistoryError(w http.ResponseWriter, err error) {
+	if errors.Is(err, corepkg.ErrLaunchProfileRevisionNotFound) {
+		httphelper.SendError(w, httphelper.NewError(http.StatusNotFound, err.Error()))
+		return
+	}
+	httphelper.SendError(w, err)
+}
@@ -0,0 +1,121 @@
+package coreapi
+
+import (
+	"errors"
+	"net/http"
+
+	corepkg "github.com/UruhaLushia/sparkle-service/core"
+	"github.com/UruhaLushia/sparkle-service/route/auth"
+	"github.com/UruhaLushia/sparkle-service/route/httphelper"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func coreProfiles(w http.ResponseWriter, r *http.Request) {
+	list, err := corepkg.ListLaunchProfiles()
+	if err != nil {
+		httphelper.SendError(w, err)
+		return
+	}
+	render.JSON(w, r, list)
+}
+
+func coreNamedProfile(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	exists, err := corepkg.NamedLaunchProfileExists(name)
+	if err != nil {
+		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+		return
+	}
+	if !exists {
+		httphelper.SendError(w, httphelper.NewError(http.StatusNotFound, "启动配置不存在"))
+		return
+	}
+
+	profile, err := corepkg.LoadNamedLaunchProfile(name)
+	if err != nil {
+		httphelper.SendError(w, err)
+		return
+	}
+	w.Header().Set("ETag", corepkg.LaunchProfileETag(profile))
+	render.JSON(w, r, profile)
+}
+
+func coreSaveNamedProfile(w http.ResponseWriter, r *http.Request) {
+	var profile corepkg.LaunchProfile
+	if err := httphelper.DecodeRequest(r, &profile); err != nil {
+		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	if err := corepkg.SaveNamedLaunchProfile(name, profile, profileSaveOptions(r)...); err != nil {
+		sendProfileError(w, r, err)
+		return
+	}
+	if name == corepkg.ActiveLaunchProfileName() {
+		if normalized, err := corepkg.LoadNamedLaunchProfile(name); err == nil {
+			cm.ApplyLaunchProfile(normalized, coreLaunchOptions(r)...)
+		}
+	}
+
+	httphelper.SendJSON(w, "success", "核心启动配置已更新")
+}
+
+func coreDeleteNamedProfile(w http.ResponseWriter, r *http.Request) {
+	if err := corepkg.DeleteNamedLaunchProfile(chi.URLParam(r, "name")); err != nil {
+		sendNamedProfileError(w, err)
+		return
+	}
+	httphelper.SendJSON(w, "success", "核心启动配置已删除")
+}
+
+func coreActivateProfile(w http.ResponseWriter, r *http.Request) {
+	if err := corepkg.SetActiveLaunchProfile(chi.URLParam(r, "name")); err != nil {
+		sendNamedProfileError(w, err)
+		return
+	}
+	httphelper.SendJSON(w, "success", "当前启动配置已切换")
+}
+
+func selectLaunchProfile(w http.ResponseWriter, r *http.Request, profile *corepkg.LaunchProfile, hasProfile bool) bool {
+	name := r.URL.Query().Get("profile")
+	if hasProfile || name != "" {
+		if err := auth.CheckScope(r, auth.ScopeCoreProfile); err != nil {
+			httphelper.SendError(w, err)
+			return false
+		}
+	}
+	if hasProfile {
+		var err error
+		if name != "" {
+			err = corepkg.SaveNamedLaunchProfile(name, *profile, profileSaveOptions(r)...)
+		} else {
+			err = corepkg.SaveLaunchProfile(*profile, profileSaveOptions(r)...)
+		}
+		if err != nil {
+			sendProfileError(w, r, err)
+			return false
+		}
+	}
+
+	if name != "" {
+		if err := corepkg.SetActiveLaunchProfile(name); err != nil {
+			sendNamedProfileError(w, err)
+			return false
+		}
+	}
+	return true
+}
+
+func sendNamedProfileError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, corepkg.ErrLaunchProfileNotFound):
+		httphelper.SendError(w, httphelper.NewError(http.StatusNotFound, err.Error()))
+	case errors.Is(err, corepkg.ErrLaunchProfileActive):
+		httphelper.SendError(w, httphelper.Conflict(err.Error()))
+	default:
+		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+	}
+}
@@ -0,0 +1,56 @@
+package coreapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	corepkg "github.com/UruhaLushia/sparkle-service/core"
+	"github.com/UruhaLushia/sparkle-service/route/httphelper"
+
+	"github.com/go-chi/render"
+)
+
+func coreScheduling(w http.ResponseWriter, r *http.Request) {
+	body, err := httphelper.ReadRequestBody(r)
+	if err != nil {
+		httphelper.SendError(w, err)
+		return
+	}
+	if !json.Valid(body) {
+		httphelper.SendError(w, httphelper.BadRequest("请求体不是有效的 JSON"))
+		return
+	}
+
+	patch, err := json.Marshal(map[string]json.RawMessage{"scheduling": body})
+	if err != nil {
+		httphelper.SendError(w, err)
+		return
+	}
+	profile, result, err := cm.PatchScheduling(patch, profileSaveOptions(r)...)
+	if err != nil {
+		if errors.Is(err, corepkg.ErrCoreNotRunning) {
+			w.Header().Set("ETag", corepkg.LaunchProfileETag(profile))
+			render.JSON(w, r, map[string]any{
+				"status":     "success",
+				"message":    "调度参数已保存，将在核心下次启动时生效",
+				"scheduling": profile.Scheduling,
+			})
+			return
+		}
+		if errors.Is(err, corepkg.ErrSchedulingNotApplied) {
+			httphelper.SendError(w, err)
+			return
+		}
+		sendProfileError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", corepkg.LaunchProfileETag(profile))
+
+	render.JSON(w, r, map[string]any{
+		"status":     "success",
+		"message":    "核心调度参数已更新",
+		"scheduling": result.Scheduling,
+		"pids":       result.PIDs,
+	})
+}
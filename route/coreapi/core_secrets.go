@@ -0,0 +1,51 @@
+package coreapi
+
+import (
+	"net/http"
+
+	corepkg "github.com/UruhaLushia/sparkle-service/core"
+	"github.com/UruhaLushia/sparkle-service/route/httphelper"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type coreSecretRequest struct {
+	Value string `json:"value"`
+}
+
+func coreSecrets(w http.ResponseWriter, r *http.Request) {
+	secrets, err := corepkg.ListSecrets()
+	if err != nil {
+		httphelper.SendError(w, err)
+		return
+	}
+	render.JSON(w, r, secrets)
+}
+
+func coreSetSecret(w http.ResponseWriter, r *http.Request) {
+	var req coreSecretRequest
+	if err := httphelper.DecodeRequest(r, &req); err != nil {
+		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+		return
+	}
+
+	if err := corepkg.SetSecret(chi.URLParam(r, "name"), req.Value); err != nil {
+		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+		return
+	}
+	httphelper.SendJSON(w, "success", "secret 已保存")
+}
+
+func coreDeleteSecret(w http.ResponseWriter, r *http.Request) {
+	deleted, err := corepkg.DeleteSecret(chi.URLParam(r, "name"))
+	if err != nil {
+		httphelper.SendError(w, httphelper.BadRequest(err.Error()))
+		return
+	}
+	if !deleted {
+		httphelper.SendError(w, httphelper.NewError(http.StatusNotFound, "secret 不存在"))
+		return
+	}
+	httphelper.SendJSON(w, "success", "secret 已删除")
+}
@@ -1,12 +1,12 @@
 package httphelper
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
-
-	"github.com/go-chi/render"
 )
 
 type Response struct {
@@ -50,6 +50,24 @@ func ServiceUnavailable(message string) error {
 	return NewError(http.StatusServiceUnavailable, message)
 }
 
+// JSON 请求体的长度上限
+const MaxJSONBodySize int64 = 1 << 20
+
+// 读取有长度上限的请求体，超过上限时返回 413
+func ReadRequestBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxJSONBodySize+1))
+	if err != nil {
+		return nil, BadRequest(fmt.Sprintf("读取请求体失败： %v", err))
+	}
+	if int64(len(body)) > MaxJSONBodySize {
+		return nil, NewError(http.StatusRequestEntityTooLarge, fmt.Sprintf("请求体不能超过 %d 字节", MaxJSONBodySize))
+	}
+	return body, nil
+}
+
 func DecodeRequest(r *http.Request, v any) error {
 	_, err := DecodeOptionalRequest(r, v)
 	return err
@@ -59,7 +77,11 @@ func DecodeOptionalRequest(r *http.Request, v any) (bool, error) {
 	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
 		return false, nil
 	}
-	if err := render.DecodeJSON(r.Body, v); err != nil {
+	body, err := ReadRequestBody(r)
+	if err != nil {
+		return false, err
+	}
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(v); err != nil {
 		if errors.Is(err, io.EOF) {
 			return false, nil
 		}
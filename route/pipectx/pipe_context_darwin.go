@@ -14,9 +14,11 @@ import (
 type darwinPeerContextKey struct{}
 
 type DarwinPeerInfo struct {
+	PID    int
 	UID    uint32
 	GID    uint32
 	HasGID bool
+	Groups []uint32
 }
 
 type syscallConn interface {
@@ -57,6 +59,10 @@ func getDarwinPeerInfo(conn net.Conn) (DarwinPeerInfo, bool) {
 		if cred.Ngroups > 0 {
 			info.GID = cred.Groups[0]
 			info.HasGID = true
+			info.Groups = append([]uint32(nil), cred.Groups[:min(int(cred.Ngroups), len(cred.Groups))]...)
+		}
+		if pid, pidErr := unix.GetsockoptInt(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERPID); pidErr == nil {
+			info.PID = pid
 		}
 		okay = true
 	}); err != nil {
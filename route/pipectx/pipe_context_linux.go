@@ -6,7 +6,9 @@ import (
 	"context"
 	"net"
 	"net/http"
+	"sync"
 	"syscall"
+	"unsafe"
 
 	"golang.org/x/sys/unix"
 )
@@ -17,8 +19,14 @@ type UnixPeerInfo struct {
 	PID int
 	UID uint32
 	GID uint32
+	// 连接建立时对端进程的 pidfd，不可用时为 -1，连接关闭时释放
+	PIDFD int
+	// 连接建立时对端的附加组（SO_PEERGROUPS，Linux 4.13+），不可用时为 nil
+	Groups []uint32
 }
 
+var peerPIDFDs sync.Map
+
 type syscallConn interface {
 	SyscallConn() (syscall.RawConn, error)
 }
@@ -29,8 +37,19 @@ func ConfigureServer(server *http.Server) {
 		if !ok {
 			return ctx
 		}
+		if info.PIDFD >= 0 {
+			peerPIDFDs.Store(conn, info.PIDFD)
+		}
 		return context.WithValue(ctx, unixPeerContextKey{}, info)
 	}
+	server.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state != http.StateClosed && state != http.StateHijacked {
+			return
+		}
+		if pidfd, ok := peerPIDFDs.LoadAndDelete(conn); ok {
+			_ = unix.Close(pidfd.(int))
+		}
+	}
 }
 
 func getUnixPeerInfo(conn net.Conn) (UnixPeerInfo, bool) {
@@ -54,11 +73,16 @@ func getUnixPeerInfo(conn net.Conn) (UnixPeerInfo, bool) {
 			return
 		}
 		info = UnixPeerInfo{
-			PID: int(ucred.Pid),
-			UID: ucred.Uid,
-			GID: ucred.Gid,
+			PID:   int(ucred.Pid),
+			UID:   ucred.Uid,
+			GID:   ucred.Gid,
+			PIDFD: -1,
 		}
 		okay = info.PID > 0
+		if okay {
+			info.PIDFD = getPeerPIDFD(int(fd), info.PID)
+			info.Groups = getPeerGroups(int(fd))
+		}
 	}); err != nil {
 		return UnixPeerInfo{}, false
 	}
@@ -66,6 +90,38 @@ func getUnixPeerInfo(conn net.Conn) (UnixPeerInfo, bool) {
 	return info, okay
 }
 
+// 优先使用 SO_PEERPIDFD（Linux 6.5+）取得连接时的对端进程，旧内核退回 pidfd_open
+func getPeerPIDFD(fd int, pid int) int {
+	pidfd, err := unix.GetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_PEERPIDFD)
+	if err != nil {
+		pidfd, err = unix.PidfdOpen(pid, 0)
+		if err != nil {
+			return -1
+		}
+	}
+	unix.CloseOnExec(pidfd)
+	return pidfd
+}
+
+func getPeerGroups(fd int) []uint32 {
+	groups := make([]uint32, 64)
+	for {
+		size := uint32(len(groups) * 4)
+		_, _, errno := unix.Syscall6(unix.SYS_GETSOCKOPT, uintptr(fd), unix.SOL_SOCKET, unix.SO_PEERGROUPS, uintptr(unsafe.Pointer(&groups[0])), uintptr(unsafe.Pointer(&size)), 0)
+		switch errno {
+		case 0:
+			return groups[:size/4]
+		case unix.ERANGE:
+			if int(size/4) <= len(groups) {
+				return nil
+			}
+			groups = make([]uint32, size/4)
+		default:
+			return nil
+		}
+	}
+}
+
 func RequestUnixPeerInfo(r *http.Request) (UnixPeerInfo, bool) {
 	info, ok := r.Context().Value(unixPeerContextKey{}).(UnixPeerInfo)
 	return info, ok && info.PID > 0
@@ -0,0 +1,61 @@
+//go:build linux
+
+package pipectx
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestGetUnixPeerInfo(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "test.sock")
+	listener, err := net.Listen("unix", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	client, err := net.Dial("unix", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	info, ok := getUnixPeerInfo(conn)
+	if !ok {
+		t.Fatal("getUnixPeerInfo() failed")
+	}
+	if info.PIDFD >= 0 {
+		defer unix.Close(info.PIDFD)
+	}
+	if info.PID != os.Getpid() || int(info.UID) != os.Geteuid() || int(info.GID) != os.Getegid() {
+		t.Fatalf("peer = %+v, want pid %d uid %d gid %d", info, os.Getpid(), os.Geteuid(), os.Getegid())
+	}
+	if info.Groups == nil {
+		t.Skip("SO_PEERGROUPS unsupported")
+	}
+
+	want, err := os.Getgroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]int, 0, len(info.Groups))
+	for _, gid := range info.Groups {
+		got = append(got, int(gid))
+	}
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Fatalf("groups = %v, want %v", got, want)
+	}
+}
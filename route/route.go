@@ -1,7 +1,10 @@
 package route
 
 import (
+	"github.com/UruhaLushia/sparkle-service/route/audit"
+	"github.com/UruhaLushia/sparkle-service/route/auditapi"
 	"github.com/UruhaLushia/sparkle-service/route/auth"
+	"github.com/UruhaLushia/sparkle-service/route/authapi"
 	"github.com/UruhaLushia/sparkle-service/route/coreapi"
 	"github.com/UruhaLushia/sparkle-service/route/httphelper"
 	"github.com/UruhaLushia/sparkle-service/route/serviceapi"
@@ -16,22 +19,29 @@ import (
 func router() *chi.Mux {
 	r := chi.NewRouter()
 	r.Use(render.SetContentType(render.ContentTypeJSON))
+	r.Use(auth.SignResponses)
 
 	r.Group(func(r chi.Router) {
 		r.Get("/ping", func(w http.ResponseWriter, r *http.Request) {
 			httphelper.SendJSON(w, "success", "pong")
 		})
+		// 配对请求方尚未持有已注册的公钥，只能凭配对码认证
+		r.With(audit.Middleware).Post("/auth/pair", authapi.Pair)
 	})
 
 	r.Group(func(r chi.Router) {
+		// 审计放在认证之外，未通过认证的请求同样留下记录
+		r.Use(audit.Middleware)
 		r.Use(auth.AuthMiddleware)
 		r.Get("/test", func(w http.ResponseWriter, r *http.Request) {
 			httphelper.SendJSON(w, "success", "auth success")
 		})
+		r.Mount("/auth", authapi.Router())
 		r.Mount("/service", serviceapi.Router())
 		r.Mount("/sysproxy", sysproxyapi.Router())
 		r.Mount("/core", coreapi.Router())
 		r.Mount("/sys", sysapi.Router())
+		r.Mount("/audit", auditapi.Router())
 	})
 	return r
 }
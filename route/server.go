@@ -5,15 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"github.com/UruhaLushia/sparkle-service/log"
+	"github.com/UruhaLushia/sparkle-service/route/audit"
 	"github.com/UruhaLushia/sparkle-service/route/auth"
 	"github.com/UruhaLushia/sparkle-service/route/coreapi"
 	"github.com/UruhaLushia/sparkle-service/route/pipectx"
+	"github.com/UruhaLushia/sparkle-service/route/serviceapi"
 	"github.com/UruhaLushia/sparkle-service/route/sysproxyapi"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"sync"
 	"syscall"
 	"time"
@@ -21,7 +24,10 @@ import (
 	"github.com/UruhaLushia/sparkle-service/listen"
 )
 
-const serverShutdownTimeout = 3 * time.Second
+const (
+	serverShutdownTimeout  = 3 * time.Second
+	listenerAccessInterval = 2 * time.Second
+)
 
 var (
 	unixServer *http.Server
@@ -53,6 +59,10 @@ func Start(addr string) error {
 		log.Printf("警告: 初始化密钥管理器失败: %v", err)
 	}
 
+	if err := audit.Init(userDataDir); err != nil {
+		log.Printf("警告: 初始化审计日志失败: %v", err)
+	}
+
 	km := auth.GetKeyManager()
 	if km.IsInitialized() {
 		log.Println("密钥管理器已初始化")
@@ -64,6 +74,19 @@ func Start(addr string) error {
 	} else {
 		log.Println("警告：请求方身份绑定未启用")
 	}
+	if identity, err := km.ServiceIdentity(); err != nil {
+		log.Printf("警告：加载服务私钥失败，响应将不带签名：%v", err)
+	} else {
+		log.Printf("服务身份公钥 ID：%s", identity.KeyID)
+	}
+	// 配对码只在管理员执行 service pair 时输出到命令行，不会自动开启，也不写入服务日志
+	if (!km.IsInitialized() || !km.HasAuthorizedPrincipal()) && !km.PairingOpen() {
+		log.Println("服务未初始化，请以管理员身份执行 service init 或 service pair 完成授权")
+	}
+
+	// 先接管脱离运行的核心，登记其临时目录后再启动残留目录清理
+	coreapi.Restore()
+	serviceapi.Start()
 
 	var err error
 	if runtime.GOOS == "windows" {
@@ -83,6 +106,7 @@ func Stop() error {
 	if err := coreapi.Stop(); err != nil {
 		errs = append(errs, fmt.Errorf("停止核心失败：%w", err))
 	}
+	serviceapi.Stop()
 	if err := closeServers(); err != nil {
 		errs = append(errs, err)
 	}
@@ -173,19 +197,24 @@ func StartUnix(addr string) error {
 	if err != nil {
 		return fmt.Errorf("unix 监听错误：%w", err)
 	}
-	if uid, ok := auth.GetKeyManager().GetAuthorizedUID(); ok {
-		if err := os.Chown(addr, int(uid), -1); err != nil {
-			_ = l.Close()
-			return fmt.Errorf("设置 unix socket 所有者失败：%w", err)
-		}
-		if err := os.Chmod(addr, 0o600); err != nil {
-			_ = l.Close()
-			return fmt.Errorf("设置 unix socket 权限失败：%w", err)
-		}
-	} else if err := os.Chmod(addr, 0o600); err != nil {
+	access, err := applyUnixSocketAccess(addr, unixSocketAccess{})
+	if err != nil {
 		_ = l.Close()
-		return fmt.Errorf("设置 unix socket 权限失败：%w", err)
+		return err
+	}
+	var accessMu sync.Mutex
+	syncAccess := func() {
+		accessMu.Lock()
+		defer accessMu.Unlock()
+		next, err := applyUnixSocketAccess(addr, access)
+		if err != nil {
+			log.Printf("同步 unix socket 权限失败：%v", err)
+			return
+		}
+		access = next
 	}
+	stopWatch := watchListenerAccess(syncAccess)
+	defer stopWatch()
 	log.Printf("unix 监听地址: %s", l.Addr().String())
 
 	server := &http.Server{
@@ -198,16 +227,136 @@ func StartUnix(addr string) error {
 	return server.Serve(l)
 }
 
-func StartPipe(addr string) error {
-	pipeSDDL := ""
-	if sid, ok := auth.GetKeyManager().GetAuthorizedSID(); ok {
-		pipeSDDL = fmt.Sprintf("D:PAI(A;OICI;GWGR;;;%s)(A;OICI;GWGR;;;SY)", sid)
+// 授权主体变化时立即同步监听器权限；配对模式可能由 service pair 命令在外部开启，需要定期检查
+func watchListenerAccess(syncAccess func()) func() {
+	auth.GetKeyManager().SetPrincipalsChangedHandler(syncAccess)
+	stopWatch := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(listenerAccessInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopWatch:
+				return
+			case <-ticker.C:
+				syncAccess()
+			}
+		}
+	}()
+	return func() { close(stopWatch) }
+}
+
+type unixSocketAccess struct {
+	uid  int
+	gid  int
+	mode os.FileMode
+	// 授权主体超出单一所有者与属组的表达能力
+	unrepresentable bool
+}
+
+// 根据授权主体推导 socket 所有者、属组与权限，配对期间对所有本地用户开放
+func unixSocketAccessFor(principals []auth.AuthorizedPrincipal, pairing bool) unixSocketAccess {
+	var uids, gids []int
+	for _, principal := range principals {
+		id, err := strconv.Atoi(principal.Value)
+		if err != nil {
+			continue
+		}
+		switch principal.Type {
+		case auth.PrincipalTypeUID:
+			if id == 0 {
+				continue
+			}
+			uids = append(uids, id)
+		case auth.PrincipalTypeGID:
+			gids = append(gids, id)
+		}
 	}
 
-	l, err := listen.ListenNamedPipe(addr, pipeSDDL)
+	access := unixSocketAccess{uid: -1, gid: -1, mode: 0o600}
+	switch {
+	case pairing:
+		access.mode = 0o666
+	case len(uids) == 0 && len(gids) == 0:
+	case len(gids) == 0 && len(uids) == 1:
+		access.uid = uids[0]
+	case len(gids) == 1 && len(uids) <= 1:
+		access.gid = gids[0]
+		access.mode = 0o660
+		if len(uids) == 1 {
+			access.uid = uids[0]
+		}
+	default:
+		// 无法用单一所有者与属组表达时只允许 root 连接，不放开 socket 权限
+		access.unrepresentable = true
+	}
+	return access
+}
+
+// 权限与 current 相同时不做修改，返回实际生效的权限
+func applyUnixSocketAccess(addr string, current unixSocketAccess) (unixSocketAccess, error) {
+	km := auth.GetKeyManager()
+	pairing := km.PairingOpen()
+	access := unixSocketAccessFor(km.AuthorizedPrincipals(), pairing)
+	if access == current {
+		return access, nil
+	}
+
+	uid, gid := access.uid, access.gid
+	if uid < 0 {
+		uid = os.Geteuid()
+	}
+	if gid < 0 {
+		gid = os.Getegid()
+	}
+	if err := os.Chown(addr, uid, gid); err != nil {
+		return current, fmt.Errorf("设置 unix socket 所有者失败：%w", err)
+	}
+	if err := os.Chmod(addr, access.mode); err != nil {
+		return current, fmt.Errorf("设置 unix socket 权限失败：%w", err)
+	}
+	switch {
+	case pairing:
+		log.Println("配对模式已开启，unix socket 暂时对所有本地用户开放")
+	case access.unrepresentable:
+		log.Printf("警告：%v，unix socket 暂时仅允许 root 连接", auth.ErrSocketPrincipals)
+	}
+	return access, nil
+}
+
+// 配对期间使用默认访问控制列表，允许本机普通用户连接
+func pipeSDDL() string {
+	if auth.GetKeyManager().PairingOpen() {
+		return ""
+	}
+	return auth.AuthorizedPipeSDDL()
+}
+
+func StartPipe(addr string) error {
+	sddl := pipeSDDL()
+	l, err := listen.ListenNamedPipe(addr, sddl)
 	if err != nil {
 		return fmt.Errorf("pipe 监听错误：%w", err)
 	}
+	var accessMu sync.Mutex
+	syncAccess := func() {
+		accessMu.Lock()
+		defer accessMu.Unlock()
+		next := pipeSDDL()
+		if next == sddl {
+			return
+		}
+		if err := listen.SetNamedPipeSDDL(l, next); err != nil {
+			log.Printf("同步命名管道权限失败：%v", err)
+			return
+		}
+		sddl = next
+		if next == "" {
+			log.Println("配对模式已开启，命名管道暂时对本机普通用户开放")
+		}
+	}
+	stopWatch := watchListenerAccess(syncAccess)
+	defer stopWatch()
 	log.Printf("pipe 监听地址: %s", l.Addr().String())
 
 	server := &http.Server{
@@ -0,0 +1,37 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/UruhaLushia/sparkle-service/route/auth"
+)
+
+func TestUnixSocketAccessFor(t *testing.T) {
+	uid := func(value string) auth.AuthorizedPrincipal {
+		return auth.AuthorizedPrincipal{Type: auth.PrincipalTypeUID, Value: value}
+	}
+	gid := func(value string) auth.AuthorizedPrincipal {
+		return auth.AuthorizedPrincipal{Type: auth.PrincipalTypeGID, Value: value}
+	}
+
+	tests := []struct {
+		name       string
+		principals []auth.AuthorizedPrincipal
+		want       unixSocketAccess
+	}{
+		{name: "none", want: unixSocketAccess{uid: -1, gid: -1, mode: 0o600}},
+		{name: "single uid", principals: []auth.AuthorizedPrincipal{uid("1000")}, want: unixSocketAccess{uid: 1000, gid: -1, mode: 0o600}},
+		{name: "root uid ignored", principals: []auth.AuthorizedPrincipal{uid("0"), uid("1000")}, want: unixSocketAccess{uid: 1000, gid: -1, mode: 0o600}},
+		{name: "gid and uid", principals: []auth.AuthorizedPrincipal{uid("1000"), gid("100")}, want: unixSocketAccess{uid: 1000, gid: 100, mode: 0o660}},
+		{name: "two uids fail closed", principals: []auth.AuthorizedPrincipal{uid("1000"), uid("1001")}, want: unixSocketAccess{uid: -1, gid: -1, mode: 0o600, unrepresentable: true}},
+		{name: "two gids fail closed", principals: []auth.AuthorizedPrincipal{gid("100"), gid("101")}, want: unixSocketAccess{uid: -1, gid: -1, mode: 0o600, unrepresentable: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unixSocketAccessFor(tt.principals, false); got != tt.want {
+				t.Fatalf("unixSocketAccessFor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
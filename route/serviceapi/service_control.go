@@ -2,28 +2,62 @@ package serviceapi
 
 import (
 	"net/http"
+	"sync"
 	"time"
 
+	corepkg "github.com/UruhaLushia/sparkle-service/core"
 	"github.com/UruhaLushia/sparkle-service/log"
+	"github.com/UruhaLushia/sparkle-service/route/auth"
 	"github.com/UruhaLushia/sparkle-service/route/httphelper"
 	appservice "github.com/UruhaLushia/sparkle-service/service"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
 )
 
 var serviceController appservice.Controller
 
+var (
+	stopSandboxJanitor func()
+	janitorMu          sync.Mutex
+)
+
 func Router() http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(httphelper.RequestLogger)
 
-	r.Post("/stop", serviceStop)
-	r.Post("/restart", serviceRestart)
+	r.With(auth.RequireScope(auth.ScopeServiceControl)).Post("/stop", serviceStop)
+	r.With(auth.RequireScope(auth.ScopeServiceControl)).Post("/restart", serviceRestart)
+	r.With(auth.RequireScope(auth.ScopeCoreRead)).Get("/sandbox", serviceSandbox)
 
 	return r
 }
 
+func Start() {
+	janitorMu.Lock()
+	defer janitorMu.Unlock()
+
+	if stopSandboxJanitor == nil {
+		stopSandboxJanitor = corepkg.StartSandboxJanitor()
+	}
+}
+
+func Stop() {
+	janitorMu.Lock()
+	stop := stopSandboxJanitor
+	stopSandboxJanitor = nil
+	janitorMu.Unlock()
+
+	if stop != nil {
+		stop()
+	}
+}
+
+func serviceSandbox(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, corepkg.SandboxInventory())
+}
+
 func serviceStop(w http.ResponseWriter, r *http.Request) {
 	controlServiceAsync(w, "stop", "服务停止中...", func() error { return serviceController.Stop() })
 }
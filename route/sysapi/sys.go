@@ -2,6 +2,7 @@ package sysapi
 
 import (
 	"fmt"
+	"github.com/UruhaLushia/sparkle-service/route/auth"
 	"github.com/UruhaLushia/sparkle-service/route/httphelper"
 	"github.com/UruhaLushia/sparkle-service/sys"
 	"net/http"
@@ -17,7 +18,7 @@ type dnsRequest struct {
 func Router() http.Handler {
 	r := chi.NewRouter()
 
-	r.Post("/dns/set", setDns)
+	r.With(auth.RequireScope(auth.ScopeDNSWrite)).Post("/dns/set", setDns)
 
 	return r
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/UruhaLushia/sparkle-service/log"
+	"github.com/UruhaLushia/sparkle-service/route/auth"
 	"github.com/UruhaLushia/sparkle-service/route/httphelper"
 
 	"github.com/UruhaLushia/sysproxy-go/sysproxy"
@@ -29,9 +30,12 @@ func Router() http.Handler {
 	r := chi.NewRouter()
 	r.Get("/status", status)
 	r.Get("/events", sysproxyEvents)
-	r.Post("/pac", pac)
-	r.Post("/proxy", proxy)
-	r.Post("/disable", disable)
+	r.Group(func(r chi.Router) {
+		r.Use(auth.RequireScope(auth.ScopeSysproxyWrite))
+		r.Post("/pac", pac)
+		r.Post("/proxy", proxy)
+		r.Post("/disable", disable)
+	})
 	return r
 }
 